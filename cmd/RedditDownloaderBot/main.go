@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"github.com/lartie/RedditDownloaderBot/internal/bot"
 	"github.com/lartie/RedditDownloaderBot/internal/cache"
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
 	"github.com/lartie/RedditDownloaderBot/pkg/common"
 	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"github.com/lartie/RedditDownloaderBot/pkg/telegraph"
 	"github.com/lartie/RedditDownloaderBot/pkg/util"
 	"log"
 	"os"
@@ -13,6 +16,7 @@ import (
 	"time"
 
 	"github.com/go-faster/errors"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -31,28 +35,122 @@ func main() {
 	}
 	botClient := bot.Client{}
 	// Start up database
+	// The same Redis connection is used for both the callback cache and the user settings
+	var redisClient *redis.Client
 	if redisAddress, redisPort := os.Getenv("REDIS_ADDRESS"), os.Getenv("REDIS_PORT"); redisAddress != "" && redisPort != "" {
-		// Parse ttl
-		ttl, _ := time.ParseDuration(os.Getenv("REDIS_TTL"))
-		if ttl <= 0 {
-			ttl = 5 * time.Minute
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     redisAddress + ":" + redisPort,
+			Password: os.Getenv("REDIS_PASSWORD"),
+		})
+		if err = redisClient.Ping(context.Background()).Err(); err != nil {
+			log.Fatalln("Cannot connect to Redis:", err)
+		}
+	}
+	// Parse ttl
+	ttl, _ := time.ParseDuration(os.Getenv("REDIS_TTL"))
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	switch cacheBackend := os.Getenv("CACHE_BACKEND"); {
+	case cacheBackend == "file":
+		cachePath := os.Getenv("CACHE_PATH")
+		if cachePath == "" {
+			cachePath = "cache.db"
 		}
-		botClient.CallbackCache, err = cache.NewRedisCache(redisAddress+":"+redisPort, os.Getenv("REDIS_PASSWORD"), ttl)
+		botClient.CallbackCache, err = cache.NewBoltCache(cachePath, ttl, 10*time.Minute)
 		if err != nil {
-			log.Fatalln("Cannot connect to Redis:", err)
+			log.Fatalln("Cannot open the cache database:", err)
+		}
+	case cacheBackend == "redis" || (cacheBackend == "" && redisClient != nil):
+		if redisClient == nil {
+			log.Fatalln("Please set REDIS_ADDRESS and REDIS_PORT to use Redis as the cache.")
 		}
-	} else { // Simple in cache memory
-		botClient.CallbackCache = cache.NewMemoryCache(5*time.Minute, 10*time.Minute)
+		botClient.CallbackCache = cache.NewRedisCache(redisClient, ttl)
+	case cacheBackend == "memory" || cacheBackend == "": // Simple in cache memory
+		botClient.CallbackCache = cache.NewMemoryCache(ttl, 10*time.Minute, getMemoryCacheLimits())
+	default:
+		log.Fatalln("Unknown CACHE_BACKEND:", cacheBackend)
 	}
 	defer botClient.CallbackCache.Close()
+	// Report how well the cache works once in a while
+	cacheStatsInterval, err := time.ParseDuration(os.Getenv("CACHE_STATS_INTERVAL"))
+	if err != nil {
+		cacheStatsInterval = time.Hour
+	}
+	if cacheStatsInterval > 0 {
+		go logCacheStats(botClient.CallbackCache, cacheStatsInterval)
+	}
+	// Open the user settings
+	if settingsPath := os.Getenv("SETTINGS_PATH"); settingsPath != "" {
+		botClient.Settings, err = settings.NewBoltStore(settingsPath)
+		if err != nil {
+			log.Fatalln("Cannot open the settings database:", err)
+		}
+	} else if redisClient != nil { // Shared between all the bot instances which use this Redis
+		botClient.Settings = settings.NewRedisStore(redisClient)
+	} else { // Settings are lost on restart
+		botClient.Settings = settings.NewMemoryStore()
+	}
+	defer botClient.Settings.Close()
 	// Start the reddit oauth
 	botClient.RedditOauth, err = reddit.NewRedditOauth(clientID, clientSecret)
 	if err != nil {
 		log.Fatalln("Cannot initialize the Reddit OAuth:", err.Error())
 	}
+	// Cache the fetched posts to save the rate limit of Reddit
+	fetchCacheTTL, _ := time.ParseDuration(os.Getenv("FETCH_CACHE_TTL"))
+	if fetchCacheTTL <= 0 {
+		fetchCacheTTL = 5 * time.Minute
+	}
+	botClient.RedditOauth.SetFetchResultCache(botClient.CallbackCache, fetchCacheTTL)
+	// Let the users get the long texts as Telegraph pages
+	if !util.ParseEnvironmentVariableBool("DISABLE_TELEGRAPH") {
+		telegraphAPIURL := os.Getenv("TELEGRAPH_API_URL")
+		if telegraphAPIURL == "" {
+			telegraphAPIURL = telegraph.DefaultAPIURL
+		}
+		botClient.TextPublisher = telegraph.NewClient(telegraphAPIURL, os.Getenv("TELEGRAPH_ACCESS_TOKEN"), os.Getenv("TELEGRAPH_AUTHOR_NAME"), &common.GlobalHttpClient)
+	}
+	if maxListingPosts, err := strconv.Atoi(os.Getenv("LISTING_MAX_POSTS")); err == nil && maxListingPosts > 0 {
+		botClient.MaxListingPosts = maxListingPosts
+	}
+	if maxUserPosts, err := strconv.Atoi(os.Getenv("USER_MAX_POSTS")); err == nil && maxUserPosts > 0 {
+		botClient.MaxUserPosts = maxUserPosts
+	}
+	if maxLinks, err := strconv.Atoi(os.Getenv("MAX_LINKS_PER_MESSAGE")); err == nil && maxLinks > 0 {
+		botClient.MaxLinksPerMessage = maxLinks
+	}
+	botClient.CaptionSignature = os.Getenv("CAPTION_SIGNATURE")
 	botClient.RunBot(botToken, getAllowedUsers())
 }
 
+// logCacheStats logs the counters of the cache every interval. It never returns.
+func logCacheStats(c cache.Interface, interval time.Duration) {
+	for range time.Tick(interval) {
+		stats, err := c.Stats()
+		if err != nil {
+			log.Println("Cannot get the stats of the cache:", err)
+			continue
+		}
+		log.Printf("Cache stats: %d hits, %d misses, %d evictions\n", stats.Hits, stats.Misses, stats.Evictions)
+	}
+}
+
+// getMemoryCacheLimits gets the limits of the in memory cache. Negative values disable a limit.
+func getMemoryCacheLimits() cache.MemoryCacheLimits {
+	limits := cache.MemoryCacheLimits{
+		MaxEntries: 10000,
+		MaxBytes:   64 << 20, // 64MiB
+	}
+	if maxEntries, err := strconv.Atoi(os.Getenv("MEMORY_CACHE_MAX_ENTRIES")); err == nil && maxEntries != 0 {
+		limits.MaxEntries = maxEntries
+	}
+	if maxBytes, err := strconv.ParseInt(os.Getenv("MEMORY_CACHE_MAX_BYTES"), 10, 64); err == nil && maxBytes != 0 {
+		limits.MaxBytes = maxBytes
+	}
+	return limits
+}
+
 // getAllowedUsers gets the list of users which are allowed to use the bot
 func getAllowedUsers() []int64 {
 	usersString := strings.Split(os.Getenv("ALLOWED_USERS"), ",")
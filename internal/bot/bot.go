@@ -2,16 +2,16 @@ package bot
 
 import (
 	"github.com/lartie/RedditDownloaderBot/internal/cache"
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
+	"github.com/lartie/RedditDownloaderBot/pkg/markdown"
 	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
 	"github.com/lartie/RedditDownloaderBot/pkg/util"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
@@ -21,76 +21,6 @@ import (
 	"github.com/google/uuid"
 )
 
-// ----- Settings (in-memory) -----
-
-type DownloadMode int
-
-const (
-	DownloadModeAsk DownloadMode = iota
-	DownloadModeMedia
-	DownloadModeFiles
-)
-
-// ----- Media quality (in-memory)
-
-type MediaQuality int
-
-const (
-	QualityAsk MediaQuality = iota
-	QualityOriginal
-	QualityHigh
-	QualityLow
-)
-
-func (q MediaQuality) String() string {
-	switch q {
-	case QualityOriginal:
-		return "original"
-	case QualityHigh:
-		return "high"
-	case QualityLow:
-		return "low"
-	default:
-		return "ask"
-	}
-}
-
-func parseMediaQuality(s string) MediaQuality {
-	switch strings.ToLower(s) {
-	case "original":
-		return QualityOriginal
-	case "high":
-		return QualityHigh
-	case "low":
-		return QualityLow
-	default:
-		return QualityAsk
-	}
-}
-
-var userQualityPrefs = struct {
-	mu    sync.RWMutex
-	byUID map[int64]MediaQuality
-}{
-	byUID: make(map[int64]MediaQuality),
-}
-
-func getUserQuality(userID int64) MediaQuality {
-	userQualityPrefs.mu.RLock()
-	q, ok := userQualityPrefs.byUID[userID]
-	userQualityPrefs.mu.RUnlock()
-	if !ok {
-		return QualityAsk
-	}
-	return q
-}
-
-func setUserQuality(userID int64, q MediaQuality) {
-	userQualityPrefs.mu.Lock()
-	userQualityPrefs.byUID[userID] = q
-	userQualityPrefs.mu.Unlock()
-}
-
 const (
 	KindSettings = "s"
 
@@ -103,128 +33,105 @@ const (
 	ActionSetQlt   = "sq"
 	ActionOpenLink = "oln"
 	ActionSetLink  = "sln"
-)
-
-// attach original reddit link in captions/text
-var userAttachLinkPrefs = struct {
-	mu    sync.RWMutex
-	byUID map[int64]bool // true = attach, false = don't attach
-}{
-	byUID: make(map[int64]bool),
-}
-
-func getUserAttachLink(uid int64) bool {
-	userAttachLinkPrefs.mu.RLock()
-	v, ok := userAttachLinkPrefs.byUID[uid]
-	userAttachLinkPrefs.mu.RUnlock()
-	if !ok {
-		return true // default: keep existing behavior and attach
-	}
-	return v
-}
+	ActionOpenPoll = "op"
+	ActionSetPoll  = "sp"
 
-func setUserAttachLink(uid int64, v bool) {
-	userAttachLinkPrefs.mu.Lock()
-	userAttachLinkPrefs.byUID[uid] = v
-	userAttachLinkPrefs.mu.Unlock()
-}
-
-func (m DownloadMode) String() string {
-	switch m {
-	case DownloadModeMedia:
-		return "media"
-	case DownloadModeFiles:
-		return "files"
-	default:
-		return "ask"
-	}
-}
+	ActionOpenLongText = "olt"
+	ActionSetLongText  = "slt"
+	ActionOpenComments = "oc"
+	ActionSetComments  = "sc"
+	ActionOpenContext  = "ocx"
+	ActionSetContext   = "scx"
+	ActionOpenCaption  = "ocp"
+	ActionSetCaption   = "scp"
+)
 
-func parseDownloadMode(s string) DownloadMode {
-	switch strings.ToLower(s) {
-	case "media":
-		return DownloadModeMedia
-	case "files":
-		return DownloadModeFiles
-	default:
-		return DownloadModeAsk
+// userSettings gets the settings of a user from the settings store.
+// If the store fails, the error is logged and the default settings are returned.
+func (c *Client) userSettings(uid int64) settings.User {
+	user, err := c.Settings.Get(uid)
+	if err != nil {
+		log.Println("Cannot get the settings of user", uid, ":", err)
+		return settings.DefaultUser()
 	}
+	return user
 }
 
-// user preferences stored in RAM
-var userPrefs = struct {
-	mu    sync.RWMutex
-	byUID map[int64]DownloadMode
-}{
-	byUID: make(map[int64]DownloadMode),
-}
-
-func getUserMode(userID int64) DownloadMode {
-	userPrefs.mu.RLock()
-	m, ok := userPrefs.byUID[userID]
-	userPrefs.mu.RUnlock()
-	if !ok {
-		return DownloadModeAsk
+// updateUserSettings applies update to the settings of a user and returns the new settings.
+// If the store fails, the error is logged and the updated settings are returned anyway.
+func (c *Client) updateUserSettings(uid int64, update func(*settings.User)) settings.User {
+	var result settings.User
+	err := c.Settings.Update(uid, func(user *settings.User) {
+		update(user)
+		result = *user
+	})
+	if err != nil {
+		log.Println("Cannot update the settings of user", uid, ":", err)
+		result = settings.DefaultUser()
+		update(&result)
 	}
-	return m
-}
-
-func setUserMode(userID int64, m DownloadMode) {
-	userPrefs.mu.Lock()
-	userPrefs.byUID[userID] = m
-	userPrefs.mu.Unlock()
+	return result
 }
 
 // UI builders
-func settingsRootKeyboardFor(uid int64) gotgbot.InlineKeyboardMarkup {
-	l := getUserLang(uid)
-	m := getUserMode(uid)
-	q := getUserQuality(uid)
-	attach := getUserAttachLink(uid)
+func (c *Client) settingsRootKeyboardFor(uid int64) gotgbot.InlineKeyboardMarkup {
+	user := c.userSettings(uid)
+	l := user.Lang
+	m := user.DownloadMode
+	q := user.Quality
+	attach := user.AttachLink
 
 	// Build button labels with current values
 	langLabel := fmt.Sprintf("%s %s", tr(l, "settings.language.caption"), langHuman(l))
 
 	var modeValue string
 	switch m {
-	case DownloadModeAsk:
-		modeValue = t(uid, "mode.ask")
-	case DownloadModeMedia:
-		if l == LangRU {
-			modeValue = "Авто (" + t(uid, "mode.media") + ")"
+	case settings.DownloadModeAsk:
+		modeValue = tr(l, "mode.ask")
+	case settings.DownloadModeMedia:
+		if l == settings.LangRU {
+			modeValue = "Авто (" + tr(l, "mode.media") + ")"
 		} else {
-			modeValue = "Auto (" + t(uid, "mode.media") + ")"
+			modeValue = "Auto (" + tr(l, "mode.media") + ")"
 		}
-	case DownloadModeFiles:
-		if l == LangRU {
-			modeValue = "Авто (" + t(uid, "mode.files") + ")"
+	case settings.DownloadModeFiles:
+		if l == settings.LangRU {
+			modeValue = "Авто (" + tr(l, "mode.files") + ")"
 		} else {
-			modeValue = "Auto (" + t(uid, "mode.files") + ")"
+			modeValue = "Auto (" + tr(l, "mode.files") + ")"
 		}
 	}
 	modeLabel := fmt.Sprintf("%s %s", tr(l, "settings.mode.caption"), modeValue)
 
 	var qltValue string
 	switch q {
-	case QualityOriginal:
-		qltValue = t(uid, "quality.original")
-	case QualityHigh:
-		qltValue = t(uid, "quality.high")
-	case QualityLow:
-		qltValue = t(uid, "quality.low")
+	case settings.QualityOriginal:
+		qltValue = tr(l, "quality.original")
+	case settings.QualityHigh:
+		qltValue = tr(l, "quality.high")
+	case settings.QualityLow:
+		qltValue = tr(l, "quality.low")
 	default:
-		qltValue = t(uid, "quality.ask")
+		qltValue = tr(l, "quality.ask")
 	}
 	qltLabel := fmt.Sprintf("%s %s", tr(l, "settings.quality.caption"), qltValue)
 
 	var linkVal string
 	if attach {
-		linkVal = t(uid, "link.on")
+		linkVal = tr(l, "link.on")
 	} else {
-		linkVal = t(uid, "link.off")
+		linkVal = tr(l, "link.off")
 	}
 	linkLabel := fmt.Sprintf("%s %s", tr(l, "settings.link.caption"), linkVal)
 
+	pollLabel := fmt.Sprintf("%s %s", tr(l, "settings.poll.caption"), tr(l, "poll."+user.PollMode.String()))
+
+	longTextLabel := fmt.Sprintf("%s %s", tr(l, "settings.long_text.caption"), tr(l, "long_text."+user.LongTextMode.String()))
+
+	commentsLabel := fmt.Sprintf("%s %s", tr(l, "settings.comments.caption"), commentsHuman(user))
+
+	contextLabel := fmt.Sprintf("%s %s", tr(l, "settings.context.caption"), contextHuman(l, user.CommentContext))
+
 	return gotgbot.InlineKeyboardMarkup{
 		InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
 			{
@@ -253,14 +160,44 @@ func settingsRootKeyboardFor(uid int64) gotgbot.InlineKeyboardMarkup {
 			},
 			{
 				{
-					Text:         t(uid, "settings.back"),
+					Text:         pollLabel,
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenPoll, "").String(),
+				},
+			},
+			{
+				{
+					Text:         longTextLabel,
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenLongText, "").String(),
+				},
+			},
+			{
+				{
+					Text:         commentsLabel,
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenComments, "").String(),
+				},
+			},
+			{
+				{
+					Text:         contextLabel,
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenContext, "").String(),
+				},
+			},
+			{
+				{
+					Text:         "📝 " + tr(l, "settings.caption.caption"),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenCaption, "").String(),
+				},
+			},
+			{
+				{
+					Text:         tr(l, "settings.back"),
 					CallbackData: NewSettingsCallbackData(KindSettings, "back", "").String(),
 				},
 			},
 		},
 	}
 }
-func settingsLinkKeyboard(uid int64, attach bool) gotgbot.InlineKeyboardMarkup {
+func settingsLinkKeyboard(l settings.Lang, attach bool) gotgbot.InlineKeyboardMarkup {
 	mark := func(label string, active bool) string {
 		if active {
 			return "• " + label + " ✅"
@@ -271,24 +208,172 @@ func settingsLinkKeyboard(uid int64, attach bool) gotgbot.InlineKeyboardMarkup {
 		InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
 			{
 				{
-					Text:         mark(t(uid, "link.on"), attach == true),
+					Text:         mark(tr(l, "link.on"), attach == true),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetLink, "on").String(),
 				},
 				{
-					Text:         mark(t(uid, "link.off"), attach == false),
+					Text:         mark(tr(l, "link.off"), attach == false),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetLink, "off").String(),
 				},
 			},
 			{
 				{
-					Text:         t(uid, "settings.back"),
+					Text:         tr(l, "settings.back"),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenRoot, "").String(),
+				},
+			},
+		},
+	}
+}
+func settingsPollKeyboard(l settings.Lang, current settings.PollMode) gotgbot.InlineKeyboardMarkup {
+	mark := func(label string, active bool) string {
+		if active {
+			return "• " + label + " ✅"
+		}
+		return label
+	}
+	return gotgbot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
+			{
+				{
+					Text:         mark(tr(l, "poll.native"), current == settings.PollModeNative),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetPoll, "native").String(),
+				},
+			},
+			{
+				{
+					Text:         mark(tr(l, "poll.text"), current == settings.PollModeText),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetPoll, "text").String(),
+				},
+			},
+			{
+				{
+					Text:         tr(l, "settings.back"),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenRoot, "").String(),
+				},
+			},
+		},
+	}
+}
+func settingsLongTextKeyboard(l settings.Lang, current settings.LongTextMode) gotgbot.InlineKeyboardMarkup {
+	mark := func(label string, active bool) string {
+		if active {
+			return "• " + label + " ✅"
+		}
+		return label
+	}
+	return gotgbot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
+			{
+				{
+					Text:         mark(tr(l, "long_text.split"), current == settings.LongTextModeSplit),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetLongText, "split").String(),
+				},
+			},
+			{
+				{
+					Text:         mark(tr(l, "long_text.telegraph"), current == settings.LongTextModeTelegraph),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetLongText, "telegraph").String(),
+				},
+			},
+			{
+				{
+					Text:         tr(l, "settings.back"),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenRoot, "").String(),
+				},
+			},
+		},
+	}
+}
+func settingsCommentsKeyboard(l settings.Lang, user settings.User) gotgbot.InlineKeyboardMarkup {
+	mark := func(label string, active bool) string {
+		if active {
+			return "• " + label + " ✅"
+		}
+		return label
+	}
+	var modeRow, countRow, depthRow []gotgbot.InlineKeyboardButton
+	for _, m := range []settings.CommentsMode{settings.CommentsModeOff, settings.CommentsModeMessage, settings.CommentsModeDocument} {
+		modeRow = append(modeRow, gotgbot.InlineKeyboardButton{
+			Text:         mark(tr(l, "comments."+m.String()), user.CommentsMode == m),
+			CallbackData: NewSettingsCallbackData(KindSettings, ActionSetComments, "mode:"+m.String()).String(),
+		})
+	}
+	for _, n := range commentsCountChoices {
+		countRow = append(countRow, gotgbot.InlineKeyboardButton{
+			Text:         mark(fmt.Sprintf(tr(l, "comments.count"), n), user.CommentsCount == n),
+			CallbackData: NewSettingsCallbackData(KindSettings, ActionSetComments, "count:"+strconv.Itoa(n)).String(),
+		})
+	}
+	for _, d := range commentsDepthChoices {
+		depthRow = append(depthRow, gotgbot.InlineKeyboardButton{
+			Text:         mark(fmt.Sprintf(tr(l, "comments.depth"), d), user.CommentsDepth == d),
+			CallbackData: NewSettingsCallbackData(KindSettings, ActionSetComments, "depth:"+strconv.Itoa(d)).String(),
+		})
+	}
+	return gotgbot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
+			modeRow,
+			countRow,
+			depthRow,
+			{
+				{
+					Text:         tr(l, "settings.back"),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenRoot, "").String(),
+				},
+			},
+		},
+	}
+}
+func settingsContextKeyboard(l settings.Lang, current int) gotgbot.InlineKeyboardMarkup {
+	mark := func(label string, active bool) string {
+		if active {
+			return "• " + label + " ✅"
+		}
+		return label
+	}
+	var row []gotgbot.InlineKeyboardButton
+	for _, n := range commentContextChoices {
+		row = append(row, gotgbot.InlineKeyboardButton{
+			Text:         mark(contextHuman(l, n), current == n),
+			CallbackData: NewSettingsCallbackData(KindSettings, ActionSetContext, strconv.Itoa(n)).String(),
+		})
+	}
+	return gotgbot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
+			row,
+			{
+				{
+					Text:         tr(l, "settings.back"),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenRoot, "").String(),
+				},
+			},
+		},
+	}
+}
+func settingsCaptionKeyboard(l settings.Lang) gotgbot.InlineKeyboardMarkup {
+	return gotgbot.InlineKeyboardMarkup{
+		InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
+			{
+				{
+					Text:         tr(l, "caption.edit"),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetCaption, "edit").String(),
+				},
+				{
+					Text:         tr(l, "caption.reset"),
+					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetCaption, "reset").String(),
+				},
+			},
+			{
+				{
+					Text:         tr(l, "settings.back"),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenRoot, "").String(),
 				},
 			},
 		},
 	}
 }
-func settingsQualityKeyboard(uid int64, current MediaQuality) gotgbot.InlineKeyboardMarkup {
+func settingsQualityKeyboard(l settings.Lang, current settings.MediaQuality) gotgbot.InlineKeyboardMarkup {
 	mark := func(label string, active bool) string {
 		if active {
 			return "• " + label + " ✅"
@@ -299,31 +384,31 @@ func settingsQualityKeyboard(uid int64, current MediaQuality) gotgbot.InlineKeyb
 		InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
 			{
 				{
-					Text:         mark(t(uid, "quality.ask"), current == QualityAsk),
+					Text:         mark(tr(l, "quality.ask"), current == settings.QualityAsk),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetQlt, "ask").String(),
 				},
 			},
 			{
 				{
-					Text:         mark(t(uid, "quality.original"), current == QualityOriginal),
+					Text:         mark(tr(l, "quality.original"), current == settings.QualityOriginal),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetQlt, "original").String(),
 				},
 			},
 			{
 				{
-					Text:         mark(t(uid, "quality.high"), current == QualityHigh),
+					Text:         mark(tr(l, "quality.high"), current == settings.QualityHigh),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetQlt, "high").String(),
 				},
 			},
 			{
 				{
-					Text:         mark(t(uid, "quality.low"), current == QualityLow),
+					Text:         mark(tr(l, "quality.low"), current == settings.QualityLow),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetQlt, "low").String(),
 				},
 			},
 			{
 				{
-					Text:         t(uid, "settings.back"),
+					Text:         tr(l, "settings.back"),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenRoot, "").String(),
 				},
 			},
@@ -331,7 +416,7 @@ func settingsQualityKeyboard(uid int64, current MediaQuality) gotgbot.InlineKeyb
 	}
 }
 
-func settingsModeKeyboard(uid int64, current DownloadMode) gotgbot.InlineKeyboardMarkup {
+func settingsModeKeyboard(l settings.Lang, current settings.DownloadMode) gotgbot.InlineKeyboardMarkup {
 	mark := func(label string, active bool) string {
 		if active {
 			return "• " + label + " ✅"
@@ -342,23 +427,23 @@ func settingsModeKeyboard(uid int64, current DownloadMode) gotgbot.InlineKeyboar
 		InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
 			{
 				{
-					Text:         mark(t(uid, "mode.media"), current == DownloadModeMedia),
+					Text:         mark(tr(l, "mode.media"), current == settings.DownloadModeMedia),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetMode, "media").String(),
 				},
 				{
-					Text:         mark(t(uid, "mode.files"), current == DownloadModeFiles),
+					Text:         mark(tr(l, "mode.files"), current == settings.DownloadModeFiles),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetMode, "files").String(),
 				},
 			},
 			{
 				{
-					Text:         mark(t(uid, "mode.ask"), current == DownloadModeAsk),
+					Text:         mark(tr(l, "mode.ask"), current == settings.DownloadModeAsk),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionSetMode, "ask").String(),
 				},
 			},
 			{
 				{
-					Text:         t(uid, "settings.back"),
+					Text:         tr(l, "settings.back"),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenRoot, "").String(),
 				},
 			},
@@ -366,10 +451,9 @@ func settingsModeKeyboard(uid int64, current DownloadMode) gotgbot.InlineKeyboar
 	}
 }
 
-func settingsLangKeyboard(uid int64) gotgbot.InlineKeyboardMarkup {
-	cur := getUserLang(uid)
+func settingsLangKeyboard(l settings.Lang) gotgbot.InlineKeyboardMarkup {
 	mark := func(code string, label string) string {
-		if string(cur) == code {
+		if string(l) == code {
 			return "• " + label + " ✅"
 		}
 		return label
@@ -388,7 +472,7 @@ func settingsLangKeyboard(uid int64) gotgbot.InlineKeyboardMarkup {
 			},
 			{
 				{
-					Text:         t(uid, "settings.back"),
+					Text:         tr(l, "settings.back"),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenRoot, "").String(),
 				},
 			},
@@ -396,12 +480,12 @@ func settingsLangKeyboard(uid int64) gotgbot.InlineKeyboardMarkup {
 	}
 }
 
-func startKeyboard(uid int64) gotgbot.InlineKeyboardMarkup {
+func startKeyboard(l settings.Lang) gotgbot.InlineKeyboardMarkup {
 	return gotgbot.InlineKeyboardMarkup{
 		InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
 			{
 				{
-					Text:         tr(getUserLang(uid), "settings.title"),
+					Text:         tr(l, "settings.title"),
 					CallbackData: NewSettingsCallbackData(KindSettings, ActionOpenRoot, "").String(),
 				},
 			},
@@ -459,23 +543,46 @@ func (c *Client) RunBot(token string, allowedUsers AllowedUsers) {
 }
 
 func (c *Client) handleMessage(bot *gotgbot.Bot, ctx *ext.Context) error {
+	uid := ctx.Message.From.Id
 	// Only text messages are allowed
 	if ctx.Message.Text == "" {
-		_, err := ctx.EffectiveChat.SendMessage(bot, t(ctx.Message.From.Id, "msg.request_post"), nil)
+		_, err := ctx.EffectiveChat.SendMessage(bot, tr(c.userSettings(uid).Lang, "msg.request_post"), nil)
 		return err
 	}
+	if target, editing := c.captionEdits.LoadAndDelete(uid); editing && target.(int64) == ctx.EffectiveChat.Id {
+		// Commands cancel the edit and are handled like always
+		if !strings.HasPrefix(ctx.Message.Text, "/") {
+			return c.saveCaptionTemplate(bot, ctx, target.(int64))
+		}
+	}
+	if command, isListing, valid := parseListingCommand(ctx.Message.Text, c.MaxListingPosts); isListing {
+		if !valid {
+			_, err := ctx.EffectiveMessage.Reply(bot, tr(c.userSettings(uid).Lang, "listing.usage"), nil)
+			return err
+		}
+		return c.handleListingCommand(bot, ctx, command)
+	}
+	if username, limit, isUser, valid := parseUserCommand(ctx.Message.Text, c.userPostsLimit()); isUser {
+		if !valid {
+			_, err := ctx.EffectiveMessage.Reply(bot, tr(c.userSettings(uid).Lang, "user.usage"), nil)
+			return err
+		}
+		return c.handleUserSubmissions(bot, ctx, username, limit)
+	}
+	if username, ok := reddit.ParseUserSubmittedURL(ctx.Message.Text); ok {
+		return c.handleUserSubmissions(bot, ctx, username, c.userPostsLimit())
+	}
 	// Check if the message is command. I don't use command handler because I'll lose
 	// the userID control.
 	switch ctx.Message.Text {
 	case "/start":
-		uid := ctx.Message.From.Id
 		// автодетект языка по первому контакту
-		maybeSetLangFrom(ctx.Message.From.LanguageCode, uid)
+		user := c.maybeSetLangFrom(ctx.Message.From.LanguageCode, uid)
 
 		// локализованное приветствие, без кнопки
 		_, err := ctx.EffectiveChat.SendMessage(
 			bot,
-			tr(getUserLang(uid), "cmd.start"),
+			tr(user.Lang, "cmd.start"),
 			&gotgbot.SendMessageOpts{
 				LinkPreviewOptions: &gotgbot.LinkPreviewOptions{
 					IsDisabled: true,
@@ -484,99 +591,108 @@ func (c *Client) handleMessage(bot *gotgbot.Bot, ctx *ext.Context) error {
 		)
 		return err
 	case "/help":
-		_, err := ctx.EffectiveChat.SendMessage(bot, tr(getUserLang(ctx.Message.From.Id), "cmd.help"), nil)
+		_, err := ctx.EffectiveChat.SendMessage(bot, tr(c.userSettings(uid).Lang, "cmd.help"), nil)
 		return err
 	case "/settings":
-		uid := ctx.Message.From.Id
-		modeTitle := tr(getUserLang(uid), "settings.title")
+		modeTitle := tr(c.userSettings(uid).Lang, "settings.title")
 		_, err := ctx.EffectiveChat.SendMessage(bot, modeTitle, &gotgbot.SendMessageOpts{
 			ParseMode:   gotgbot.ParseModeMarkdownV2,
-			ReplyMarkup: settingsRootKeyboardFor(uid),
+			ReplyMarkup: c.settingsRootKeyboardFor(uid),
 		})
 		return err
 	default:
-		return c.fetchPostDetailsAndSend(bot, ctx)
+		return c.handleLinks(bot, ctx)
 	}
 }
 
 // fetchPostDetailsAndSend gets the basic info about the post being sent to us
 func (c *Client) fetchPostDetailsAndSend(bot *gotgbot.Bot, ctx *ext.Context) error {
-	result, realPostUrl, fetchErr := c.RedditOauth.StartFetch(ctx.Message.Text)
+	err := c.sendPost(bot, ctx.Message.Text, ctx.Message.From.Id, ctx.EffectiveChat.Id, ctx.EffectiveMessage.MessageId)
+	if errors.Is(err, postNotFetchedErr) {
+		return nil
+	}
+	return err
+}
+
+// sendPost fetches the post or comment in text and sends it to the chat based on the settings of
+// the user. The messages which ask the user reply to replyTo if it's not zero. If the post cannot
+// be fetched, the user is told why and postNotFetchedErr is returned.
+func (c *Client) sendPost(bot *gotgbot.Bot, text string, uid, chatID, replyTo int64) error {
+	var replyParameters *gotgbot.ReplyParameters
+	if replyTo != 0 {
+		replyParameters = &gotgbot.ReplyParameters{MessageId: replyTo}
+	}
+	result, realPostUrl, fetchErr := c.RedditOauth.StartFetch(text)
 	if fetchErr != nil {
 		if fetchErr.NormalError != "" {
-			log.Println("Cannot fetch the post", ctx.Message.Text, ":", fetchErr.NormalError)
+			log.Println("Cannot fetch the post", text, ":", fetchErr.NormalError)
 		}
-		_, err := ctx.EffectiveMessage.Reply(bot, fetchErr.BotError, nil)
-		return err
+		_, err := bot.SendMessage(chatID, fetchErr.BotError, &gotgbot.SendMessageOpts{ReplyParameters: replyParameters})
+		if err != nil {
+			return err
+		}
+		return postNotFetchedErr
 	}
 	// link preference
-	uid := ctx.Message.From.Id
+	user := c.userSettings(uid)
 	postUrl := realPostUrl
-	if !getUserAttachLink(uid) {
+	if !user.AttachLink {
 		postUrl = ""
 	}
 	// Check the result type
 	toSendText := ""
 	toSendOpt := &gotgbot.SendMessageOpts{
-		ParseMode: gotgbot.ParseModeMarkdownV2,
+		ParseMode:       gotgbot.ParseModeMarkdownV2,
+		ReplyParameters: replyParameters,
 	}
 	switch data := result.(type) {
 	case reddit.FetchResultText:
-		toSendText = addLinkIfNeeded(data.Title+"\n"+data.Text, postUrl)
+		return c.sendTextBlocks(bot, data.Title, appendLinkBlockIfNeeded(textPostBlocks(data), postUrl), uid, chatID, replyTo)
 	case reddit.FetchResultComment:
-		toSendText = addLinkIfNeeded(data.Text, postUrl)
+		// The context in the link overrides the settings
+		depth := user.CommentContext
+		if context, ok := reddit.CommentContext(text); ok {
+			depth = context
+		}
+		// Comments in old caches do not have a parent
+		if depth > 0 && data.ParentID != "" {
+			thread, fetchErr := c.RedditOauth.FetchCommentThread(data, depth)
+			if fetchErr == nil {
+				return c.sendTextBlocks(bot, thread.PostTitle, appendLinkBlockIfNeeded(commentThreadBlocks(thread), postUrl), uid, chatID, replyTo)
+			}
+			log.Println("Cannot fetch the parents of the comment", text, ":", fetchErr.NormalError)
+		}
+		return c.sendTextBlocks(bot, "", appendLinkBlockIfNeeded(markdown.Blocks(data.Text), postUrl), uid, chatID, replyTo)
 	case reddit.FetchResultMedia:
 		if len(data.Medias) == 0 {
-			toSendText = t(ctx.Message.From.Id, "msg.no_media_found")
+			toSendText = tr(user.Lang, "msg.no_media_found")
 			break
 		}
+		caption := c.postCaption(bot, uid, chatID, captionPost{
+			Title:    data.Title,
+			Metadata: data.Metadata,
+			Link:     postUrl,
+		})
+		// Telegram blurs the media with spoilers
+		spoiler := data.NSFW || data.Spoiler
 		// Try auto-select by user quality preference
-		uid := ctx.Message.From.Id
-		if getUserQuality(uid) != QualityAsk && len(data.Medias) > 0 {
-			// pick index by desired quality
-			pickIdx := func() int {
-				// sort by area descending (original first)
-				type pair struct {
-					idx  int
-					area int64
-				}
-				arr := make([]pair, 0, len(data.Medias))
-				for i, m := range data.Medias {
-					area := m.Dim.Width * m.Dim.Height
-					arr = append(arr, pair{i, area})
-				}
-				sort.Slice(arr, func(i, j int) bool { return arr[i].area > arr[j].area })
-				q := getUserQuality(uid)
-				switch q {
-				case QualityOriginal:
-					return arr[0].idx
-				case QualityHigh:
-					if len(arr) >= 3 { // original + high + low (or more)
-						return arr[1].idx
-					}
-					return arr[0].idx // map to original when only original/low
-				case QualityLow:
-					return arr[len(arr)-1].idx
-				default:
-					return -1
-				}
-			}
-			idx := pickIdx()
+		if user.Quality != settings.QualityAsk && len(data.Medias) > 0 {
+			idx := selectQualityIndex(data.Medias, user.Quality)
 			if idx >= 0 {
 				switch data.Type {
 				case reddit.FetchResultMediaTypeGif:
-					return c.handleGifUpload(bot, data.Medias[idx].Link, data.Title, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, data.Medias[idx].Dim, ctx.EffectiveChat.Id)
+					return c.handleGifUpload(bot, data.Medias[idx].Link, caption, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, data.Medias[idx].Dim, uid, chatID, spoiler)
 				case reddit.FetchResultMediaTypeVideo:
 					if _, hasAudio := data.HasAudio(); !hasAudio {
-						return c.handleVideoUpload(bot, data.Medias[idx].Link, "", data.Title, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, data.Medias[idx].Dim, data.Duration, ctx.EffectiveChat.Id)
+						return c.handleVideoUpload(bot, data.Medias[idx].Link, "", caption, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, data.Medias[idx].Dim, data.Duration, uid, chatID, spoiler)
 					}
 					// with audio: pair selected video with audio URL
 					ai, _ := data.HasAudio()
 					audio := data.Medias[ai]
-					return c.handleVideoUpload(bot, data.Medias[idx].Link, audio.Link, data.Title, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, data.Medias[idx].Dim, data.Duration, ctx.EffectiveChat.Id)
+					return c.handleVideoUpload(bot, data.Medias[idx].Link, audio.Link, caption, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, data.Medias[idx].Dim, data.Duration, uid, chatID, spoiler)
 				case reddit.FetchResultMediaTypePhoto:
 					// send as photo by default
-					return c.handlePhotoUpload(bot, data.Medias[idx].Link, data.Title, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, ctx.EffectiveChat.Id, true)
+					return c.handlePhotoUpload(bot, data.Medias[idx].Link, caption, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, uid, chatID, true, spoiler)
 				}
 			}
 		}
@@ -585,19 +701,19 @@ func (c *Client) fetchPostDetailsAndSend(bot *gotgbot.Bot, ctx *ext.Context) err
 		if len(data.Medias) == 1 && data.Type != reddit.FetchResultMediaTypePhoto {
 			switch data.Type {
 			case reddit.FetchResultMediaTypeGif:
-				return c.handleGifUpload(bot, data.Medias[0].Link, data.Title, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, data.Medias[0].Dim, ctx.EffectiveChat.Id)
+				return c.handleGifUpload(bot, data.Medias[0].Link, caption, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, data.Medias[0].Dim, uid, chatID, spoiler)
 			case reddit.FetchResultMediaTypeVideo:
 				// If the video does have an audio, ask user if they want the audio
 				if _, hasAudio := data.HasAudio(); !hasAudio {
 					// Otherwise, just download the video
-					return c.handleVideoUpload(bot, data.Medias[0].Link, "", data.Title, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, data.Medias[0].Dim, data.Duration, ctx.EffectiveChat.Id)
+					return c.handleVideoUpload(bot, data.Medias[0].Link, "", caption, data.ThumbnailLinks.SelectThumbnail(maxThumbnailDimensions), postUrl, data.Description, data.Medias[0].Dim, data.Duration, uid, chatID, spoiler)
 				}
 			default:
 				panic("Shash")
 			}
 		}
 		// Allow the user to select quality
-		toSendText = t(ctx.Message.From.Id, "msg.select_quality")
+		toSendText = tr(user.Lang, "msg.select_quality")
 		idString := util.UUIDToBase64(uuid.New())
 		audioIndex, _ := data.HasAudio()
 		switch data.Type {
@@ -618,18 +734,24 @@ func (c *Client) fetchPostDetailsAndSend(bot *gotgbot.Bot, ctx *ext.Context) err
 			Type:          data.Type,
 			Duration:      data.Duration,
 			AudioIndex:    audioIndex,
+			Metadata:      data.Metadata,
+			Spoiler:       spoiler,
 		})
 		if err != nil {
 			log.Println("Cannot set the media cache in database:", err)
 		}
+	case reddit.FetchResultPoll:
+		if user.PollMode == settings.PollModeNative && canSendNativePoll(data) {
+			return c.handlePollUpload(bot, data, postUrl, uid, chatID, replyTo)
+		}
+		return c.sendTextBlocks(bot, data.Title, appendLinkBlockIfNeeded(pollSummary(user.Lang, data), postUrl), uid, chatID, replyTo)
 	case reddit.FetchResultAlbum:
 		// auto-apply user preference if not "ask"
-		uid := ctx.Message.From.Id
-		switch getUserMode(uid) {
-		case DownloadModeMedia:
-			return c.handleAlbumUpload(bot, data, postUrl, ctx.EffectiveChat.Id, false)
-		case DownloadModeFiles:
-			return c.handleAlbumUpload(bot, data, postUrl, ctx.EffectiveChat.Id, true)
+		switch user.DownloadMode {
+		case settings.DownloadModeMedia:
+			return c.handleAlbumUpload(bot, data, postUrl, uid, chatID, false)
+		case settings.DownloadModeFiles:
+			return c.handleAlbumUpload(bot, data, postUrl, uid, chatID, true)
 		}
 		idString := util.UUIDToBase64(uuid.New())
 		err := c.CallbackCache.SetAlbumCache(idString, cache.CallbackAlbumCached{
@@ -639,19 +761,18 @@ func (c *Client) fetchPostDetailsAndSend(bot *gotgbot.Bot, ctx *ext.Context) err
 		if err != nil {
 			log.Println("Cannot set the album cache in database:", err)
 		}
-		toSendText = t(ctx.Message.From.Id, "album.ask")
-		uid = ctx.Message.From.Id
+		toSendText = tr(user.Lang, "album.ask")
 		toSendOpt.ReplyMarkup = gotgbot.InlineKeyboardMarkup{
 			InlineKeyboard: [][]gotgbot.InlineKeyboardButton{{
 				gotgbot.InlineKeyboardButton{
-					Text: t(uid, "album.button.media"),
+					Text: tr(user.Lang, "album.button.media"),
 					CallbackData: CallbackButtonData{
 						ID:   idString,
 						Mode: CallbackButtonDataModePhoto,
 					}.String(),
 				},
 				gotgbot.InlineKeyboardButton{
-					Text: t(uid, "album.button.file"),
+					Text: tr(user.Lang, "album.button.file"),
 					CallbackData: CallbackButtonData{
 						ID:   idString,
 						Mode: CallbackButtonDataModeFile,
@@ -661,116 +782,205 @@ func (c *Client) fetchPostDetailsAndSend(bot *gotgbot.Bot, ctx *ext.Context) err
 		}
 	default:
 		log.Printf("unknown type: %T\n", result)
-		toSendText = tr(getUserLang(ctx.Message.From.Id), "unknown.type")
-	}
-	// Check the toSendText size
-	if len(toSendText) > 4096 {
-		_, err := bot.SendDocument(ctx.EffectiveChat.Id, &gotgbot.FileReader{
-			Name: "post.txt",
-			Data: strings.NewReader(toSendText),
-		}, &gotgbot.SendDocumentOpts{ReplyParameters: &gotgbot.ReplyParameters{
-			MessageId: ctx.EffectiveMessage.MessageId,
-		}})
-		return err
+		toSendText = tr(user.Lang, "unknown.type")
 	}
-	_, err := ctx.EffectiveMessage.Reply(bot, toSendText, toSendOpt)
+	_, err := bot.SendMessage(chatID, toSendText, toSendOpt)
 	if err != nil {
-		toSendOpt.ParseMode = gotgbot.ParseModeMarkdown // fall to V1 and try again
-		_, err = ctx.EffectiveMessage.Reply(bot, toSendText, toSendOpt)
-		if err != nil {
-			toSendOpt.ParseMode = gotgbot.ParseModeNone // fall back again and don't format message
-			_, err = ctx.EffectiveMessage.Reply(bot, toSendText, toSendOpt)
-		}
+		toSendOpt.ParseMode = gotgbot.ParseModeNone // fall back and don't format message
+		_, err = bot.SendMessage(chatID, toSendText, toSendOpt)
 	}
 	return err
 }
 
 // handleCallback handles the callback query of selecting a quality for any media type
 func (c *Client) handleCallback(bot *gotgbot.Bot, ctx *ext.Context) error {
+	uid := ctx.CallbackQuery.From.Id
+	user := c.userSettings(uid)
 	// Don't crash!
 	defer func() {
 		if r := recover(); r != nil {
-			_, _ = ctx.EffectiveChat.SendMessage(bot, t(ctx.CallbackQuery.From.Id, "err.panic"), nil)
+			_, _ = ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "err.panic"), nil)
 			log.Println("Recovering from panic:", r)
 		}
 	}()
-	// Delete the message
-	_, _ = bot.DeleteMessage(ctx.EffectiveChat.Id, ctx.EffectiveMessage.GetMessageId(), nil)
 	// Settings callbacks (identified by kind == "settings")
 	var scd settingsCallbackData
 	if err := json.Unmarshal([]byte(ctx.CallbackQuery.Data), &scd); err == nil && scd.Kind == KindSettings {
-		uid := ctx.CallbackQuery.From.Id
+		// Delete the message. Each settings page is sent as a new message
+		_, _ = bot.DeleteMessage(ctx.EffectiveChat.Id, ctx.EffectiveMessage.GetMessageId(), nil)
 		switch scd.Action {
 		case ActionOpenLink:
-			_, err := ctx.EffectiveChat.SendMessage(bot, t(uid, "settings.link.caption"), &gotgbot.SendMessageOpts{
-				ReplyMarkup: settingsLinkKeyboard(uid, getUserAttachLink(uid)),
+			_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "settings.link.caption"), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsLinkKeyboard(user.Lang, user.AttachLink),
 			})
 			return err
 		case ActionSetLink:
 			v := strings.ToLower(scd.Value) == "on"
-			setUserAttachLink(uid, v)
-			label := t(uid, "link.off")
+			user = c.updateUserSettings(uid, func(u *settings.User) {
+				u.AttachLink = v
+			})
+			label := tr(user.Lang, "link.off")
 			if v {
-				label = t(uid, "link.on")
+				label = tr(user.Lang, "link.on")
 			}
-			_, err := ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(getUserLang(uid), "settings.link.saved"), label), &gotgbot.SendMessageOpts{
-				ReplyMarkup: settingsLinkKeyboard(uid, v),
+			_, err := ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(user.Lang, "settings.link.saved"), label), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsLinkKeyboard(user.Lang, v),
 			})
 			return err
 		case ActionOpenMode:
-			_, err := ctx.EffectiveChat.SendMessage(bot, t(uid, "settings.mode.caption"), &gotgbot.SendMessageOpts{
-				ReplyMarkup: settingsModeKeyboard(uid, getUserMode(uid)),
+			_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "settings.mode.caption"), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsModeKeyboard(user.Lang, user.DownloadMode),
 			})
 			return err
 		case ActionOpenRoot:
-			text := tr(getUserLang(uid), "settings.title")
+			text := tr(user.Lang, "settings.title")
 			_, err := ctx.EffectiveChat.SendMessage(bot, text, &gotgbot.SendMessageOpts{
-				ReplyMarkup: settingsRootKeyboardFor(uid),
+				ReplyMarkup: c.settingsRootKeyboardFor(uid),
 			})
 			return err
 		case "back":
-			_, err := ctx.EffectiveChat.SendMessage(bot, tr(getUserLang(uid), "cmd.start"), &gotgbot.SendMessageOpts{
+			_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "cmd.start"), &gotgbot.SendMessageOpts{
 				LinkPreviewOptions: &gotgbot.LinkPreviewOptions{IsDisabled: true},
 			})
 			return err
 		case ActionSetMode:
-			m := parseDownloadMode(scd.Value)
-			setUserMode(uid, m)
-			_, err := ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(getUserLang(uid), "settings.mode.saved"), parseDownloadMode(scd.Value).String()), &gotgbot.SendMessageOpts{
-				ReplyMarkup: settingsModeKeyboard(uid, m),
+			m := settings.ParseDownloadMode(scd.Value)
+			user = c.updateUserSettings(uid, func(u *settings.User) {
+				u.DownloadMode = m
+			})
+			_, err := ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(user.Lang, "settings.mode.saved"), m.String()), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsModeKeyboard(user.Lang, m),
 			})
 			return err
 		case ActionOpenLang:
-			_, err := ctx.EffectiveChat.SendMessage(bot, t(uid, "settings.language.caption"), &gotgbot.SendMessageOpts{
-				ReplyMarkup: settingsLangKeyboard(uid),
+			_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "settings.language.caption"), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsLangKeyboard(user.Lang),
 			})
 			return err
 		case ActionSetLang:
-			switch strings.ToLower(scd.Value) {
-			case "ru":
-				setUserLang(uid, LangRU)
-			default:
-				setUserLang(uid, LangEN)
+			l := settings.LangEN
+			if strings.ToLower(scd.Value) == "ru" {
+				l = settings.LangRU
 			}
-			cur := getUserLang(uid)
-			_, err := ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(cur, "settings.language.saved"), strings.ToUpper(string(cur))), &gotgbot.SendMessageOpts{
-				ReplyMarkup: settingsLangKeyboard(uid),
+			user = c.updateUserSettings(uid, func(u *settings.User) {
+				u.Lang = l
+			})
+			_, err := ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(user.Lang, "settings.language.saved"), strings.ToUpper(string(user.Lang))), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsLangKeyboard(user.Lang),
 			})
 			return err
 		case ActionOpenQlt:
-			_, err := ctx.EffectiveChat.SendMessage(bot, t(uid, "settings.quality.caption"), &gotgbot.SendMessageOpts{
-				ReplyMarkup: settingsQualityKeyboard(uid, getUserQuality(uid)),
+			_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "settings.quality.caption"), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsQualityKeyboard(user.Lang, user.Quality),
 			})
 			return err
 		case ActionSetQlt:
-			q := parseMediaQuality(scd.Value)
-			setUserQuality(uid, q)
-			_, err := ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(getUserLang(uid), "settings.quality.saved"), q.String()), &gotgbot.SendMessageOpts{
-				ReplyMarkup: settingsQualityKeyboard(uid, q),
+			q := settings.ParseMediaQuality(scd.Value)
+			user = c.updateUserSettings(uid, func(u *settings.User) {
+				u.Quality = q
+			})
+			_, err := ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(user.Lang, "settings.quality.saved"), q.String()), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsQualityKeyboard(user.Lang, q),
+			})
+			return err
+		case ActionOpenPoll:
+			_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "settings.poll.caption"), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsPollKeyboard(user.Lang, user.PollMode),
+			})
+			return err
+		case ActionSetPoll:
+			m := settings.ParsePollMode(scd.Value)
+			user = c.updateUserSettings(uid, func(u *settings.User) {
+				u.PollMode = m
+			})
+			_, err := ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(user.Lang, "settings.poll.saved"), tr(user.Lang, "poll."+m.String())), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsPollKeyboard(user.Lang, m),
+			})
+			return err
+		case ActionOpenLongText:
+			_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "settings.long_text.caption"), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsLongTextKeyboard(user.Lang, user.LongTextMode),
+			})
+			return err
+		case ActionSetLongText:
+			m := settings.ParseLongTextMode(scd.Value)
+			user = c.updateUserSettings(uid, func(u *settings.User) {
+				u.LongTextMode = m
+			})
+			_, err := ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(user.Lang, "settings.long_text.saved"), tr(user.Lang, "long_text."+m.String())), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsLongTextKeyboard(user.Lang, m),
+			})
+			return err
+		case ActionOpenComments:
+			_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "settings.comments.caption"), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsCommentsKeyboard(user.Lang, user),
+			})
+			return err
+		case ActionSetComments:
+			// The value is like "mode:message", "count:5" or "depth:2"
+			field, value, _ := strings.Cut(scd.Value, ":")
+			n, _ := strconv.Atoi(value)
+			user = c.updateUserSettings(uid, func(u *settings.User) {
+				switch field {
+				case "mode":
+					u.CommentsMode = settings.ParseCommentsMode(value)
+				case "count":
+					if n > 0 {
+						u.CommentsCount = n
+					}
+				case "depth":
+					if n > 0 {
+						u.CommentsDepth = n
+					}
+				}
+			})
+			_, err := ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(user.Lang, "settings.comments.saved"), commentsHuman(user)), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsCommentsKeyboard(user.Lang, user),
+			})
+			return err
+		case ActionOpenContext:
+			_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "settings.context.caption"), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsContextKeyboard(user.Lang, user.CommentContext),
+			})
+			return err
+		case ActionSetContext:
+			n, err := strconv.Atoi(scd.Value)
+			if err != nil || n < 0 || n > reddit.MaxCommentContext {
+				n = 0
+			}
+			user = c.updateUserSettings(uid, func(u *settings.User) {
+				u.CommentContext = n
+			})
+			_, err = ctx.EffectiveChat.SendMessage(bot, fmt.Sprintf(tr(user.Lang, "settings.context.saved"), contextHuman(user.Lang, n)), &gotgbot.SendMessageOpts{
+				ReplyMarkup: settingsContextKeyboard(user.Lang, n),
+			})
+			return err
+		case ActionOpenCaption, ActionSetCaption:
+			c.captionEdits.Delete(uid)
+			target, allowed := captionSettingsTarget(bot, uid, ctx.EffectiveChat.Id)
+			if !allowed {
+				_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "caption.admins_only"), nil)
+				return err
+			}
+			text := ""
+			switch scd.Value {
+			case "edit":
+				c.captionEdits.Store(uid, target)
+				_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "caption.send"), nil)
+				return err
+			case "reset":
+				c.updateUserSettings(target, func(u *settings.User) {
+					u.CaptionTemplate = ""
+				})
+				text = escapeMarkdown(tr(user.Lang, "settings.caption.saved")) + "\n\n"
+			}
+			_, err := ctx.EffectiveChat.SendMessage(bot, text+c.captionSettingsText(bot, user.Lang, target), &gotgbot.SendMessageOpts{
+				ParseMode:   gotgbot.ParseModeMarkdownV2,
+				ReplyMarkup: settingsCaptionKeyboard(user.Lang),
 			})
 			return err
 		default:
-			_, err := ctx.EffectiveChat.SendMessage(bot, t(uid, "settings.unknown_action"), nil)
+			_, err := ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "settings.unknown_action"), nil)
 			return err
 		}
 	}
@@ -778,56 +988,70 @@ func (c *Client) handleCallback(bot *gotgbot.Bot, ctx *ext.Context) error {
 	var data CallbackButtonData
 	err := json.Unmarshal([]byte(ctx.CallbackQuery.Data), &data)
 	if err != nil {
-		uid := ctx.CallbackQuery.From.Id
-		_, err = ctx.EffectiveChat.SendMessage(bot, t(uid, "err.broken_callback"), nil)
+		_, err = ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "err.broken_callback"), nil)
 		return err
 	}
+	// Stop the loading of the button. The keyboard is not deleted, so the user can
+	// choose other qualities or modes from it until the cache expires.
+	_, _ = ctx.CallbackQuery.Answer(bot, nil)
 	// Get the cache from database
-	cachedData, err := c.CallbackCache.GetAndDeleteMediaCache(data.ID)
+	cachedData, err := c.CallbackCache.GetMediaCache(data.ID)
 	if errors.Is(err, cache.NotFoundErr) {
 		// Check albums
 		var album cache.CallbackAlbumCached
-		album, err = c.CallbackCache.GetAndDeleteAlbumCache(data.ID)
+		album, err = c.CallbackCache.GetAlbumCache(data.ID)
 		if err == nil {
-			return c.handleAlbumUpload(bot, album.Album, album.PostLink, ctx.EffectiveChat.Id, data.Mode == CallbackButtonDataModeFile)
+			_ = c.CallbackCache.TouchAlbumCache(data.ID)
+			return c.handleAlbumUpload(bot, album.Album, album.PostLink, uid, ctx.EffectiveChat.Id, data.Mode == CallbackButtonDataModeFile)
 		} else if errors.Is(err, cache.NotFoundErr) {
-			// It does not exist...
-			uid := ctx.CallbackQuery.From.Id
-			_, err = ctx.EffectiveChat.SendMessage(bot, t(uid, "err.resend_link"), nil)
+			// It does not exist... The keyboard is useless now
+			_, _ = bot.DeleteMessage(ctx.EffectiveChat.Id, ctx.EffectiveMessage.GetMessageId(), nil)
+			_, err = ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "err.resend_link"), nil)
 			return err
 		}
 		// Fall to report internal error
 	}
+	// The cache was written by a newer version of the bot which is being deployed
+	if errors.Is(err, cache.UnknownSchemaVersionErr) {
+		_, _ = bot.DeleteMessage(ctx.EffectiveChat.Id, ctx.EffectiveMessage.GetMessageId(), nil)
+		_, err = ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "err.outdated_callback"), nil)
+		return err
+	}
 	// Check other errors
 	if err != nil {
-		uid := ctx.CallbackQuery.From.Id
 		log.Println("Cannot get Callback ID from database:", err)
-		_, err = ctx.EffectiveChat.SendMessage(bot, t(uid, "err.internal"), nil)
+		_, err = ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "err.internal"), nil)
 		return err
 	}
 	// Check the link
 	link, exists := cachedData.Links[data.LinkKey]
 	if !exists {
-		uid := ctx.CallbackQuery.From.Id
-		_, err = ctx.EffectiveChat.SendMessage(bot, t(uid, "err.resend_link"), nil)
+		_, err = ctx.EffectiveChat.SendMessage(bot, tr(user.Lang, "err.resend_link"), nil)
 		return err
 	}
+	// Keep the keyboard alive while the user is using it
+	_ = c.CallbackCache.TouchMediaCache(data.ID)
 	dim := reddit.Dimension{
 		Width:  link.Width,
 		Height: link.Height,
 	}
+	caption := c.postCaption(bot, uid, ctx.EffectiveChat.Id, captionPost{
+		Title:    cachedData.Title,
+		Metadata: cachedData.Metadata,
+		Link:     cachedData.PostLink,
+	})
 	// Check the media type
 	switch cachedData.Type {
 	case reddit.FetchResultMediaTypeGif:
-		return c.handleGifUpload(bot, link.Link, cachedData.Title, cachedData.ThumbnailLink, cachedData.PostLink, cachedData.Description, dim, ctx.EffectiveChat.Id)
+		return c.handleGifUpload(bot, link.Link, caption, cachedData.ThumbnailLink, cachedData.PostLink, cachedData.Description, dim, uid, ctx.EffectiveChat.Id, cachedData.Spoiler)
 	case reddit.FetchResultMediaTypePhoto:
-		return c.handlePhotoUpload(bot, link.Link, cachedData.Title, cachedData.ThumbnailLink, cachedData.PostLink, cachedData.Description, ctx.EffectiveChat.Id, data.Mode == CallbackButtonDataModePhoto)
+		return c.handlePhotoUpload(bot, link.Link, caption, cachedData.ThumbnailLink, cachedData.PostLink, cachedData.Description, uid, ctx.EffectiveChat.Id, data.Mode == CallbackButtonDataModePhoto, cachedData.Spoiler)
 	case reddit.FetchResultMediaTypeVideo:
 		if data.LinkKey == cachedData.AudioIndex {
-			return c.handleAudioUpload(bot, link.Link, cachedData.Title, cachedData.PostLink, cachedData.Description, cachedData.Duration, ctx.EffectiveChat.Id)
+			return c.handleAudioUpload(bot, link.Link, caption, cachedData.PostLink, cachedData.Description, cachedData.Duration, uid, ctx.EffectiveChat.Id)
 		} else {
 			audioURL := cachedData.Links[cachedData.AudioIndex]
-			return c.handleVideoUpload(bot, link.Link, audioURL.Link, cachedData.Title, cachedData.ThumbnailLink, cachedData.PostLink, cachedData.Description, dim, cachedData.Duration, ctx.EffectiveChat.Id)
+			return c.handleVideoUpload(bot, link.Link, audioURL.Link, caption, cachedData.ThumbnailLink, cachedData.PostLink, cachedData.Description, dim, cachedData.Duration, uid, ctx.EffectiveChat.Id, cachedData.Spoiler)
 		}
 	}
 	// What
@@ -853,23 +1077,54 @@ func NewSettingsCallbackData(kind string, action string, value string) *settings
 	}
 }
 
-func maybeSetLangFrom(code string, uid int64) {
+// maybeSetLangFrom sets the language of the user based on the language code of their Telegram
+// client. It's only done for the users which have no stored settings, so the language which a
+// user has chosen is kept. Returns the settings of the user after the change.
+func (c *Client) maybeSetLangFrom(code string, uid int64) settings.User {
 	if code == "" {
-		return
+		return c.userSettings(uid)
+	}
+	exists, err := c.Settings.Exists(uid)
+	if err != nil {
+		log.Println("Cannot check the settings of user", uid, ":", err)
+		return c.userSettings(uid)
+	}
+	if exists {
+		return c.userSettings(uid)
 	}
 	lc := strings.ToLower(code)
-	if strings.HasPrefix(lc, "ru") {
-		setUserLang(uid, LangRU)
-	} else {
-		setUserLang(uid, LangEN)
+	return c.updateUserSettings(uid, func(u *settings.User) {
+		if strings.HasPrefix(lc, "ru") {
+			u.Lang = settings.LangRU
+		} else {
+			u.Lang = settings.LangEN
+		}
+	})
+}
+
+// commentsHuman describes the comments settings of a user like "Messages, 5, depth 1"
+func commentsHuman(user settings.User) string {
+	if user.CommentsMode == settings.CommentsModeOff {
+		return tr(user.Lang, "comments.off")
+	}
+	return tr(user.Lang, "comments."+user.CommentsMode.String()) + ", " +
+		fmt.Sprintf(tr(user.Lang, "comments.count"), user.CommentsCount) + ", " +
+		fmt.Sprintf(tr(user.Lang, "comments.depth"), user.CommentsDepth)
+}
+
+// contextHuman describes the number of parents which are sent with a comment
+func contextHuman(l settings.Lang, n int) string {
+	if n == 0 {
+		return tr(l, "context.off")
 	}
+	return fmt.Sprintf(tr(l, "context.parents"), n)
 }
 
-func langHuman(l Lang) string {
+func langHuman(l settings.Lang) string {
 	switch l {
-	case LangRU:
+	case settings.LangRU:
 		return "Русский"
-	case LangEN:
+	case settings.LangEN:
 		return "English"
 	default:
 		return "English"
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaybeSetLangFrom(t *testing.T) {
+	c := &Client{Settings: settings.NewMemoryStore()}
+	// Nothing is stored without a language code
+	assert.Equal(t, settings.LangEN, c.maybeSetLangFrom("", 1).Lang)
+	exists, err := c.Settings.Exists(1)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	// New users get the language of their client
+	assert.Equal(t, settings.LangRU, c.maybeSetLangFrom("ru-RU", 1).Lang)
+	assert.Equal(t, settings.LangEN, c.maybeSetLangFrom("de", 2).Lang)
+	// The language which a user has chosen is kept
+	c.updateUserSettings(1, func(user *settings.User) { user.Lang = settings.LangEN })
+	assert.Equal(t, settings.LangEN, c.maybeSetLangFrom("ru", 1).Lang)
+	c.updateUserSettings(3, func(user *settings.User) { user.Quality = settings.QualityHigh })
+	user := c.maybeSetLangFrom("ru", 3)
+	assert.Equal(t, settings.LangEN, user.Lang)
+	assert.Equal(t, settings.QualityHigh, user.Quality)
+}
+
+func TestTextPostBlocks(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Post     reddit.FetchResultText
+		Expected []string
+	}{
+		{
+			TestName: "Text",
+			Post:     reddit.FetchResultText{Title: "Q & A", Text: "First\n\n**Second**"},
+			Expected: []string{"<b>Q &amp; A</b>", "First", "<b>Second</b>"},
+		},
+		{
+			// The title of a link post is only sent once
+			TestName: "Link",
+			Post:     reddit.FetchResultText{Title: "Article", Text: "https://example.com/article"},
+			Expected: []string{"<b>Article</b>", "https://example.com/article"},
+		},
+		{
+			TestName: "Spoiler",
+			Post:     reddit.FetchResultText{Title: "Ending", Text: "He dies", Spoiler: true},
+			Expected: []string{"<b>Ending</b>", "<tg-spoiler>He dies</tg-spoiler>"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Equal(t, test.Expected, textPostBlocks(test.Post))
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+)
+
+// DefaultCaptionTemplate is the caption template of the users and chats which have not set one.
+// It renders the same captions as the bot did before the templates were added.
+const DefaultCaptionTemplate = "{title}\n\n{link}"
+
+// maxCaptionLength is the maximum length of captions in Telegram. It's counted in UTF-16 code
+// units of the text after the entities are parsed.
+const maxCaptionLength = 1024
+
+// maxCaptionTemplateLength is the maximum length of the templates which users can set
+const maxCaptionTemplateLength = 512
+
+// captionPlaceholderRegex matches the placeholders of caption templates
+var captionPlaceholderRegex = regexp.MustCompile(`\{[a-z]+}`)
+
+// extraNewlinesRegex matches the empty lines which are left by the empty placeholders
+var extraNewlinesRegex = regexp.MustCompile(`\n{3,}`)
+
+// captionLinkEscaper escapes the characters which are not ok in the URL part of MarkdownV2 links
+var captionLinkEscaper = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+// captionPost is the data of a post which is put in its caption
+type captionPost struct {
+	Title    string
+	Metadata reddit.PostMetadata
+	// The link of the post. Empty if the link should not be attached.
+	Link string
+}
+
+// sampleCaptionPost is the post which the previews of templates are rendered with
+var sampleCaptionPost = captionPost{
+	Title: "Look at this cat",
+	Metadata: reddit.PostMetadata{
+		Subreddit: "cats",
+		Author:    "spez",
+		Score:     1234,
+		Flair:     "Cute",
+		Created:   1700000000,
+	},
+	Link: "https://www.reddit.com/r/cats/comments/abc123/look_at_this_cat/",
+}
+
+// renderCaption renders a caption template for a post as a MarkdownV2 text. Everything except the
+// link is escaped. Empty lines which are left by the empty placeholders are removed. If the caption
+// is longer than what Telegram allows, the title is shortened and if that is not enough, the caption
+// is cut.
+func renderCaption(template string, post captionPost, signature string) string {
+	caption, plain := renderCaptionParts(template, post, signature)
+	excess := utf16Length(plain) - maxCaptionLength
+	if excess <= 0 {
+		return caption
+	}
+	titleLength := utf16Length(post.Title)
+	if strings.Contains(template, "{title}") && titleLength > excess {
+		// Each title in the template must be shortened
+		count := strings.Count(template, "{title}")
+		post.Title = truncateUTF16(post.Title, titleLength-(excess+count-1)/count-1) + "…"
+		caption, plain = renderCaptionParts(template, post, signature)
+		if utf16Length(plain) <= maxCaptionLength {
+			return caption
+		}
+	}
+	// Last resort: send the beginning of the text without formatting
+	return escapeMarkdown(truncateUTF16(plain, maxCaptionLength-1) + "…")
+}
+
+// renderCaptionParts renders a caption template for a post as a MarkdownV2 text and as the plain
+// text which Telegram shows of it
+func renderCaptionParts(template string, post captionPost, signature string) (caption, plain string) {
+	var sb, plainSb strings.Builder
+	last := 0
+	for _, loc := range captionPlaceholderRegex.FindAllStringIndex(template, -1) {
+		literal := template[last:loc[0]]
+		sb.WriteString(escapeMarkdown(literal))
+		plainSb.WriteString(literal)
+		last = loc[1]
+		placeholder := template[loc[0]:loc[1]]
+		if placeholder == "{link}" {
+			if post.Link != "" && !disableIncludeLinkInCaption {
+				sb.WriteString("[🔗 Link](" + captionLinkEscaper.Replace(post.Link) + ")")
+				plainSb.WriteString("🔗 Link")
+			}
+			continue
+		}
+		value, known := captionPlaceholderValue(placeholder, post, signature)
+		if !known {
+			// Keep the unknown placeholders as they are
+			value = placeholder
+		}
+		sb.WriteString(escapeMarkdown(value))
+		plainSb.WriteString(value)
+	}
+	sb.WriteString(escapeMarkdown(template[last:]))
+	plainSb.WriteString(template[last:])
+	return cleanCaption(sb.String()), cleanCaption(plainSb.String())
+}
+
+// captionPlaceholderValue gets the text which a placeholder (except {link}) is replaced with. The
+// metadata of the posts which are cached by older versions is empty, so their placeholders are empty.
+func captionPlaceholderValue(placeholder string, post captionPost, signature string) (string, bool) {
+	metadata := post.Metadata
+	switch placeholder {
+	case "{title}":
+		return post.Title, true
+	case "{subreddit}":
+		if metadata.Subreddit == "" {
+			return "", true
+		}
+		return "r/" + metadata.Subreddit, true
+	case "{author}":
+		if metadata.Author == "" {
+			return "", true
+		}
+		return "u/" + metadata.Author, true
+	case "{score}":
+		// Zero is a valid score, so it's only empty if there is no metadata at all
+		if !metadata.Present() {
+			return "", true
+		}
+		return strconv.FormatInt(metadata.Score, 10), true
+	case "{flair}":
+		return metadata.Flair, true
+	case "{created}":
+		if metadata.Created == 0 {
+			return "", true
+		}
+		return time.Unix(metadata.Created, 0).UTC().Format("2006-01-02"), true
+	case "{signature}":
+		return signature, true
+	default:
+		return "", false
+	}
+}
+
+// cleanCaption removes the spaces around a caption and the empty lines which are more than one
+func cleanCaption(text string) string {
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " \t")
+	}
+	return extraNewlinesRegex.ReplaceAllString(strings.TrimSpace(strings.Join(lines, "\n")), "\n\n")
+}
+
+// utf16Length gets the length of a text in UTF-16 code units like Telegram counts it
+func utf16Length(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
+
+// truncateUTF16 cuts a text to at most n UTF-16 code units without breaking its characters
+func truncateUTF16(text string, n int) string {
+	length := 0
+	for i, r := range text {
+		length += utf16.RuneLen(r)
+		if length > n {
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// escapeCode escapes the characters which are not ok in the code blocks of MarkdownV2
+func escapeCode(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(text)
+}
+
+// captionSignature gets the text of the {signature} placeholder
+func (c *Client) captionSignature(bot *gotgbot.Bot) string {
+	if c.CaptionSignature != "" {
+		return c.CaptionSignature
+	}
+	return "@" + bot.Username
+}
+
+// captionTemplate gets the caption template which is used for the posts which a user sends to a
+// chat. The template of a group is preferred over the template of the user.
+func (c *Client) captionTemplate(uid, chatID int64) string {
+	if chatID != uid {
+		if template := c.userSettings(chatID).CaptionTemplate; template != "" {
+			return template
+		}
+	}
+	if template := c.userSettings(uid).CaptionTemplate; template != "" {
+		return template
+	}
+	return DefaultCaptionTemplate
+}
+
+// postCaption renders the caption of a post which a user sends to a chat
+func (c *Client) postCaption(bot *gotgbot.Bot, uid, chatID int64, post captionPost) string {
+	return renderCaption(c.captionTemplate(uid, chatID), post, c.captionSignature(bot))
+}
+
+// captionSettingsTarget gets the ID of the settings record whose caption template a user edits in
+// a chat. In private chats, it's the user. In groups, it's the group and only its admins can edit it.
+func captionSettingsTarget(bot *gotgbot.Bot, uid, chatID int64) (target int64, allowed bool) {
+	if chatID == uid {
+		return uid, true
+	}
+	member, err := bot.GetChatMember(chatID, uid, nil)
+	if err != nil {
+		log.Println("Cannot get the chat member", uid, "of", chatID, ":", err)
+		return chatID, false
+	}
+	status := member.GetStatus()
+	return chatID, status == "creator" || status == "administrator"
+}
+
+// captionSettingsText is the text of the caption settings page. It shows the current template of
+// the target (see captionSettingsTarget) and a preview of it.
+func (c *Client) captionSettingsText(bot *gotgbot.Bot, l settings.Lang, target int64) string {
+	template := c.userSettings(target).CaptionTemplate
+	if template == "" {
+		template = DefaultCaptionTemplate
+	}
+	titleKey := "settings.caption.user"
+	// The IDs of groups are negative
+	if target < 0 {
+		titleKey = "settings.caption.chat"
+	}
+	return escapeMarkdown(tr(l, titleKey)) + "\n```\n" + escapeCode(template) + "\n```\n" +
+		escapeMarkdown(tr(l, "caption.preview")) + "\n\n" +
+		renderCaption(template, sampleCaptionPost, c.captionSignature(bot)) + "\n\n" +
+		escapeMarkdown(tr(l, "caption.placeholders"))
+}
+
+// validCaptionTemplate checks if a text can be saved as a caption template
+func validCaptionTemplate(template string) bool {
+	return strings.TrimSpace(template) != "" && utf16Length(template) <= maxCaptionTemplateLength
+}
+
+// saveCaptionTemplate saves the text of a message as the caption template of a settings record
+// and shows the new settings page. If the template is not valid, the user is asked for another one.
+func (c *Client) saveCaptionTemplate(bot *gotgbot.Bot, ctx *ext.Context, target int64) error {
+	uid := ctx.Message.From.Id
+	l := c.userSettings(uid).Lang
+	template := ctx.Message.Text
+	if !validCaptionTemplate(template) {
+		c.captionEdits.Store(uid, target)
+		_, err := ctx.EffectiveMessage.Reply(bot, fmt.Sprintf(tr(l, "caption.invalid"), maxCaptionTemplateLength), nil)
+		return err
+	}
+	c.updateUserSettings(target, func(u *settings.User) {
+		u.CaptionTemplate = template
+	})
+	_, err := ctx.EffectiveMessage.Reply(bot, escapeMarkdown(tr(l, "settings.caption.saved"))+"\n\n"+c.captionSettingsText(bot, l, target), &gotgbot.SendMessageOpts{
+		ParseMode:   gotgbot.ParseModeMarkdownV2,
+		ReplyMarkup: settingsCaptionKeyboard(l),
+	})
+	return err
+}
@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderCaptionParts(t *testing.T) {
+	tests := []struct {
+		TestName        string
+		Template        string
+		Post            captionPost
+		ExpectedCaption string
+		ExpectedPlain   string
+	}{
+		{
+			TestName:        "Default",
+			Template:        DefaultCaptionTemplate,
+			Post:            captionPost{Title: "Hello (world)!", Link: "https://www.reddit.com/r/a/comments/b/c/"},
+			ExpectedCaption: "Hello \\(world\\)\\!\n\n[🔗 Link](https://www.reddit.com/r/a/comments/b/c/)",
+			ExpectedPlain:   "Hello (world)!\n\n🔗 Link",
+		},
+		{
+			TestName:        "Link Escaping",
+			Template:        "{link}",
+			Post:            captionPost{Link: `https://example.com/a_(b)\c`},
+			ExpectedCaption: `[🔗 Link](https://example.com/a_(b\)\\c)`,
+			ExpectedPlain:   "🔗 Link",
+		},
+		{
+			TestName:        "No Link",
+			Template:        DefaultCaptionTemplate,
+			Post:            captionPost{Title: "Title"},
+			ExpectedCaption: "Title",
+			ExpectedPlain:   "Title",
+		},
+		{
+			TestName:        "Metadata",
+			Template:        "{subreddit} | {author} | {score} | {flair} | {created} | {signature}",
+			Post:            sampleCaptionPost,
+			ExpectedCaption: "r/cats \\| u/spez \\| 1234 \\| Cute \\| 2023\\-11\\-14 \\| @bot\\_name",
+			ExpectedPlain:   "r/cats | u/spez | 1234 | Cute | 2023-11-14 | @bot_name",
+		},
+		{
+			TestName:        "Zero Score",
+			Template:        "{score}",
+			Post:            captionPost{Metadata: reddit.PostMetadata{Author: "spez"}},
+			ExpectedCaption: "0",
+			ExpectedPlain:   "0",
+		},
+		{
+			TestName:        "No Metadata",
+			Template:        "{title}\n\n{subreddit} {score}\n\n{author}\n\n{link}",
+			Post:            captionPost{Title: "Title"},
+			ExpectedCaption: "Title",
+			ExpectedPlain:   "Title",
+		},
+		{
+			TestName:        "Unknown Placeholder",
+			Template:        "{title} {unknown}",
+			Post:            captionPost{Title: "Title"},
+			ExpectedCaption: "Title \\{unknown\\}",
+			ExpectedPlain:   "Title {unknown}",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			caption, plain := renderCaptionParts(test.Template, test.Post, "@bot_name")
+			assert.Equal(t, test.ExpectedCaption, caption)
+			assert.Equal(t, test.ExpectedPlain, plain)
+		})
+	}
+}
+
+func TestRenderCaption(t *testing.T) {
+	link := "https://www.reddit.com/r/a/comments/b/c/"
+	tests := []struct {
+		TestName string
+		Template string
+		Post     captionPost
+		// The signature is used to make the text around the titles long
+		Signature string
+		Expected  string
+	}{
+		{
+			TestName: "Short",
+			Template: DefaultCaptionTemplate,
+			Post:     captionPost{Title: "Title", Link: link},
+			Expected: "Title\n\n[🔗 Link](" + link + ")",
+		},
+		{
+			// 1024 - len("\n\n🔗 Link") = 1015 UTF-16 code units are left for the title
+			TestName: "Exactly The Limit",
+			Template: DefaultCaptionTemplate,
+			Post:     captionPost{Title: strings.Repeat("a", 1015), Link: link},
+			Expected: strings.Repeat("a", 1015) + "\n\n[🔗 Link](" + link + ")",
+		},
+		{
+			TestName: "Shortened Title",
+			Template: DefaultCaptionTemplate,
+			Post:     captionPost{Title: strings.Repeat("a", 2000), Link: link},
+			Expected: strings.Repeat("a", 1014) + "…\n\n[🔗 Link](" + link + ")",
+		},
+		{
+			// Each emoji is two UTF-16 code units
+			TestName: "Shortened Title With Emojis",
+			Template: DefaultCaptionTemplate,
+			Post:     captionPost{Title: strings.Repeat("😀", 1000), Link: link},
+			Expected: strings.Repeat("😀", 507) + "…\n\n[🔗 Link](" + link + ")",
+		},
+		{
+			// Both titles are shortened by ceil((2001 - 1024) / 2) + 1 for the ellipsis
+			TestName: "Two Titles",
+			Template: "{title} {title}",
+			Post:     captionPost{Title: strings.Repeat("b", 1000)},
+			Expected: strings.Repeat("b", 510) + "… " + strings.Repeat("b", 510) + "…",
+		},
+		{
+			TestName: "Escaped Shortened Title",
+			Template: "{title}",
+			Post:     captionPost{Title: strings.Repeat(".", 2000)},
+			Expected: strings.Repeat("\\.", 1023) + "…",
+		},
+		{
+			TestName:  "Cut",
+			Template:  "{title}\n{signature}",
+			Post:      captionPost{Title: "Title"},
+			Signature: strings.Repeat("-", 1100),
+			Expected:  "Title\n" + strings.Repeat("\\-", 1017) + "…",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			caption := renderCaption(test.Template, test.Post, test.Signature)
+			assert.Equal(t, test.Expected, caption)
+		})
+	}
+}
+
+func TestTruncateUTF16(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Text     string
+		N        int
+		Expected string
+	}{
+		{"Shorter", "abc", 10, "abc"},
+		{"Exact", "abc", 3, "abc"},
+		{"ASCII", "abcdef", 4, "abcd"},
+		{"Zero", "abc", 0, ""},
+		{"Whole Surrogate Pair", "a😀b", 3, "a😀"},
+		{"Half Surrogate Pair", "a😀b", 2, "a"},
+		{"Multibyte", "привет", 3, "при"},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Equal(t, test.Expected, truncateUTF16(test.Text, test.N))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"html"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
+	"github.com/lartie/RedditDownloaderBot/pkg/markdown"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+)
+
+// The choices of the number of comments and the depth of the threads in the settings
+var (
+	commentsCountChoices = []int{3, 5, 10}
+	commentsDepthChoices = []int{1, 2, 3}
+	// The choices of the number of parents which are sent with a comment
+	commentContextChoices = []int{0, 1, 3, 5}
+)
+
+// sendPostComments sends the top comments of the post in link after it. They are sent as messages
+// or a text file based on the settings of the user. replyTo can be zero.
+func (c *Client) sendPostComments(bot *gotgbot.Bot, link string, uid, chatID, replyTo int64) error {
+	user := c.userSettings(uid)
+	comments, fetchErr := c.RedditOauth.FetchComments(link, user.CommentsCount, user.CommentsDepth)
+	if fetchErr != nil {
+		// The user already knows about the post. Comments are extra, so just log the error.
+		log.Println("Cannot fetch the comments of", link, ":", fetchErr.NormalError)
+		return nil
+	}
+	if len(comments) == 0 {
+		return nil
+	}
+	title := tr(user.Lang, "comments.title")
+	blocks := append([]string{"<b>" + html.EscapeString(title) + "</b>"}, commentBlocks(comments)...)
+	if user.CommentsMode == settings.CommentsModeDocument {
+		opts := &gotgbot.SendDocumentOpts{}
+		if replyTo != 0 {
+			opts.ReplyParameters = &gotgbot.ReplyParameters{MessageId: replyTo}
+		}
+		_, err := bot.SendDocument(chatID, &gotgbot.FileReader{
+			Name: "comments.txt",
+			Data: strings.NewReader(markdown.PlainText(strings.Join(blocks, "\n\n"))),
+		}, opts)
+		return err
+	}
+	return c.sendTextBlocks(bot, title, blocks, uid, chatID, replyTo)
+}
+
+// commentBlocks converts each top level comment and its replies to an HTML block
+func commentBlocks(comments []reddit.Comment) []string {
+	blocks := make([]string, len(comments))
+	for i, comment := range comments {
+		var sb strings.Builder
+		writeComment(&sb, comment, 0)
+		blocks[i] = strings.TrimSuffix(sb.String(), "\n")
+	}
+	return blocks
+}
+
+// commentThreadBlocks converts a comment and its parents to HTML blocks. The first block is the
+// title of the post and the comment itself is marked, so it can be told apart from its parents.
+func commentThreadBlocks(thread reddit.CommentThread) []string {
+	var blocks []string
+	if thread.PostTitle != "" {
+		blocks = append(blocks, "<b>"+html.EscapeString(thread.PostTitle)+"</b>")
+	}
+	var sb strings.Builder
+	for i, parent := range thread.Parents {
+		writeCommentEntry(&sb, parent, i, false)
+	}
+	writeCommentEntry(&sb, thread.Comment, len(thread.Parents), true)
+	return append(blocks, strings.TrimSuffix(sb.String(), "\n"))
+}
+
+// writeComment writes a comment and its replies
+func writeComment(sb *strings.Builder, comment reddit.Comment, level int) {
+	writeCommentEntry(sb, comment, level, false)
+	for _, reply := range comment.Replies {
+		writeComment(sb, reply, level+1)
+	}
+}
+
+// writeCommentEntry writes the author, the score and the text of a comment without its replies.
+// Each line is prefixed with a bar for each level of nesting.
+func writeCommentEntry(sb *strings.Builder, comment reddit.Comment, level int, highlight bool) {
+	prefix := strings.Repeat("│ ", level)
+	author := comment.Author
+	if author != "[deleted]" {
+		author = "u/" + author
+	}
+	header := "<b>" + html.EscapeString(author) + "</b>"
+	if highlight {
+		header = "👉 <b><u>" + html.EscapeString(author) + "</u></b>"
+	}
+	sb.WriteString(prefix + header + " · ⬆️ " + strconv.FormatInt(comment.Score, 10) + "\n")
+	for _, line := range strings.Split(markdown.ToHTML(comment.Text), "\n") {
+		sb.WriteString(prefix + line + "\n")
+	}
+}
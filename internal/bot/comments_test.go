@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentBlocks(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Comments []reddit.Comment
+		Expected []string
+	}{
+		{
+			TestName: "No Comments",
+			Expected: []string{},
+		},
+		{
+			TestName: "Single",
+			Comments: []reddit.Comment{{Author: "spez", Score: 42, Text: "Hello"}},
+			Expected: []string{"<b>u/spez</b> · ⬆️ 42\nHello"},
+		},
+		{
+			TestName: "Escaped",
+			Comments: []reddit.Comment{{Author: "[deleted]", Score: -3, Text: "a < b & c"}},
+			Expected: []string{"<b>[deleted]</b> · ⬆️ -3\na &lt; b &amp; c"},
+		},
+		{
+			TestName: "Replies",
+			Comments: []reddit.Comment{
+				{
+					Author: "a",
+					Score:  10,
+					Text:   "First\n\nSecond",
+					Replies: []reddit.Comment{
+						{Author: "b", Score: 5, Text: "Reply", Replies: []reddit.Comment{{Author: "c", Score: 1, Text: "Nested"}}},
+						{Author: "d", Score: 2, Text: "Other"},
+					},
+				},
+				{Author: "e", Score: 7, Text: "Top"},
+			},
+			Expected: []string{
+				"<b>u/a</b> · ⬆️ 10\nFirst\n\nSecond\n" +
+					"│ <b>u/b</b> · ⬆️ 5\n│ Reply\n" +
+					"│ │ <b>u/c</b> · ⬆️ 1\n│ │ Nested\n" +
+					"│ <b>u/d</b> · ⬆️ 2\n│ Other",
+				"<b>u/e</b> · ⬆️ 7\nTop",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Equal(t, test.Expected, commentBlocks(test.Comments))
+		})
+	}
+}
+
+func TestCommentThreadBlocks(t *testing.T) {
+	thread := reddit.CommentThread{
+		PostTitle: "Title & more",
+		Parents:   []reddit.Comment{{Author: "a", Score: 3, Text: "Parent"}},
+		Comment:   reddit.Comment{Author: "b", Score: 1, Text: "Comment"},
+	}
+	assert.Equal(t, []string{
+		"<b>Title &amp; more</b>",
+		"<b>u/a</b> · ⬆️ 3\nParent\n│ 👉 <b><u>u/b</u></b> · ⬆️ 1\n│ Comment",
+	}, commentThreadBlocks(thread))
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+)
+
+// DefaultMaxLinksPerMessage is the default maximum number of links which are processed from a message
+const DefaultMaxLinksPerMessage = 10
+
+// postNotFetchedErr is returned from sendPost when the post cannot be fetched. The user has
+// already been told about it.
+var postNotFetchedErr = errors.New("post not fetched")
+
+// linksLimit gets the maximum number of links which are processed from a message
+func (c *Client) linksLimit() int {
+	if c.MaxLinksPerMessage <= 0 {
+		return DefaultMaxLinksPerMessage
+	}
+	return c.MaxLinksPerMessage
+}
+
+// handleLinks sends all the Reddit links in a message (including the links behind texts) one by
+// one in their order. If some of several links fail, a summary of them is sent at the end.
+func (c *Client) handleLinks(bot *gotgbot.Bot, ctx *ext.Context) error {
+	links := reddit.ExtractLinks(expandTextLinks(ctx.Message.Text, ctx.Message.Entities))
+	if len(links) == 0 {
+		// Let StartFetch explain the problem
+		return c.fetchPostDetailsAndSend(bot, ctx)
+	}
+	uid := ctx.Message.From.Id
+	chatID := ctx.EffectiveChat.Id
+	replyTo := ctx.EffectiveMessage.MessageId
+	if len(links) == 1 {
+		err := c.sendPostWithComments(bot, links[0], uid, chatID, replyTo)
+		if errors.Is(err, postNotFetchedErr) {
+			return nil
+		}
+		return err
+	}
+	user := c.userSettings(uid)
+	links, notice := capLinks(user.Lang, links, c.linksLimit())
+	if notice != "" {
+		if _, err := ctx.EffectiveMessage.Reply(bot, notice, nil); err != nil {
+			return err
+		}
+	}
+	var failed []string
+	for i, link := range links {
+		if i > 0 {
+			time.Sleep(listingPostInterval)
+		}
+		if err := c.sendPostWithComments(bot, link, uid, chatID, replyTo); err != nil {
+			if !errors.Is(err, postNotFetchedErr) {
+				log.Println("Cannot send the post", link, ":", err)
+			}
+			failed = append(failed, link)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	_, err := ctx.EffectiveMessage.Reply(bot, failedLinksSummary(user.Lang, failed, len(links)), &gotgbot.SendMessageOpts{
+		LinkPreviewOptions: &gotgbot.LinkPreviewOptions{IsDisabled: true},
+	})
+	return err
+}
+
+// capLinks keeps the first limit links. If some links are dropped, notice tells the user about it.
+func capLinks(l settings.Lang, links []string, limit int) (capped []string, notice string) {
+	if len(links) <= limit {
+		return links, ""
+	}
+	return links[:limit], fmt.Sprintf(tr(l, "links.capped"), limit, len(links))
+}
+
+// failedLinksSummary lists the links which could not be sent out of total links
+func failedLinksSummary(l settings.Lang, failed []string, total int) string {
+	return fmt.Sprintf(tr(l, "links.failed"), len(failed), total) + "\n" + strings.Join(failed, "\n")
+}
+
+// sendPostWithComments sends a post and then its comments if the user wants them
+func (c *Client) sendPostWithComments(bot *gotgbot.Bot, link string, uid, chatID, replyTo int64) error {
+	if err := c.sendPost(bot, link, uid, chatID, replyTo); err != nil {
+		return err
+	}
+	if user := c.userSettings(uid); user.CommentsMode != settings.CommentsModeOff {
+		return c.sendPostComments(bot, link, uid, chatID, replyTo)
+	}
+	return nil
+}
+
+// expandTextLinks replaces the texts of the text links (links behind a text) of a message with
+// their URLs, so they can be found like the other links of the text. The offsets of the entities
+// are in UTF-16 code units.
+func expandTextLinks(text string, entities []gotgbot.MessageEntity) string {
+	encoded := utf16.Encode([]rune(text))
+	// Replace from the end, so the offsets of the previous entities do not change
+	for i := len(entities) - 1; i >= 0; i-- {
+		entity := entities[i]
+		if entity.Type != "text_link" || entity.Offset < 0 || entity.Length < 0 || entity.Offset+entity.Length > int64(len(encoded)) {
+			continue
+		}
+		replacement := utf16.Encode([]rune(" " + entity.Url + " "))
+		rest := encoded[entity.Offset+entity.Length:]
+		encoded = append(append(encoded[:entity.Offset:entity.Offset], replacement...), rest...)
+	}
+	return string(utf16.Decode(encoded))
+}
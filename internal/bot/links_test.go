@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandTextLinks(t *testing.T) {
+	const link = "https://redd.it/a"
+	textLink := func(offset, length int64) gotgbot.MessageEntity {
+		return gotgbot.MessageEntity{Type: "text_link", Offset: offset, Length: length, Url: link}
+	}
+	tests := []struct {
+		TestName string
+		Text     string
+		Entities []gotgbot.MessageEntity
+		Expected string
+	}{
+		{
+			TestName: "No Entities",
+			Text:     "https://redd.it/b",
+			Expected: "https://redd.it/b",
+		},
+		{
+			TestName: "Text Link",
+			Text:     "see this post",
+			Entities: []gotgbot.MessageEntity{textLink(4, 4)},
+			Expected: "see  " + link + "  post",
+		},
+		{
+			// The emoji is two UTF-16 code units
+			TestName: "After Emoji",
+			Text:     "😀 here",
+			Entities: []gotgbot.MessageEntity{textLink(3, 4)},
+			Expected: "😀  " + link + " ",
+		},
+		{
+			TestName: "Emoji In Link Text",
+			Text:     "a 😀😀 b",
+			Entities: []gotgbot.MessageEntity{textLink(2, 4)},
+			Expected: "a  " + link + "  b",
+		},
+		{
+			TestName: "Cyrillic",
+			Text:     "смотри тут",
+			Entities: []gotgbot.MessageEntity{textLink(7, 3)},
+			Expected: "смотри  " + link + " ",
+		},
+		{
+			// The offsets of the second link are not changed by the first one
+			TestName: "Two Links",
+			Text:     "one 😀 two",
+			Entities: []gotgbot.MessageEntity{
+				textLink(0, 3),
+				{Type: "text_link", Offset: 7, Length: 3, Url: "https://redd.it/c"},
+			},
+			Expected: " " + link + "  😀  https://redd.it/c ",
+		},
+		{
+			TestName: "Other Entities",
+			Text:     "bold https://redd.it/b",
+			Entities: []gotgbot.MessageEntity{{Type: "bold", Offset: 0, Length: 4}, {Type: "url", Offset: 5, Length: 17}},
+			Expected: "bold https://redd.it/b",
+		},
+		{
+			TestName: "Out Of Range",
+			Text:     "short",
+			Entities: []gotgbot.MessageEntity{textLink(3, 10), textLink(-1, 2)},
+			Expected: "short",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Equal(t, test.Expected, expandTextLinks(test.Text, test.Entities))
+		})
+	}
+}
+
+func TestCapLinks(t *testing.T) {
+	links := []string{"https://redd.it/a", "https://redd.it/b", "https://redd.it/c"}
+	tests := []struct {
+		TestName       string
+		Lang           settings.Lang
+		Limit          int
+		Expected       []string
+		ExpectedNotice string
+	}{
+		{
+			TestName: "Under The Limit",
+			Lang:     settings.LangEN,
+			Limit:    10,
+			Expected: links,
+		},
+		{
+			TestName: "Exactly The Limit",
+			Lang:     settings.LangEN,
+			Limit:    3,
+			Expected: links,
+		},
+		{
+			TestName:       "Capped",
+			Lang:           settings.LangEN,
+			Limit:          2,
+			Expected:       links[:2],
+			ExpectedNotice: "Only the first 2 of the 3 links are sent.",
+		},
+		{
+			TestName:       "Capped In Russian",
+			Lang:           settings.LangRU,
+			Limit:          1,
+			Expected:       links[:1],
+			ExpectedNotice: "Будут отправлены только первые 1 из 3 ссылок.",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			capped, notice := capLinks(test.Lang, links, test.Limit)
+			assert.Equal(t, test.Expected, capped)
+			assert.Equal(t, test.ExpectedNotice, notice)
+		})
+	}
+}
+
+func TestFailedLinksSummary(t *testing.T) {
+	failed := []string{"https://redd.it/a", "https://redd.it/c"}
+	assert.Equal(t, "2 of 3 links could not be sent:\nhttps://redd.it/a\nhttps://redd.it/c", failedLinksSummary(settings.LangEN, failed, 3))
+	assert.Equal(t, "Не удалось отправить 2 из 3 ссылок:\nhttps://redd.it/a\nhttps://redd.it/c", failedLinksSummary(settings.LangRU, failed, 3))
+}
+
+func TestLinksLimit(t *testing.T) {
+	assert.Equal(t, DefaultMaxLinksPerMessage, (&Client{}).linksLimit())
+	assert.Equal(t, DefaultMaxLinksPerMessage, (&Client{MaxLinksPerMessage: -1}).linksLimit())
+	assert.Equal(t, 3, (&Client{MaxLinksPerMessage: 3}).linksLimit())
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+)
+
+// DefaultMaxListingPosts is the default maximum number of posts which a listing command sends
+const DefaultMaxListingPosts = 10
+
+// defaultListingPosts is the number of posts which a listing command sends if no number is given
+const defaultListingPosts = 5
+
+// listingPostInterval is the time between sending the posts of a listing. It keeps the bot
+// under the rate limits of Reddit and Telegram.
+const listingPostInterval = time.Second
+
+// listingCommand is a /top, /hot or /new command like "/top r/EarthPorn week 10"
+type listingCommand struct {
+	Sort      reddit.ListingSort
+	Subreddit string
+	// The period of the top posts. Empty means the default of Reddit.
+	Period string
+	Limit  int
+}
+
+// parseListingCommand parses a listing command. isListing is false if the text is not a listing
+// command and valid is false if its arguments are invalid. The number of posts is capped at maxLimit
+// or DefaultMaxListingPosts if it's not positive.
+func parseListingCommand(text string, maxLimit int) (command listingCommand, isListing, valid bool) {
+	if maxLimit <= 0 {
+		maxLimit = DefaultMaxListingPosts
+	}
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return
+	}
+	// Commands in groups can be like /top@bot
+	name, _, _ := strings.Cut(fields[0], "@")
+	switch name {
+	case "/top":
+		command.Sort = reddit.ListingSortTop
+	case "/hot":
+		command.Sort = reddit.ListingSortHot
+	case "/new":
+		command.Sort = reddit.ListingSortNew
+	default:
+		return
+	}
+	isListing = true
+	args := fields[1:]
+	if len(args) == 0 || len(args) > 3 {
+		return
+	}
+	command.Subreddit, args = strings.TrimPrefix(strings.TrimPrefix(args[0], "/"), "r/"), args[1:]
+	command.Limit = min(defaultListingPosts, maxLimit)
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+			command.Limit = min(n, maxLimit)
+		} else if command.Sort == reddit.ListingSortTop && command.Period == "" && slices.Contains(reddit.ListingPeriods, strings.ToLower(arg)) {
+			command.Period = strings.ToLower(arg)
+		} else {
+			return
+		}
+	}
+	valid = true
+	return
+}
+
+// handleListingCommand sends the posts of a subreddit listing one by one like they were sent by
+// the user. The progress is shown by editing a message. Each user can only run one listing command
+// at a time.
+func (c *Client) handleListingCommand(bot *gotgbot.Bot, ctx *ext.Context, command listingCommand) error {
+	uid := ctx.Message.From.Id
+	chatID := ctx.EffectiveChat.Id
+	user := c.userSettings(uid)
+	if _, running := c.runningListings.LoadOrStore(uid, struct{}{}); running {
+		_, err := ctx.EffectiveMessage.Reply(bot, tr(user.Lang, "listing.busy"), nil)
+		return err
+	}
+	defer c.runningListings.Delete(uid)
+	setProgress, err := replyProgress(bot, ctx, fmt.Sprintf(tr(user.Lang, "listing.fetching"), command.Subreddit))
+	if err != nil {
+		return err
+	}
+	links, fetchErr := c.RedditOauth.FetchListing(command.Subreddit, command.Sort, command.Period, command.Limit)
+	if fetchErr != nil {
+		if fetchErr.NormalError != "" {
+			log.Println("Cannot fetch the listing of", command.Subreddit, ":", fetchErr.NormalError)
+		}
+		setProgress(fetchErr.BotError)
+		return nil
+	}
+	if len(links) == 0 {
+		setProgress(tr(user.Lang, "listing.empty"))
+		return nil
+	}
+	failed := 0
+	for i, link := range links {
+		if i > 0 {
+			time.Sleep(listingPostInterval)
+		}
+		setProgress(fmt.Sprintf(tr(user.Lang, "listing.progress"), i+1, len(links)))
+		if err := c.sendPost(bot, link, uid, chatID, 0); err != nil {
+			log.Println("Cannot send the post", link, "of the listing:", err)
+			failed++
+		}
+	}
+	setProgress(fmt.Sprintf(tr(user.Lang, "listing.done"), len(links)-failed, len(links)))
+	return nil
+}
+
+// replyProgress replies to the message of the user with a message which shows the progress of a
+// long command. Returns a function which changes the progress.
+func replyProgress(bot *gotgbot.Bot, ctx *ext.Context, text string) (func(string), error) {
+	progress, err := ctx.EffectiveMessage.Reply(bot, text, nil)
+	if err != nil {
+		return nil, err
+	}
+	return func(text string) {
+		_, _, err := bot.EditMessageText(text, &gotgbot.EditMessageTextOpts{ChatId: progress.Chat.Id, MessageId: progress.MessageId})
+		if err != nil {
+			log.Println("Cannot edit the progress message:", err)
+		}
+	}, nil
+}
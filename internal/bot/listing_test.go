@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseListingCommand(t *testing.T) {
+	tests := []struct {
+		TestName          string
+		Text              string
+		MaxLimit          int
+		Expected          listingCommand
+		ExpectedIsListing bool
+		ExpectedValid     bool
+	}{
+		{
+			TestName:          "Top With Everything",
+			Text:              "/top r/EarthPorn week 10",
+			MaxLimit:          20,
+			Expected:          listingCommand{Sort: reddit.ListingSortTop, Subreddit: "EarthPorn", Period: "week", Limit: 10},
+			ExpectedIsListing: true,
+			ExpectedValid:     true,
+		},
+		{
+			TestName:          "Default Limit",
+			Text:              "/hot pics",
+			MaxLimit:          20,
+			Expected:          listingCommand{Sort: reddit.ListingSortHot, Subreddit: "pics", Limit: defaultListingPosts},
+			ExpectedIsListing: true,
+			ExpectedValid:     true,
+		},
+		{
+			TestName:          "Default Limit Over The Max",
+			Text:              "/new /r/pics",
+			MaxLimit:          3,
+			Expected:          listingCommand{Sort: reddit.ListingSortNew, Subreddit: "pics", Limit: 3},
+			ExpectedIsListing: true,
+			ExpectedValid:     true,
+		},
+		{
+			TestName:          "Capped Limit",
+			Text:              "/top pics 100 ALL",
+			MaxLimit:          20,
+			Expected:          listingCommand{Sort: reddit.ListingSortTop, Subreddit: "pics", Period: "all", Limit: 20},
+			ExpectedIsListing: true,
+			ExpectedValid:     true,
+		},
+		{
+			TestName:          "Default Max Limit",
+			Text:              "/top pics 100",
+			MaxLimit:          0,
+			Expected:          listingCommand{Sort: reddit.ListingSortTop, Subreddit: "pics", Limit: DefaultMaxListingPosts},
+			ExpectedIsListing: true,
+			ExpectedValid:     true,
+		},
+		{
+			TestName:          "Group Command",
+			Text:              "/hot@bot pics 2",
+			MaxLimit:          20,
+			Expected:          listingCommand{Sort: reddit.ListingSortHot, Subreddit: "pics", Limit: 2},
+			ExpectedIsListing: true,
+			ExpectedValid:     true,
+		},
+		{
+			TestName:          "Period Of Hot",
+			Text:              "/hot pics week",
+			MaxLimit:          20,
+			ExpectedIsListing: true,
+		},
+		{
+			TestName:          "Two Periods",
+			Text:              "/top pics week day",
+			MaxLimit:          20,
+			ExpectedIsListing: true,
+		},
+		{
+			TestName:          "Zero Posts",
+			Text:              "/top pics 0",
+			MaxLimit:          20,
+			ExpectedIsListing: true,
+		},
+		{
+			TestName:          "No Subreddit",
+			Text:              "/top",
+			MaxLimit:          20,
+			ExpectedIsListing: true,
+		},
+		{
+			TestName:          "Too Many Arguments",
+			Text:              "/top pics week 10 more",
+			MaxLimit:          20,
+			ExpectedIsListing: true,
+		},
+		{
+			TestName: "Other Command",
+			Text:     "/topics pics",
+			MaxLimit: 20,
+		},
+		{
+			TestName: "Empty",
+			Text:     "  ",
+			MaxLimit: 20,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			command, isListing, valid := parseListingCommand(test.Text, test.MaxLimit)
+			assert.Equal(t, test.ExpectedIsListing, isListing)
+			assert.Equal(t, test.ExpectedValid, valid)
+			if test.ExpectedValid {
+				assert.Equal(t, test.Expected, command)
+			}
+		})
+	}
+}
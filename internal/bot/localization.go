@@ -1,46 +1,15 @@
 package bot
 
 import (
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
 	"log"
-	"sync"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 )
 
-type Lang string
-
-const (
-	LangEN Lang = "en"
-	LangRU Lang = "ru"
-)
-
-var langPrefs = struct {
-	mu    sync.RWMutex
-	byUID map[int64]Lang
-}{
-	byUID: make(map[int64]Lang),
-}
-
-func setUserLang(userID int64, l Lang) {
-	langPrefs.mu.Lock()
-	langPrefs.byUID[userID] = l
-	langPrefs.mu.Unlock()
-}
-
-func getUserLang(userID int64) Lang {
-	langPrefs.mu.RLock()
-	l, ok := langPrefs.byUID[userID]
-	langPrefs.mu.RUnlock()
-	if ok {
-		return l
-	}
-	// default to EN if not set
-	return LangEN
-}
-
 // i18n dictionaries
-var i18n = map[Lang]map[string]string{
-	LangEN: {
+var i18n = map[settings.Lang]map[string]string{
+	settings.LangEN: {
 		"settings.title":                     "⚙️ Settings",
 		"settings.current_mode":              "• Download mode: *%s*",
 		"settings.current_lang":              "• Language: %s",
@@ -72,6 +41,18 @@ var i18n = map[Lang]map[string]string{
 		"settings.link.saved":   "Saved link: %s",
 		"link.on":               "Yes",
 		"link.off":              "No",
+		"settings.poll.caption": "Polls:",
+		"settings.poll.saved":   "Saved polls: %s",
+		"poll.native":           "Telegram poll",
+		"poll.text":             "Text with results",
+		"poll.ended":            "📊 Poll ended",
+		"poll.open":             "📊 Poll is open",
+		"poll.open_until":       "📊 Poll is open until %s",
+		"poll.votes":            "%d votes",
+		"poll.total":            "Total votes: %d",
+		"poll.hidden":           "Results are hidden until the poll ends.",
+		"poll.failed":           "I couldn’t send this poll.",
+		"poll.failed_link":      "I couldn’t send this poll.\nHere is the link: %s",
 		"album.ask":             "Send album as media or files?",
 		"album.button.media":    "Media",
 		"album.button.file":     "Files",
@@ -81,15 +62,68 @@ var i18n = map[Lang]map[string]string{
 		"err.panic":             "Something went wrong (panic).",
 		"err.broken_callback":   "Broken callback data.",
 		"err.resend_link":       "Please resend the link.",
+		"err.outdated_callback": "The bot is being updated right now, so this button can't be used. Please resend the link.",
 		"err.internal":          "Internal error.",
 		"unknown.type":          "Unknown type (please report it on GitHub).",
 		"cmd.start":             "Welcome! This bot downloads media from Reddit posts — just send me a link, for example:\nhttps://www.reddit.com/r/TheCatternet/comments/1nrw9xt/she_grow_up/\n\nCommands:\n/start — start\n/settings — settings\n/help — help",
-		"cmd.help":              "Send a Reddit link. Text becomes text; images/videos get uploaded with title & link.",
+		"cmd.help":              "Send a Reddit link. Text becomes text; images/videos get uploaded with a caption which you can change in /settings.\n\nSend many posts of a subreddit with /top r/EarthPorn week 10, /hot r/videos 5 or /new r/pics. Send the media posts of a user with /user spez 20.",
 		"cmd.desc.start":        "Start the bot",
 		"cmd.desc.help":         "How to use the bot",
 		"cmd.desc.settings":     "Open settings",
+
+		"settings.long_text.caption": "Long texts:",
+		"settings.long_text.saved":   "Saved long texts: %s",
+		"long_text.split":            "Several messages",
+		"long_text.telegraph":        "Telegraph page",
+		"long_text.read":             "📖 Read on Telegraph",
+
+		"settings.comments.caption": "Comments:",
+		"settings.comments.saved":   "Saved comments: %s",
+		"comments.off":              "Off",
+		"comments.message":          "Messages",
+		"comments.document":         "Text file",
+		"comments.count":            "%d comments",
+		"comments.depth":            "depth %d",
+		"comments.title":            "💬 Top comments",
+
+		"settings.context.caption": "Comment context:",
+		"settings.context.saved":   "Saved comment context: %s",
+		"context.off":              "Only the comment",
+		"context.parents":          "%d parents",
+
+		"cmd.desc.top":     "Top posts of a subreddit",
+		"cmd.desc.hot":     "Hot posts of a subreddit",
+		"cmd.desc.new":     "New posts of a subreddit",
+		"listing.usage":    "Usage: /top r/subreddit [hour|day|week|month|year|all] [count]\nor /hot r/subreddit [count] and /new r/subreddit [count]",
+		"listing.busy":     "Please wait until the posts of your previous command are sent.",
+		"listing.fetching": "Getting the posts of r/%s…",
+		"listing.progress": "Sending post %d of %d…",
+		"listing.done":     "Done: sent %d of %d posts.",
+		"listing.empty":    "No posts found.",
+
+		"cmd.desc.user": "Media posts of a user",
+		"user.usage":    "Usage: /user username [count]",
+		"user.fetching": "Getting the posts of u/%s…",
+		"user.progress": "Sending media %d–%d of %d…",
+		"user.videos":   "Sending video %d of %d…",
+		"user.done":     "Done: sent %d posts of u/%s.",
+
+		"links.capped": "Only the first %d of the %d links are sent.",
+		"links.failed": "%d of %d links could not be sent:",
+
+		"settings.caption.caption": "Caption template",
+		"settings.caption.user":    "Your caption template:",
+		"settings.caption.chat":    "Caption template of this chat:",
+		"settings.caption.saved":   "Caption template saved.",
+		"caption.preview":          "Preview:",
+		"caption.placeholders":     "Placeholders: {title} {subreddit} {author} {score} {flair} {created} {link} {signature}",
+		"caption.edit":             "✏️ Edit",
+		"caption.reset":            "↩️ Reset",
+		"caption.send":             "Send the new caption template. Send any command to cancel.",
+		"caption.invalid":          "The template must not be empty or longer than %d characters. Send another one.",
+		"caption.admins_only":      "Only the admins of this chat can change its caption template.",
 	},
-	LangRU: {
+	settings.LangRU: {
 		"settings.title":                     "⚙️ Настройки",
 		"settings.current_mode":              "• Режим скачивания: *%s*",
 		"settings.current_lang":              "• Язык: %s",
@@ -121,6 +155,18 @@ var i18n = map[Lang]map[string]string{
 		"settings.link.saved":   "Настройка сохранена: %s",
 		"link.on":               "Да",
 		"link.off":              "Нет",
+		"settings.poll.caption": "Опросы:",
+		"settings.poll.saved":   "Настройка сохранена: %s",
+		"poll.native":           "Опрос Telegram",
+		"poll.text":             "Текст с результатами",
+		"poll.ended":            "📊 Опрос завершён",
+		"poll.open":             "📊 Опрос идёт",
+		"poll.open_until":       "📊 Опрос идёт до %s",
+		"poll.votes":            "голосов: %d",
+		"poll.total":            "Всего голосов: %d",
+		"poll.hidden":           "Результаты скрыты до окончания опроса.",
+		"poll.failed":           "Не удалось отправить этот опрос.",
+		"poll.failed_link":      "Не удалось отправить этот опрос.\nВот ссылка: %s",
 		"album.ask":             "Отправить альбом как медиа или файлами?",
 		"album.button.media":    "Медиа",
 		"album.button.file":     "Файлы",
@@ -130,57 +176,110 @@ var i18n = map[Lang]map[string]string{
 		"err.panic":             "Что-то пошло не так (panic).",
 		"err.broken_callback":   "Некорректные callback-данные.",
 		"err.resend_link":       "Пришлите ссылку ещё раз.",
+		"err.outdated_callback": "Бот сейчас обновляется, поэтому эта кнопка не работает. Пришлите ссылку ещё раз.",
 		"err.internal":          "Внутренняя ошибка.",
 		"unknown.type":          "Неизвестный тип (сообщите в репозитории).",
 		"cmd.start":             "Добро пожаловать! Бот умеет скачивать медиа из постов Reddit — просто пришли мне ссылку, например:\nhttps://www.reddit.com/r/TheCatternet/comments/1nrw9xt/she_grow_up/\n\nКоманды:\n/start — старт\n/settings — настройки\n/help — помощь",
-		"cmd.help":              "Пришлите ссылку на Reddit. Текст — текстом, картинки/видео — загружу с заголовком и ссылкой.",
+		"cmd.help":              "Пришлите ссылку на Reddit. Текст — текстом, картинки/видео — загружу с подписью, которую можно изменить в /settings.\n\nНесколько постов сабреддита: /top r/EarthPorn week 10, /hot r/videos 5 или /new r/pics. Медиапосты пользователя: /user spez 20.",
 		"cmd.desc.start":        "Запустить бота",
 		"cmd.desc.help":         "Как пользоваться ботом",
 		"cmd.desc.settings":     "Открыть настройки",
+
+		"settings.long_text.caption": "Длинные тексты:",
+		"settings.long_text.saved":   "Настройка сохранена: %s",
+		"long_text.split":            "Несколько сообщений",
+		"long_text.telegraph":        "Страница Telegraph",
+		"long_text.read":             "📖 Читать в Telegraph",
+
+		"settings.comments.caption": "Комментарии:",
+		"settings.comments.saved":   "Настройка сохранена: %s",
+		"comments.off":              "Выкл",
+		"comments.message":          "Сообщениями",
+		"comments.document":         "Текстовым файлом",
+		"comments.count":            "%d комм.",
+		"comments.depth":            "глубина %d",
+		"comments.title":            "💬 Лучшие комментарии",
+
+		"settings.context.caption": "Контекст комментария:",
+		"settings.context.saved":   "Настройка сохранена: %s",
+		"context.off":              "Только комментарий",
+		"context.parents":          "Родителей: %d",
+
+		"cmd.desc.top":     "Лучшие посты сабреддита",
+		"cmd.desc.hot":     "Горячие посты сабреддита",
+		"cmd.desc.new":     "Новые посты сабреддита",
+		"listing.usage":    "Использование: /top r/subreddit [hour|day|week|month|year|all] [количество]\nили /hot r/subreddit [количество] и /new r/subreddit [количество]",
+		"listing.busy":     "Подождите, пока отправятся посты предыдущей команды.",
+		"listing.fetching": "Получаю посты r/%s…",
+		"listing.progress": "Отправляю пост %d из %d…",
+		"listing.done":     "Готово: отправлено %d из %d постов.",
+		"listing.empty":    "Посты не найдены.",
+
+		"cmd.desc.user": "Медиапосты пользователя",
+		"user.usage":    "Использование: /user username [количество]",
+		"user.fetching": "Получаю посты u/%s…",
+		"user.progress": "Отправляю медиа %d–%d из %d…",
+		"user.videos":   "Отправляю видео %d из %d…",
+		"user.done":     "Готово: отправлено постов u/%[2]s: %[1]d.",
+
+		"links.capped": "Будут отправлены только первые %d из %d ссылок.",
+		"links.failed": "Не удалось отправить %d из %d ссылок:",
+
+		"settings.caption.caption": "Шаблон подписи",
+		"settings.caption.user":    "Ваш шаблон подписи:",
+		"settings.caption.chat":    "Шаблон подписи этого чата:",
+		"settings.caption.saved":   "Шаблон подписи сохранён.",
+		"caption.preview":          "Предпросмотр:",
+		"caption.placeholders":     "Подстановки: {title} {subreddit} {author} {score} {flair} {created} {link} {signature}",
+		"caption.edit":             "✏️ Изменить",
+		"caption.reset":            "↩️ Сбросить",
+		"caption.send":             "Пришлите новый шаблон подписи. Чтобы отменить, пришлите любую команду.",
+		"caption.invalid":          "Шаблон не должен быть пустым или длиннее %d символов. Пришлите другой.",
+		"caption.admins_only":      "Шаблон подписи этого чата могут менять только его администраторы.",
 	},
 }
 
-func tr(l Lang, key string) string {
+func tr(l settings.Lang, key string) string {
 	if m, ok := i18n[l]; ok {
 		if s, ok2 := m[key]; ok2 {
 			return s
 		}
 	}
 	// fallback to EN
-	v := i18n[LangEN][key]
+	v := i18n[settings.LangEN][key]
 	if v == "" {
 		return key
 	}
 	return v
 }
 
-func t(uid int64, key string) string {
-	return tr(getUserLang(uid), key)
-}
-
 // ----- Bot commands (per-language) -----
-func commandsFor(lang Lang) []gotgbot.BotCommand {
+func commandsFor(lang settings.Lang) []gotgbot.BotCommand {
 	return []gotgbot.BotCommand{
 		{Command: "start", Description: tr(lang, "cmd.desc.start")},
 		{Command: "settings", Description: tr(lang, "cmd.desc.settings")},
 		{Command: "help", Description: tr(lang, "cmd.desc.help")},
+		{Command: "top", Description: tr(lang, "cmd.desc.top")},
+		{Command: "hot", Description: tr(lang, "cmd.desc.hot")},
+		{Command: "new", Description: tr(lang, "cmd.desc.new")},
+		{Command: "user", Description: tr(lang, "cmd.desc.user")},
 	}
 }
 
 func installCommands(bot *gotgbot.Bot) {
-	if _, err := bot.SetMyCommands(commandsFor(LangEN), &gotgbot.SetMyCommandsOpts{
+	if _, err := bot.SetMyCommands(commandsFor(settings.LangEN), &gotgbot.SetMyCommandsOpts{
 		Scope:        gotgbot.BotCommandScopeDefault{},
 		LanguageCode: "",
 	}); err != nil {
 		log.Println("SetMyCommands (default) failed:", err)
 	}
-	if _, err := bot.SetMyCommands(commandsFor(LangEN), &gotgbot.SetMyCommandsOpts{
+	if _, err := bot.SetMyCommands(commandsFor(settings.LangEN), &gotgbot.SetMyCommandsOpts{
 		Scope:        gotgbot.BotCommandScopeDefault{},
 		LanguageCode: "en",
 	}); err != nil {
 		log.Println("SetMyCommands (en) failed:", err)
 	}
-	if _, err := bot.SetMyCommands(commandsFor(LangRU), &gotgbot.SetMyCommandsOpts{
+	if _, err := bot.SetMyCommands(commandsFor(settings.LangRU), &gotgbot.SetMyCommandsOpts{
 		Scope:        gotgbot.BotCommandScopeDefault{},
 		LanguageCode: "ru",
 	}); err != nil {
@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
+	"github.com/lartie/RedditDownloaderBot/pkg/markdown"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"log"
+	"strings"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+)
+
+// Limits of Telegram polls
+const (
+	maxPollQuestionLength = 300
+	maxPollOptionLength   = 100
+	minPollOptions        = 2
+	maxPollOptions        = 10
+)
+
+// canSendNativePoll checks if a Reddit poll can be sent as a Telegram poll.
+// Long texts are truncated, but the number of options cannot be changed.
+func canSendNativePoll(poll reddit.FetchResultPoll) bool {
+	return poll.Title != "" && len(poll.Options) >= minPollOptions && len(poll.Options) <= maxPollOptions
+}
+
+// handlePollUpload sends a Reddit poll as a non-anonymous Telegram poll. The poll is closed if
+// the Reddit poll has ended. The description and the link of post are sent as a reply to the poll.
+func (c *Client) handlePollUpload(bot *gotgbot.Bot, poll reddit.FetchResultPoll, postUrl string, uid, chatID, replyTo int64) error {
+	options := make([]gotgbot.InputPollOption, len(poll.Options))
+	for i, option := range poll.Options {
+		options[i] = gotgbot.InputPollOption{Text: truncateText(option.Text, maxPollOptionLength)}
+	}
+	opts := &gotgbot.SendPollOpts{
+		IsAnonymous: false,
+		IsClosed:    poll.Ended(),
+	}
+	if replyTo != 0 {
+		opts.ReplyParameters = &gotgbot.ReplyParameters{MessageId: replyTo}
+	}
+	sentMessage, err := bot.SendPoll(chatID, truncateText(poll.Title, maxPollQuestionLength), options, opts)
+	if err != nil {
+		log.Println("Unable to send the poll of post", postUrl, ":", err)
+		user := c.userSettings(uid)
+		text := tr(user.Lang, "poll.failed")
+		if postUrl != "" {
+			text = fmt.Sprintf(tr(user.Lang, "poll.failed_link"), postUrl)
+		}
+		_, err = bot.SendMessage(chatID, text, nil)
+		return err
+	}
+	return c.sendTextBlocks(bot, poll.Title, appendLinkBlockIfNeeded(markdown.Blocks(poll.Description), postUrl), uid, chatID, sentMessage.MessageId)
+}
+
+// pollSummary converts a poll to HTML blocks which contain the votes and the percentage of each
+// option. The description is converted like the texts of the other posts.
+func pollSummary(l settings.Lang, poll reddit.FetchResultPoll) []string {
+	blocks := []string{"<b>" + html.EscapeString(poll.Title) + "</b>"}
+	blocks = append(blocks, markdown.Blocks(poll.Description)...)
+	// State of the poll
+	var sb strings.Builder
+	switch {
+	case poll.Ended():
+		sb.WriteString(html.EscapeString(tr(l, "poll.ended")))
+	case !poll.EndTime.IsZero():
+		sb.WriteString(html.EscapeString(fmt.Sprintf(tr(l, "poll.open_until"), poll.EndTime.Format("2006-01-02 15:04 UTC"))))
+	default:
+		sb.WriteString(html.EscapeString(tr(l, "poll.open")))
+	}
+	// Options
+	hasVotes := poll.HasVotes()
+	for _, option := range poll.Options {
+		line := "• " + option.Text
+		if hasVotes {
+			line += " — " + fmt.Sprintf(tr(l, "poll.votes"), option.Votes)
+			if poll.TotalVotes > 0 {
+				line += fmt.Sprintf(" (%.1f%%)", float64(option.Votes)*100/float64(poll.TotalVotes))
+			}
+		}
+		sb.WriteString("\n" + html.EscapeString(line))
+	}
+	blocks = append(blocks, sb.String())
+	// Total
+	total := html.EscapeString(fmt.Sprintf(tr(l, "poll.total"), poll.TotalVotes))
+	if !hasVotes {
+		total += "\n" + html.EscapeString(tr(l, "poll.hidden"))
+	}
+	return append(blocks, total)
+}
+
+// truncateText cuts a text to at most maxLength characters. An ellipsis is added if the text is cut.
+func truncateText(text string, maxLength int) string {
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+	return string(runes[:maxLength-1]) + "…"
+}
@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPollSummary(t *testing.T) {
+	options := []reddit.FetchResultPollOption{{Text: "Yes", Votes: 3}, {Text: "No <never>", Votes: 1}}
+	tests := []struct {
+		TestName string
+		Lang     settings.Lang
+		Poll     reddit.FetchResultPoll
+		Expected []string
+	}{
+		{
+			TestName: "Ended",
+			Lang:     settings.LangEN,
+			Poll: reddit.FetchResultPoll{
+				Title:       "Cats & dogs?",
+				Description: "Be **honest**.\n\n* [Rules](https://example.com)",
+				Options:     options,
+				TotalVotes:  4,
+				EndTime:     time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC),
+			},
+			Expected: []string{
+				"<b>Cats &amp; dogs?</b>",
+				"Be <b>honest</b>.",
+				`• <a href="https://example.com">Rules</a>`,
+				"📊 Poll ended\n• Yes — 3 votes (75.0%)\n• No &lt;never&gt; — 1 votes (25.0%)",
+				"Total votes: 4",
+			},
+		},
+		{
+			TestName: "Open Until",
+			Lang:     settings.LangEN,
+			Poll: reddit.FetchResultPoll{
+				Title:      "Cats?",
+				Options:    options,
+				TotalVotes: 4,
+				EndTime:    time.Date(2999, 1, 2, 3, 4, 0, 0, time.UTC),
+			},
+			Expected: []string{
+				"<b>Cats?</b>",
+				"📊 Poll is open until 2999-01-02 03:04 UTC\n• Yes — 3 votes (75.0%)\n• No &lt;never&gt; — 1 votes (25.0%)",
+				"Total votes: 4",
+			},
+		},
+		{
+			TestName: "Hidden Votes",
+			Lang:     settings.LangEN,
+			Poll: reddit.FetchResultPoll{
+				Title:      "Cats?",
+				Options:    []reddit.FetchResultPollOption{{Text: "Yes", Votes: -1}, {Text: "No", Votes: -1}},
+				TotalVotes: 4,
+			},
+			Expected: []string{
+				"<b>Cats?</b>",
+				"📊 Poll is open\n• Yes\n• No",
+				"Total votes: 4\nResults are hidden until the poll ends.",
+			},
+		},
+		{
+			// The percentages are not shown without any vote
+			TestName: "No Votes",
+			Lang:     settings.LangRU,
+			Poll: reddit.FetchResultPoll{
+				Title:   "Cats?",
+				Options: []reddit.FetchResultPollOption{{Text: "Yes"}, {Text: "No"}},
+			},
+			Expected: []string{
+				"<b>Cats?</b>",
+				"📊 Опрос идёт\n• Yes — голосов: 0\n• No — голосов: 0",
+				"Всего голосов: 0",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Equal(t, test.Expected, pollSummary(test.Lang, test.Poll))
+		})
+	}
+}
+
+func TestHandlePollUploadFailed(t *testing.T) {
+	poll := reddit.FetchResultPoll{Title: "Cats?", Options: []reddit.FetchResultPollOption{{Text: "Yes"}, {Text: "No"}}}
+	c := &Client{Settings: settings.NewMemoryStore()}
+	c.updateUserSettings(1, func(user *settings.User) { user.Lang = settings.LangRU })
+	tests := []struct {
+		TestName string
+		PostUrl  string
+		UserID   int64
+		Expected string
+	}{
+		{
+			TestName: "Link",
+			PostUrl:  "https://redd.it/a",
+			UserID:   2,
+			Expected: "I couldn’t send this poll.\nHere is the link: https://redd.it/a",
+		},
+		{
+			// The link is not attached for the users who have disabled it
+			TestName: "No Link",
+			UserID:   2,
+			Expected: "I couldn’t send this poll.",
+		},
+		{
+			TestName: "Localized",
+			PostUrl:  "https://redd.it/a",
+			UserID:   1,
+			Expected: "Не удалось отправить этот опрос.\nВот ссылка: https://redd.it/a",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			bot, client := newFakeBot(t)
+			client.failMethods = map[string]bool{"sendPoll": true}
+			assert.NoError(t, c.handlePollUpload(bot, poll, test.PostUrl, test.UserID, 10, 0))
+			if assert.Len(t, client.requests, 2) {
+				assert.Equal(t, "sendMessage", client.requests[1].Method)
+				assert.Equal(t, test.Expected, client.requests[1].Params["text"])
+			}
+		})
+	}
+}
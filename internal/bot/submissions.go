@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+)
+
+// DefaultMaxUserPosts is the default maximum number of posts which are sent from the posts of a user
+const DefaultMaxUserPosts = 50
+
+// albumSize is the maximum number of media in a Telegram album
+const albumSize = 10
+
+// userPostsLimit gets the maximum number of posts which are sent from the posts of a user
+func (c *Client) userPostsLimit() int {
+	if c.MaxUserPosts <= 0 {
+		return DefaultMaxUserPosts
+	}
+	return c.MaxUserPosts
+}
+
+// parseUserCommand parses a command like "/user spez 20". isUser is false if the text is not a
+// /user command and valid is false if its arguments are invalid. The number of posts is capped at
+// maxLimit. It's maxLimit if no number is given.
+func parseUserCommand(text string, maxLimit int) (username string, limit int, isUser, valid bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return
+	}
+	// Commands in groups can be like /user@bot
+	if name, _, _ := strings.Cut(fields[0], "@"); name != "/user" {
+		return
+	}
+	isUser = true
+	args := fields[1:]
+	if len(args) == 0 || len(args) > 2 {
+		return
+	}
+	username = strings.TrimPrefix(strings.TrimPrefix(args[0], "/"), "u/")
+	limit = maxLimit
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return
+		}
+		limit = min(n, maxLimit)
+	}
+	valid = true
+	return
+}
+
+// handleUserSubmissions sends the media posts of a user as albums. The videos which have an audio
+// are sent one by one like they were sent by the user, because their audio cannot be merged in albums.
+func (c *Client) handleUserSubmissions(bot *gotgbot.Bot, ctx *ext.Context, username string, limit int) error {
+	uid := ctx.Message.From.Id
+	chatID := ctx.EffectiveChat.Id
+	user := c.userSettings(uid)
+	if _, running := c.runningListings.LoadOrStore(uid, struct{}{}); running {
+		_, err := ctx.EffectiveMessage.Reply(bot, tr(user.Lang, "listing.busy"), nil)
+		return err
+	}
+	defer c.runningListings.Delete(uid)
+	setProgress, err := replyProgress(bot, ctx, fmt.Sprintf(tr(user.Lang, "user.fetching"), username))
+	if err != nil {
+		return err
+	}
+	submissions, fetchErr := c.RedditOauth.FetchUserSubmissions(username, limit)
+	if fetchErr != nil {
+		if fetchErr.NormalError != "" {
+			log.Println("Cannot fetch the submissions of", username, ":", fetchErr.NormalError)
+		}
+		setProgress(fetchErr.BotError)
+		return nil
+	}
+	if len(submissions) == 0 {
+		setProgress(tr(user.Lang, "listing.empty"))
+		return nil
+	}
+	entries, videos := submissionAlbumEntries(submissions, user.Quality)
+	asFile := user.DownloadMode == settings.DownloadModeFiles
+	for start := 0; start < len(entries); start += albumSize {
+		if start > 0 {
+			time.Sleep(listingPostInterval)
+		}
+		end := min(start+albumSize, len(entries))
+		setProgress(fmt.Sprintf(tr(user.Lang, "user.progress"), start+1, end, len(entries)))
+		if _, err := c.uploadAlbumMedia(bot, entries[start:end], chatID, asFile); err != nil {
+			log.Println("Cannot send the submissions of", username, ":", err)
+		}
+	}
+	for i, link := range videos {
+		time.Sleep(listingPostInterval)
+		setProgress(fmt.Sprintf(tr(user.Lang, "user.videos"), i+1, len(videos)))
+		if err := c.sendPost(bot, link, uid, chatID, 0); err != nil {
+			log.Println("Cannot send the post", link, "of the user:", err)
+		}
+	}
+	setProgress(fmt.Sprintf(tr(user.Lang, "user.done"), len(submissions), username))
+	return nil
+}
+
+// submissionAlbumEntries converts the media posts of a user to the entries of albums. The quality
+// of each media is picked based on the settings of the user (the original one if they want to be
+// asked). The media of NSFW and spoiler posts are hidden. The links of the videos which have an
+// audio are returned separately.
+func submissionAlbumEntries(submissions []reddit.Submission, quality settings.MediaQuality) (entries []albumEntry, videos []string) {
+	for _, submission := range submissions {
+		switch data := submission.Result.(type) {
+		case reddit.FetchResultMedia:
+			if len(data.Medias) == 0 {
+				continue
+			}
+			if _, hasAudio := data.HasAudio(); hasAudio {
+				videos = append(videos, submission.Link)
+				continue
+			}
+			idx := selectQualityIndex(data.Medias, quality)
+			if idx < 0 {
+				idx = selectQualityIndex(data.Medias, settings.QualityOriginal)
+			}
+			entries = append(entries, albumEntry{
+				FetchResultAlbumEntry: reddit.FetchResultAlbumEntry{
+					Link:    data.Medias[idx].Link,
+					Caption: data.Title,
+					Type:    data.Type,
+				},
+				Spoiler: data.NSFW || data.Spoiler,
+			})
+		case reddit.FetchResultAlbum:
+			for _, entry := range data.Album {
+				if entry.Caption == "" {
+					entry.Caption = data.Title
+				}
+				entries = append(entries, albumEntry{FetchResultAlbumEntry: entry, Spoiler: data.NSFW || data.Spoiler})
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserCommand(t *testing.T) {
+	tests := []struct {
+		TestName         string
+		Text             string
+		ExpectedUsername string
+		ExpectedLimit    int
+		ExpectedIsUser   bool
+		ExpectedValid    bool
+	}{
+		{
+			TestName:         "Default Limit",
+			Text:             "/user spez",
+			ExpectedUsername: "spez",
+			ExpectedLimit:    50,
+			ExpectedIsUser:   true,
+			ExpectedValid:    true,
+		},
+		{
+			TestName:         "Limit",
+			Text:             "/user u/spez 20",
+			ExpectedUsername: "spez",
+			ExpectedLimit:    20,
+			ExpectedIsUser:   true,
+			ExpectedValid:    true,
+		},
+		{
+			TestName:         "Capped Limit",
+			Text:             "/user /u/spez 100",
+			ExpectedUsername: "spez",
+			ExpectedLimit:    50,
+			ExpectedIsUser:   true,
+			ExpectedValid:    true,
+		},
+		{
+			TestName:         "Group Command",
+			Text:             "/user@bot spez",
+			ExpectedUsername: "spez",
+			ExpectedLimit:    50,
+			ExpectedIsUser:   true,
+			ExpectedValid:    true,
+		},
+		{
+			TestName:       "No Username",
+			Text:           "/user",
+			ExpectedIsUser: true,
+		},
+		{
+			TestName:       "Invalid Limit",
+			Text:           "/user spez many",
+			ExpectedIsUser: true,
+		},
+		{
+			TestName:       "Zero Limit",
+			Text:           "/user spez 0",
+			ExpectedIsUser: true,
+		},
+		{
+			TestName:       "Too Many Arguments",
+			Text:           "/user spez 10 more",
+			ExpectedIsUser: true,
+		},
+		{
+			TestName: "Other Command",
+			Text:     "/users spez",
+		},
+		{
+			TestName: "Empty",
+			Text:     "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			username, limit, isUser, valid := parseUserCommand(test.Text, 50)
+			assert.Equal(t, test.ExpectedIsUser, isUser)
+			assert.Equal(t, test.ExpectedValid, valid)
+			if test.ExpectedValid {
+				assert.Equal(t, test.ExpectedUsername, username)
+				assert.Equal(t, test.ExpectedLimit, limit)
+			}
+		})
+	}
+}
@@ -1,14 +1,39 @@
 package bot
 
 import (
+	"sync"
+
 	"github.com/lartie/RedditDownloaderBot/internal/cache"
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
 	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
 )
 
 // Client is the contains the data needed to operate the bot
 type Client struct {
 	CallbackCache cache.Interface
+	Settings      settings.Store
 	RedditOauth   *reddit.Oauth
+	// If not nil, the users can choose to get the long texts as pages of this
+	TextPublisher TextPublisher
+	// The maximum number of posts which a /top, /hot or /new command sends
+	MaxListingPosts int
+	// The maximum number of posts which are sent from the posts of a user
+	MaxUserPosts int
+	// The maximum number of links which are processed from a message
+	MaxLinksPerMessage int
+	// The text of the {signature} placeholder of captions. The username of the bot if empty.
+	CaptionSignature string
+	// The users which a listing command is being sent to
+	runningListings sync.Map
+	// The users whose next message is a caption template. The values are the IDs of
+	// the settings records which the template is saved in (see captionSettingsTarget).
+	captionEdits sync.Map
+}
+
+// TextPublisher publishes the texts which do not fit in a message as web pages
+type TextPublisher interface {
+	// Publish creates a page with the title and the HTML blocks of markdown.Blocks and returns its URL
+	Publish(title string, blocks []string) (string, error)
 }
 
 // AllowedUsers is a list of users which can use the bot
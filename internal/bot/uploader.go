@@ -1,10 +1,14 @@
 package bot
 
 import (
+	"github.com/lartie/RedditDownloaderBot/internal/cache"
+	"github.com/lartie/RedditDownloaderBot/pkg/markdown"
 	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
 	"github.com/lartie/RedditDownloaderBot/pkg/util"
+	"html"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,7 +18,20 @@ import (
 )
 
 // handleGifUpload downloads a gif and then uploads it to Telegram
-func (c *Client) handleGifUpload(bot *gotgbot.Bot, gifUrl, title, thumbnailUrl, postUrl, description string, dimension reddit.Dimension, chatID int64) error {
+func (c *Client) handleGifUpload(bot *gotgbot.Bot, gifUrl, caption, thumbnailUrl, postUrl, description string, dimension reddit.Dimension, uid, chatID int64, spoiler bool) error {
+	// Check if we have uploaded it before
+	fileIDKey := fileIDCacheKey(fileIDModeAnimation, gifUrl)
+	if sentMessage := c.sendCachedFile(fileIDKey, func(file gotgbot.InputFileOrString) (*gotgbot.Message, error) {
+		return bot.SendAnimation(chatID, file, &gotgbot.SendAnimationOpts{
+			Caption:    caption,
+			ParseMode:  gotgbot.ParseModeMarkdownV2,
+			HasSpoiler: spoiler,
+			Width:      dimension.Width,
+			Height:     dimension.Height,
+		})
+	}); sentMessage != nil {
+		return c.sendPostDescription(bot, description, uid, sentMessage)
+	}
 	// Inform the user we are doing some shit
 	stopReportChannel := statusReporter(bot, chatID, gotgbot.ChatActionUploadVideo)
 	defer close(stopReportChannel)
@@ -57,10 +74,11 @@ func (c *Client) handleGifUpload(bot *gotgbot.Bot, gifUrl, title, thumbnailUrl,
 	}
 	// Upload it
 	animationOpt := &gotgbot.SendAnimationOpts{
-		Caption:   addLinkIfNeeded(escapeMarkdown(title), postUrl),
-		ParseMode: gotgbot.ParseModeMarkdownV2,
-		Width:     dimension.Width,
-		Height:    dimension.Height,
+		Caption:    caption,
+		ParseMode:  gotgbot.ParseModeMarkdownV2,
+		HasSpoiler: spoiler,
+		Width:      dimension.Width,
+		Height:     dimension.Height,
 	}
 	if tmpThumbnailFile != nil {
 		animationOpt.Thumbnail = fileReaderFromOsFile(tmpThumbnailFile)
@@ -71,12 +89,28 @@ func (c *Client) handleGifUpload(bot *gotgbot.Bot, gifUrl, title, thumbnailUrl,
 		_, err = bot.SendMessage(chatID, "I couldn’t upload this GIF.\nHere is the link: "+gifUrl, nil)
 		return err
 	}
+	c.cacheSentFile(fileIDKey, sentMessage)
 	// Send description as another message (if available)
-	return sendPostDescription(bot, description, sentMessage, false)
+	return c.sendPostDescription(bot, description, uid, sentMessage)
 }
 
 // handleVideoUpload downloads a video and then uploads it to Telegram
-func (c *Client) handleVideoUpload(bot *gotgbot.Bot, vidUrl, audioUrl, title, thumbnailUrl, postUrl, description string, dimension reddit.Dimension, duration, chatID int64) error {
+func (c *Client) handleVideoUpload(bot *gotgbot.Bot, vidUrl, audioUrl, caption, thumbnailUrl, postUrl, description string, dimension reddit.Dimension, duration, uid, chatID int64, spoiler bool) error {
+	// Check if we have uploaded it before
+	fileIDKey := fileIDCacheKey(fileIDModeVideo, vidUrl, audioUrl)
+	if sentMessage := c.sendCachedFile(fileIDKey, func(file gotgbot.InputFileOrString) (*gotgbot.Message, error) {
+		return bot.SendVideo(chatID, file, &gotgbot.SendVideoOpts{
+			Duration:          duration,
+			Caption:           caption,
+			ParseMode:         gotgbot.ParseModeMarkdownV2,
+			HasSpoiler:        spoiler,
+			SupportsStreaming: true,
+			Width:             dimension.Width,
+			Height:            dimension.Height,
+		})
+	}); sentMessage != nil {
+		return c.sendPostDescription(bot, description, uid, sentMessage)
+	}
 	// Inform the user we are doing some shit
 	stopReportChannel := statusReporter(bot, chatID, gotgbot.ChatActionUploadVideo)
 	defer close(stopReportChannel)
@@ -123,8 +157,9 @@ func (c *Client) handleVideoUpload(bot *gotgbot.Bot, vidUrl, audioUrl, title, th
 	// Upload it
 	videoOpt := &gotgbot.SendVideoOpts{
 		Duration:          duration,
-		Caption:           addLinkIfNeeded(escapeMarkdown(title), postUrl),
+		Caption:           caption,
 		ParseMode:         gotgbot.ParseModeMarkdownV2,
+		HasSpoiler:        spoiler,
 		SupportsStreaming: true,
 		Width:             dimension.Width,
 		Height:            dimension.Height,
@@ -138,12 +173,34 @@ func (c *Client) handleVideoUpload(bot *gotgbot.Bot, vidUrl, audioUrl, title, th
 		_, err = bot.SendMessage(chatID, "I couldn’t upload this video.\n"+generateVideoUrlsMessage(vidUrl, audioUrl), nil)
 		return err
 	}
+	c.cacheSentFile(fileIDKey, sentMessage)
 	// Send description as another message (if available)
-	return sendPostDescription(bot, description, sentMessage, false)
+	return c.sendPostDescription(bot, description, uid, sentMessage)
 }
 
 // handleVideoUpload downloads a photo and then uploads it to Telegram
-func (c *Client) handlePhotoUpload(bot *gotgbot.Bot, photoUrl, title, thumbnailUrl, postUrl, description string, chatID int64, asPhoto bool) error {
+func (c *Client) handlePhotoUpload(bot *gotgbot.Bot, photoUrl, caption, thumbnailUrl, postUrl, description string, uid, chatID int64, asPhoto, spoiler bool) error {
+	// Check if we have uploaded it before
+	var sentMessage *gotgbot.Message
+	if asPhoto {
+		sentMessage = c.sendCachedFile(fileIDCacheKey(fileIDModePhoto, photoUrl), func(file gotgbot.InputFileOrString) (*gotgbot.Message, error) {
+			return bot.SendPhoto(chatID, file, &gotgbot.SendPhotoOpts{
+				Caption:    caption,
+				ParseMode:  gotgbot.ParseModeMarkdownV2,
+				HasSpoiler: spoiler,
+			})
+		})
+	} else {
+		sentMessage = c.sendCachedFile(fileIDCacheKey(fileIDModeDocument, photoUrl), func(file gotgbot.InputFileOrString) (*gotgbot.Message, error) {
+			return bot.SendDocument(chatID, file, &gotgbot.SendDocumentOpts{
+				Caption:   caption,
+				ParseMode: gotgbot.ParseModeMarkdownV2,
+			})
+		})
+	}
+	if sentMessage != nil {
+		return c.sendPostDescription(bot, description, uid, sentMessage)
+	}
 	// Inform the user we are doing some shit
 	var stopReportChannel chan struct{}
 	if asPhoto {
@@ -186,15 +243,18 @@ func (c *Client) handlePhotoUpload(bot *gotgbot.Bot, photoUrl, title, thumbnailU
 		}
 	}
 	// Upload
-	var sentMessage *gotgbot.Message
+	var fileIDKey string
 	if asPhoto {
+		fileIDKey = fileIDCacheKey(fileIDModePhoto, photoUrl)
 		sentMessage, err = bot.SendPhoto(chatID, fileReaderFromOsFile(tmpFile), &gotgbot.SendPhotoOpts{
-			Caption:   addLinkIfNeeded(escapeMarkdown(title), postUrl),
-			ParseMode: gotgbot.ParseModeMarkdownV2,
+			Caption:    caption,
+			ParseMode:  gotgbot.ParseModeMarkdownV2,
+			HasSpoiler: spoiler,
 		})
 	} else {
+		fileIDKey = fileIDCacheKey(fileIDModeDocument, photoUrl)
 		documentOpt := &gotgbot.SendDocumentOpts{
-			Caption:   addLinkIfNeeded(escapeMarkdown(title), postUrl),
+			Caption:   caption,
 			ParseMode: gotgbot.ParseModeMarkdownV2,
 		}
 		if tmpThumbnailFile != nil {
@@ -207,122 +267,255 @@ func (c *Client) handlePhotoUpload(bot *gotgbot.Bot, photoUrl, title, thumbnailU
 		_, err = bot.SendMessage(chatID, "I couldn’t upload this image.\nHere is the link: "+photoUrl, nil)
 		return err
 	}
+	c.cacheSentFile(fileIDKey, sentMessage)
 	// Send description as another message (if available)
-	return sendPostDescription(bot, description, sentMessage, false)
+	return c.sendPostDescription(bot, description, uid, sentMessage)
+}
+
+// albumEntry is a media which is uploaded in an album
+type albumEntry struct {
+	reddit.FetchResultAlbumEntry
+	// Hide the media behind a spoiler. Files cannot be hidden.
+	Spoiler bool
+}
+
+// albumItem is a media of an album which is ready to be sent to Telegram
+type albumItem struct {
+	// The media in the album
+	entry albumEntry
+	// The key of this media in file ID cache
+	fileIDKey string
+	// The media to send to Telegram
+	media gotgbot.InputMedia
+	// True if media is a file ID from the cache instead of a downloaded file
+	cached bool
 }
 
 // handleAlbumUpload uploads an album to Telegram
-func (c *Client) handleAlbumUpload(bot *gotgbot.Bot, album reddit.FetchResultAlbum, postUrl string, chatID int64, asFile bool) error {
+func (c *Client) handleAlbumUpload(bot *gotgbot.Bot, album reddit.FetchResultAlbum, postUrl string, uid, chatID int64, asFile bool) error {
+	entries := make([]albumEntry, len(album.Album))
+	for i, entry := range album.Album {
+		entries[i] = albumEntry{FetchResultAlbumEntry: entry, Spoiler: album.NSFW || album.Spoiler}
+	}
+	lastMessage, err := c.uploadAlbumMedia(bot, entries, chatID, asFile)
+	if err != nil {
+		return err
+	}
+	// Send the title and description
+	blocks := append([]string{"<b>" + html.EscapeString(album.Title) + "</b>"}, markdown.Blocks(album.Description)...)
+	var replyTo int64
+	if lastMessage != nil {
+		replyTo = lastMessage.MessageId
+	}
+	return c.sendTextBlocks(bot, album.Title, appendLinkBlockIfNeeded(blocks, postUrl), uid, chatID, replyTo)
+}
+
+// uploadAlbumMedia uploads the media of an album in groups of 10. Returns the last sent message
+// which is nil if nothing is sent.
+func (c *Client) uploadAlbumMedia(bot *gotgbot.Bot, entries []albumEntry, chatID int64, asFile bool) (*gotgbot.Message, error) {
 	// Report status
 	stopReportChannel := statusReporter(bot, chatID, gotgbot.ChatActionUploadPhoto)
 	defer close(stopReportChannel)
 	// Download each file of album
 	var err error
-	filePaths := make([]*os.File, 0, len(album.Album))
+	filePaths := make([]*os.File, 0, len(entries))
 	defer func() { // cleanup
 		for _, f := range filePaths {
 			_ = f.Close()
 			_ = os.Remove(f.Name())
 		}
 	}()
-	fileConfigs := make([]gotgbot.InputMedia, 0, len(album.Album))
-	fileLinks := make([]string, 0, len(album.Album))
-	for _, media := range album.Album {
-		var tmpFile *os.File
-		var f gotgbot.InputMedia
-		switch media.Type {
-		case reddit.FetchResultMediaTypePhoto:
-			tmpFile, err = c.RedditOauth.DownloadPhoto(media.Link)
-			if err == nil {
-				if asFile {
-					f = gotgbot.InputMediaDocument{Media: fileReaderFromOsFile(tmpFile), Caption: media.Caption}
-				} else {
-					f = gotgbot.InputMediaPhoto{Media: fileReaderFromOsFile(tmpFile), Caption: media.Caption}
-				}
-			}
-		case reddit.FetchResultMediaTypeGif:
-			tmpFile, err = c.RedditOauth.DownloadGif(media.Link)
-			if err == nil {
-				if asFile {
-					f = gotgbot.InputMediaDocument{Media: fileReaderFromOsFile(tmpFile), Caption: media.Caption}
-				} else {
-					f = gotgbot.InputMediaVideo{Media: fileReaderFromOsFile(tmpFile), Caption: media.Caption}
-				}
-			}
-		case reddit.FetchResultMediaTypeVideo:
-			tmpFile, err = c.RedditOauth.DownloadVideo(media.Link, "") // TODO: can i do something about audio URL?
-			if err == nil {
-				if asFile {
-					f = gotgbot.InputMediaDocument{Media: fileReaderFromOsFile(tmpFile), Caption: media.Caption}
-				} else {
-					f = gotgbot.InputMediaVideo{
-						Media:             fileReaderFromOsFile(tmpFile),
-						Caption:           media.Caption,
-						SupportsStreaming: true,
-					}
-				}
-			}
+	items := make([]albumItem, 0, len(entries))
+	for _, media := range entries {
+		item := albumItem{
+			entry:     media,
+			fileIDKey: albumFileIDCacheKey(media.FetchResultAlbumEntry, asFile),
+		}
+		// Check if we have uploaded it before
+		if cached, err := c.CallbackCache.GetFileIDCache(item.fileIDKey); err == nil {
+			item.media = albumInputMedia(media, gotgbot.InputFileByID(cached.FileID), asFile)
+			item.cached = true
+			items = append(items, item)
+			continue
 		}
+		var tmpFile *os.File
+		tmpFile, err = c.downloadAlbumMedia(media.FetchResultAlbumEntry)
 		if err != nil {
 			log.Println("Unable to download album media:", err)
 			_, _ = bot.SendMessage(chatID, "I couldn’t download the gallery.\nHere is the link: "+media.Link, nil)
 			continue
 		}
-		fileConfigs = append(fileConfigs, f)
-		fileLinks = append(fileLinks, media.Link)
 		filePaths = append(filePaths, tmpFile)
+		item.media = albumInputMedia(media, fileReaderFromOsFile(tmpFile), asFile)
+		items = append(items, item)
 	}
 	// Now upload 10 of them at once
 	var lastMessage *gotgbot.Message
-	i := 0
-	for ; i < len(fileConfigs)/10; i++ {
-		sentMessages, err := bot.SendMediaGroup(chatID, fileConfigs[i*10:(i+1)*10], nil)
+	for start := 0; start < len(items); start += 10 {
+		chunk := items[start:min(start+10, len(items))]
+		var sentMessages []gotgbot.Message
+		sentMessages, err = sendAlbumChunk(bot, chatID, chunk)
+		if fileIDRejected(err) && c.redownloadCachedAlbumItems(chunk, asFile, &filePaths) {
+			// One of the file IDs might have been rejected. Try again with real files
+			sentMessages, err = sendAlbumChunk(bot, chatID, chunk)
+		}
 		if err != nil {
 			log.Println("Unable to upload gallery:", err)
-			_, _ = bot.SendMessage(chatID, generateGalleryFailedMessage(fileLinks[i*10:(i+1)*10]), nil)
+			fileLinks := make([]string, len(chunk))
+			for i, item := range chunk {
+				fileLinks[i] = item.entry.Link
+			}
+			_, err = bot.SendMessage(chatID, generateGalleryFailedMessage(fileLinks), nil)
+			if err != nil {
+				return lastMessage, err
+			}
+			continue
+		}
+		// Cache the file IDs
+		for i := range sentMessages {
+			if i < len(chunk) && !chunk[i].cached {
+				c.cacheSentFile(chunk[i].fileIDKey, &sentMessages[i])
+			}
 		}
 		if len(sentMessages) != 0 {
 			lastMessage = &sentMessages[len(sentMessages)-1]
 		}
 	}
-	err = nil // needed for last error check
-	fileConfigs = fileConfigs[i*10:]
-	if len(fileConfigs) == 1 {
-		switch f := fileConfigs[0].(type) {
+	return lastMessage, nil
+}
+
+// downloadAlbumMedia downloads a media of an album based on its type
+func (c *Client) downloadAlbumMedia(media reddit.FetchResultAlbumEntry) (*os.File, error) {
+	switch media.Type {
+	case reddit.FetchResultMediaTypePhoto:
+		return c.RedditOauth.DownloadPhoto(media.Link)
+	case reddit.FetchResultMediaTypeGif:
+		return c.RedditOauth.DownloadGif(media.Link)
+	case reddit.FetchResultMediaTypeVideo:
+		return c.RedditOauth.DownloadVideo(media.Link, media.AudioLink)
+	}
+	return nil, errors.New("unknown media type: " + strconv.Itoa(int(media.Type)))
+}
+
+// redownloadCachedAlbumItems downloads the items of an album chunk which are sent from the file ID
+// cache and deletes them from the cache. It's used when Telegram rejects a file ID.
+// Returns false if there was no cached item in the chunk.
+func (c *Client) redownloadCachedAlbumItems(chunk []albumItem, asFile bool, filePaths *[]*os.File) bool {
+	hadCached := false
+	for i := range chunk {
+		if !chunk[i].cached {
+			continue
+		}
+		hadCached = true
+		_ = c.CallbackCache.DeleteFileIDCache(chunk[i].fileIDKey)
+		tmpFile, err := c.downloadAlbumMedia(chunk[i].entry.FetchResultAlbumEntry)
+		if err != nil {
+			log.Println("Unable to download album media:", err)
+			continue
+		}
+		*filePaths = append(*filePaths, tmpFile)
+		chunk[i].media = albumInputMedia(chunk[i].entry, fileReaderFromOsFile(tmpFile), asFile)
+		chunk[i].cached = false
+	}
+	return hadCached
+}
+
+// albumFileIDCacheKey gets the key of the file ID cache of a media of an album. The audio is
+// only in the key of the videos which have a separate audio, so the older keys stay the same.
+func albumFileIDCacheKey(media reddit.FetchResultAlbumEntry, asFile bool) string {
+	if media.AudioLink != "" {
+		return fileIDCacheKey(albumFileIDMode(media.Type, asFile), media.Link, media.AudioLink)
+	}
+	return fileIDCacheKey(albumFileIDMode(media.Type, asFile), media.Link)
+}
+
+// albumFileIDMode gets the mode which a media of an album is sent to Telegram with
+func albumFileIDMode(mediaType reddit.FetchResultMediaType, asFile bool) string {
+	if asFile {
+		return fileIDModeDocument
+	}
+	if mediaType == reddit.FetchResultMediaTypePhoto {
+		return fileIDModePhoto
+	}
+	return fileIDModeVideo
+}
+
+// albumInputMedia creates the media of an album which must be sent to Telegram from a file
+func albumInputMedia(media albumEntry, file gotgbot.InputFileOrString, asFile bool) gotgbot.InputMedia {
+	if asFile {
+		return gotgbot.InputMediaDocument{Media: file, Caption: media.Caption}
+	}
+	switch media.Type {
+	case reddit.FetchResultMediaTypePhoto:
+		return gotgbot.InputMediaPhoto{Media: file, Caption: media.Caption, HasSpoiler: media.Spoiler}
+	case reddit.FetchResultMediaTypeVideo:
+		return gotgbot.InputMediaVideo{
+			Media:             file,
+			Caption:           media.Caption,
+			Duration:          media.Duration,
+			SupportsStreaming: true,
+			HasSpoiler:        media.Spoiler,
+		}
+	default:
+		return gotgbot.InputMediaVideo{Media: file, Caption: media.Caption, HasSpoiler: media.Spoiler}
+	}
+}
+
+// sendAlbumChunk sends at most 10 medias of an album. A single media is sent on its own
+// because Telegram does not accept a media group with one media.
+func sendAlbumChunk(bot *gotgbot.Bot, chatID int64, chunk []albumItem) ([]gotgbot.Message, error) {
+	if len(chunk) == 1 {
+		var sentMessage *gotgbot.Message
+		var err error
+		// The caption and the spoiler of the media must be kept
+		switch f := chunk[0].media.(type) {
 		case gotgbot.InputMediaPhoto:
-			lastMessage, err = bot.SendPhoto(chatID, f.Media, nil)
+			sentMessage, err = bot.SendPhoto(chatID, f.Media, &gotgbot.SendPhotoOpts{
+				Caption:    f.Caption,
+				ParseMode:  f.ParseMode,
+				HasSpoiler: f.HasSpoiler,
+			})
 		case gotgbot.InputMediaVideo:
-			lastMessage, err = bot.SendVideo(chatID, f.Media, nil)
+			sentMessage, err = bot.SendVideo(chatID, f.Media, &gotgbot.SendVideoOpts{
+				Caption:           f.Caption,
+				ParseMode:         f.ParseMode,
+				Duration:          f.Duration,
+				SupportsStreaming: f.SupportsStreaming,
+				HasSpoiler:        f.HasSpoiler,
+			})
 		case gotgbot.InputMediaDocument:
-			lastMessage, err = bot.SendDocument(chatID, f.Media, nil)
+			sentMessage, err = bot.SendDocument(chatID, f.Media, &gotgbot.SendDocumentOpts{
+				Caption:   f.Caption,
+				ParseMode: f.ParseMode,
+			})
 		default:
 			panic("IMPOSSIBLE")
 		}
-	} else if len(fileConfigs) > 1 {
-		var sentMessages []gotgbot.Message
-		sentMessages, err = bot.SendMediaGroup(chatID, fileConfigs, nil)
-		if len(sentMessages) != 0 {
-			lastMessage = &sentMessages[len(sentMessages)-1]
-		}
-	}
-	if err != nil {
-		log.Println("Unable to upload gallery:", err)
-		_, err = bot.SendMessage(chatID, generateGalleryFailedMessage(fileLinks[i*10:]), nil)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		return []gotgbot.Message{*sentMessage}, nil
 	}
-	// Send the title and description
-	titleDescriptionMessageText := "*" + escapeMarkdown(album.Title) + "*"
-	if album.Description != "" {
-		titleDescriptionMessageText += "\n\n" + escapeMarkdown(album.Description)
+	medias := make([]gotgbot.InputMedia, len(chunk))
+	for i, item := range chunk {
+		medias[i] = item.media
 	}
-	titleDescriptionMessageText = addLinkIfNeeded(titleDescriptionMessageText, postUrl)
-	return sendPostDescription(bot, titleDescriptionMessageText, lastMessage, true)
+	return bot.SendMediaGroup(chatID, medias, nil)
 }
 
 // handleAudioUpload simply downloads then uploads an audio to Telegram
-func (c *Client) handleAudioUpload(bot *gotgbot.Bot, audioURL, title, postUrl, description string, duration, chatID int64) error {
+func (c *Client) handleAudioUpload(bot *gotgbot.Bot, audioURL, caption, postUrl, description string, duration, uid, chatID int64) error {
+	// Check if we have uploaded it before
+	fileIDKey := fileIDCacheKey(fileIDModeAudio, audioURL)
+	if sentMessage := c.sendCachedFile(fileIDKey, func(file gotgbot.InputFileOrString) (*gotgbot.Message, error) {
+		return bot.SendAudio(chatID, file, &gotgbot.SendAudioOpts{
+			Caption:   caption,
+			ParseMode: gotgbot.ParseModeMarkdownV2,
+			Duration:  duration,
+		})
+	}); sentMessage != nil {
+		return c.sendPostDescription(bot, description, uid, sentMessage)
+	}
 	// Send status
 	stopReportChannel := statusReporter(bot, chatID, gotgbot.ChatActionUploadVoice)
 	defer close(stopReportChannel)
@@ -339,7 +532,7 @@ func (c *Client) handleAudioUpload(bot *gotgbot.Bot, audioURL, title, postUrl, d
 	}()
 	// Simply upload it to telegram
 	sentMessage, err := bot.SendAudio(chatID, fileReaderFromOsFile(audioFile), &gotgbot.SendAudioOpts{
-		Caption:   addLinkIfNeeded(escapeMarkdown(title), postUrl),
+		Caption:   caption,
 		ParseMode: gotgbot.ParseModeMarkdownV2,
 		Duration:  duration,
 	})
@@ -348,8 +541,50 @@ func (c *Client) handleAudioUpload(bot *gotgbot.Bot, audioURL, title, postUrl, d
 		_, err = bot.SendMessage(chatID, "I couldn’t upload the audio.\n"+generateAudioURLMessage(audioURL), nil)
 		return err
 	}
+	c.cacheSentFile(fileIDKey, sentMessage)
 	// Send description as another message (if available)
-	return sendPostDescription(bot, description, sentMessage, false)
+	return c.sendPostDescription(bot, description, uid, sentMessage)
+}
+
+// sendCachedFile tries to send a media which we have uploaded before by its file ID.
+// send must send the given file to the chat. Returns nil if the media is not in the cache or
+// Telegram has rejected the file ID; In this case, the media must be uploaded normally.
+func (c *Client) sendCachedFile(key string, send func(file gotgbot.InputFileOrString) (*gotgbot.Message, error)) *gotgbot.Message {
+	cached, err := c.CallbackCache.GetFileIDCache(key)
+	if err != nil {
+		if !errors.Is(err, cache.NotFoundErr) {
+			log.Println("Cannot get the file ID cache:", err)
+		}
+		return nil
+	}
+	sentMessage, err := send(gotgbot.InputFileByID(cached.FileID))
+	if err != nil {
+		log.Println("Cannot resend the cached file ID:", err)
+		// Network errors do not mean that the file ID is not valid anymore
+		if fileIDRejected(err) {
+			_ = c.CallbackCache.DeleteFileIDCache(key)
+		}
+		return nil
+	}
+	return sentMessage
+}
+
+// fileIDRejected checks if an error of Telegram is because of a file ID which it does not accept
+func fileIDRejected(err error) bool {
+	var telegramErr *gotgbot.TelegramError
+	return errors.As(err, &telegramErr) && telegramErr.Code == 400 &&
+		strings.Contains(strings.ToLower(telegramErr.Description), "file")
+}
+
+// cacheSentFile stores the file ID of the media in a message which we have uploaded
+func (c *Client) cacheSentFile(key string, sentMessage *gotgbot.Message) {
+	fileID := sentFileID(sentMessage)
+	if fileID == "" {
+		return
+	}
+	if err := c.CallbackCache.SetFileIDCache(key, cache.FileIDCached{FileID: fileID}); err != nil {
+		log.Println("Cannot set the file ID cache:", err)
+	}
 }
 
 // statusReporter starts reporting for uploading a thing in telegram
@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// botRequest is a request which fakeBotClient has got
+type botRequest struct {
+	Method string
+	Params map[string]string
+}
+
+// fakeBotClient records the requests of a bot instead of sending them to Telegram.
+// Every request gets a message as the result, except the methods in failMethods which fail.
+type fakeBotClient struct {
+	requests    []botRequest
+	failMethods map[string]bool
+}
+
+func (c *fakeBotClient) RequestWithContext(_ context.Context, _ string, method string, params map[string]string, _ map[string]gotgbot.FileReader, _ *gotgbot.RequestOpts) (json.RawMessage, error) {
+	c.requests = append(c.requests, botRequest{Method: method, Params: params})
+	if c.failMethods[method] {
+		return nil, &gotgbot.TelegramError{Method: method, Params: params, Code: 400, Description: "Bad Request"}
+	}
+	if method == "sendMediaGroup" {
+		return json.RawMessage(`[{"message_id":1},{"message_id":2}]`), nil
+	}
+	return json.RawMessage(`{"message_id":1}`), nil
+}
+
+func (c *fakeBotClient) GetAPIURL(*gotgbot.RequestOpts) string {
+	return gotgbot.DefaultAPIURL
+}
+
+func (c *fakeBotClient) FileURL(_ string, path string, _ *gotgbot.RequestOpts) string {
+	return gotgbot.DefaultAPIURL + "/file/" + path
+}
+
+// newFakeBot creates a bot which sends its requests to a fakeBotClient
+func newFakeBot(t *testing.T) (*gotgbot.Bot, *fakeBotClient) {
+	client := &fakeBotClient{}
+	bot, err := gotgbot.NewBot("1:token", &gotgbot.BotOpts{BotClient: client, DisableTokenCheck: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bot, client
+}
+
+func TestSendAlbumChunkSingleMedia(t *testing.T) {
+	file := gotgbot.InputFileByID("file")
+	tests := []struct {
+		TestName       string
+		Media          gotgbot.InputMedia
+		ExpectedMethod string
+		ExpectedParams map[string]string
+	}{
+		{
+			TestName:       "Photo",
+			Media:          gotgbot.InputMediaPhoto{Media: file, Caption: "caption", HasSpoiler: true},
+			ExpectedMethod: "sendPhoto",
+			ExpectedParams: map[string]string{"caption": "caption", "has_spoiler": "true"},
+		},
+		{
+			TestName:       "Video",
+			Media:          gotgbot.InputMediaVideo{Media: file, Caption: "caption", Duration: 12, SupportsStreaming: true, HasSpoiler: true},
+			ExpectedMethod: "sendVideo",
+			ExpectedParams: map[string]string{"caption": "caption", "duration": "12", "supports_streaming": "true", "has_spoiler": "true"},
+		},
+		{
+			TestName:       "Document",
+			Media:          gotgbot.InputMediaDocument{Media: file, Caption: "caption"},
+			ExpectedMethod: "sendDocument",
+			ExpectedParams: map[string]string{"caption": "caption"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			bot, client := newFakeBot(t)
+			messages, err := sendAlbumChunk(bot, 10, []albumItem{{media: test.Media}})
+			assert.NoError(t, err)
+			assert.Len(t, messages, 1)
+			if assert.Len(t, client.requests, 1) {
+				request := client.requests[0]
+				assert.Equal(t, test.ExpectedMethod, request.Method)
+				for key, value := range test.ExpectedParams {
+					assert.Equal(t, value, request.Params[key], key)
+				}
+			}
+		})
+	}
+}
+
+func TestSendAlbumChunkGroup(t *testing.T) {
+	bot, client := newFakeBot(t)
+	messages, err := sendAlbumChunk(bot, 10, []albumItem{
+		{media: gotgbot.InputMediaPhoto{Media: gotgbot.InputFileByID("a"), HasSpoiler: true}},
+		{media: gotgbot.InputMediaPhoto{Media: gotgbot.InputFileByID("b")}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	if assert.Len(t, client.requests, 1) {
+		assert.Equal(t, "sendMediaGroup", client.requests[0].Method)
+		assert.Contains(t, client.requests[0].Params["media"], `"has_spoiler":true`)
+	}
+}
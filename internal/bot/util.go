@@ -1,11 +1,18 @@
 package bot
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"github.com/lartie/RedditDownloaderBot/internal/cache"
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
+	"github.com/lartie/RedditDownloaderBot/pkg/markdown"
 	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
 	"github.com/lartie/RedditDownloaderBot/pkg/util"
+	"html"
 	"io"
+	"log"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
@@ -18,6 +25,16 @@ var disableIncludeLinkInCaption = util.ParseEnvironmentVariableBool("DISABLE_LIN
 // https://core.telegram.org/bots/api#formatting-options
 var markdownEscaper = strings.NewReplacer("_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)", "~", "\\~", "`", "\\`", ">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-", "=", "\\=", "|", "\\|", "{", "\\{", "}", "\\}", ".", "\\.", "!", "\\!")
 
+// The ways which a media can be sent to Telegram. They are a part of the file ID cache keys
+// because for example, the file ID of a document cannot be sent as a photo.
+const (
+	fileIDModePhoto     = "photo"
+	fileIDModeDocument  = "document"
+	fileIDModeVideo     = "video"
+	fileIDModeAnimation = "animation"
+	fileIDModeAudio     = "audio"
+)
+
 // createPhotoInlineKeyboard creates inline keyboards to get the quality info of a photo
 // Each row represents a quality and each row has two columns: Send as photo or send as file
 // The id must match the ID in the mediaCache
@@ -85,16 +102,31 @@ func createVideoInlineKeyboard(id string, medias reddit.FetchResultMedia) gotgbo
 	return gotgbot.InlineKeyboardMarkup{InlineKeyboard: rows}
 }
 
-// Adds the link of the post to a text if needed (the INCLUDE_LINK is set)
-func addLinkIfNeeded(text, link string) string {
-	if link == "" {
-		return text
+// appendLinkBlockIfNeeded adds the link of the post to the HTML blocks of markdown.Blocks if
+// needed (DISABLE_LINK_IN_CAPTION is not set)
+func appendLinkBlockIfNeeded(blocks []string, link string) []string {
+	if link == "" || disableIncludeLinkInCaption {
+		return blocks
 	}
+	return append(blocks, `<a href="`+html.EscapeString(link)+`">🔗 Link</a>`)
+}
+
+// spoilerBlocks hides the HTML blocks of markdown.Blocks behind spoilers
+func spoilerBlocks(blocks []string) []string {
+	result := make([]string, len(blocks))
+	for i, block := range blocks {
+		result[i] = "<tg-spoiler>" + block + "</tg-spoiler>"
+	}
+	return result
+}
 
-	if disableIncludeLinkInCaption {
-		return text
+// textPostBlocks converts a text post to HTML blocks. The title is the first block.
+func textPostBlocks(post reddit.FetchResultText) []string {
+	textBlocks := markdown.Blocks(post.Text)
+	if post.Spoiler {
+		textBlocks = spoilerBlocks(textBlocks)
 	}
-	return text + "\n\n" + "[🔗 Link](" + link + ")"
+	return append([]string{"<b>" + html.EscapeString(post.Title) + "</b>"}, textBlocks...)
 }
 
 // escapeMarkdown will escape the characters which are not ok in markdown
@@ -131,25 +163,109 @@ func getLinkMapOfFetchResultMediaEntries(entries reddit.FetchResultMediaEntries)
 }
 
 // Sends a post description to the bot if it exists.
-// Replies to a message. Can optionally use markdown if the description
-// fits in a message.
-func sendPostDescription(bot *gotgbot.Bot, description string, sentMessage *gotgbot.Message, useMarkdown bool) error {
-	var err error = nil
-	if description != "" { // if the description is empty don't do anything
-		if len(description) > maxTextSize {
-			_, err = bot.SendDocument(sentMessage.Chat.Id, &gotgbot.FileReader{
-				Name: "description.txt",
-				Data: strings.NewReader(description),
-			}, &gotgbot.SendDocumentOpts{ReplyParameters: &gotgbot.ReplyParameters{
-				MessageId: sentMessage.MessageId,
-			}})
-		} else {
-			opts := new(gotgbot.SendMessageOpts)
-			if useMarkdown {
-				opts.ParseMode = gotgbot.ParseModeMarkdownV2
+// Replies to a message. The description is the markdown of Reddit.
+func (c *Client) sendPostDescription(bot *gotgbot.Bot, description string, uid int64, sentMessage *gotgbot.Message) error {
+	if strings.TrimSpace(description) == "" { // if the description is empty don't do anything
+		return nil
+	}
+	return c.sendTextBlocks(bot, "", markdown.Blocks(description), uid, sentMessage.Chat.Id, sentMessage.MessageId)
+}
+
+// sendTextBlocks sends the HTML blocks of markdown.Blocks as a reply to a message. If they do not
+// fit in a message, they are either split into several messages or published as a Telegraph page
+// based on the settings of the user. title is the title of the page. replyTo can be zero.
+func (c *Client) sendTextBlocks(bot *gotgbot.Bot, title string, blocks []string, uid, chatID, replyTo int64) error {
+	messages := markdown.Split(blocks, maxTextSize)
+	if len(messages) > 1 && c.TextPublisher != nil {
+		if user := c.userSettings(uid); user.LongTextMode == settings.LongTextModeTelegraph {
+			pageURL, err := c.TextPublisher.Publish(title, blocks)
+			if err == nil {
+				message := `<a href="` + html.EscapeString(pageURL) + `">` + tr(user.Lang, "long_text.read") + "</a>"
+				if title != "" {
+					message = "<b>" + html.EscapeString(title) + "</b>\n\n" + message
+				}
+				messages = []string{message}
+			} else {
+				log.Println("Unable to publish the text as a page:", err)
 			}
-			_, err = sentMessage.Reply(bot, description, opts)
 		}
 	}
-	return err
+	for i, message := range messages {
+		opts := &gotgbot.SendMessageOpts{ParseMode: gotgbot.ParseModeHTML}
+		if i == 0 && replyTo != 0 {
+			opts.ReplyParameters = &gotgbot.ReplyParameters{MessageId: replyTo}
+		}
+		_, err := bot.SendMessage(chatID, message, opts)
+		if err != nil {
+			log.Println("Unable to send the formatted text:", err)
+			opts.ParseMode = gotgbot.ParseModeNone // fall back and don't format message
+			_, err = bot.SendMessage(chatID, markdown.PlainText(message), opts)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fileIDCacheKey creates the key of a media in the file ID cache. links must contain all the
+// links which the uploaded file is made of (like a video and its audio). Each quality has its
+// own link, so the quality is identified by them as well.
+func fileIDCacheKey(mode string, links ...string) string {
+	hash := sha256.New()
+	hash.Write([]byte(mode))
+	for _, link := range links {
+		hash.Write([]byte{0})
+		hash.Write([]byte(link))
+	}
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// sentFileID gets the file ID of the media in a message which we have sent.
+// Returns an empty string if the message does not contain any media.
+func sentFileID(msg *gotgbot.Message) string {
+	switch {
+	case msg == nil:
+		return ""
+	case msg.Animation != nil: // animations also have Document set, so check them first
+		return msg.Animation.FileId
+	case msg.Video != nil:
+		return msg.Video.FileId
+	case msg.Audio != nil:
+		return msg.Audio.FileId
+	case msg.Document != nil:
+		return msg.Document.FileId
+	case len(msg.Photo) != 0: // the last one is the biggest size
+		return msg.Photo[len(msg.Photo)-1].FileId
+	}
+	return ""
+}
+
+// selectQualityIndex picks a media from its qualities based on the quality in the settings of a user.
+// The qualities are sorted by their area. Returns -1 if the user wants to be asked.
+func selectQualityIndex(medias reddit.FetchResultMediaEntries, quality settings.MediaQuality) int {
+	// sort by area descending (original first)
+	type pair struct {
+		idx  int
+		area int64
+	}
+	arr := make([]pair, 0, len(medias))
+	for i, m := range medias {
+		area := m.Dim.Width * m.Dim.Height
+		arr = append(arr, pair{i, area})
+	}
+	sort.Slice(arr, func(i, j int) bool { return arr[i].area > arr[j].area })
+	switch quality {
+	case settings.QualityOriginal:
+		return arr[0].idx
+	case settings.QualityHigh:
+		if len(arr) >= 3 { // original + high + low (or more)
+			return arr[1].idx
+		}
+		return arr[0].idx // map to original when only original/low
+	case settings.QualityLow:
+		return arr[len(arr)-1].idx
+	default:
+		return -1
+	}
 }
@@ -0,0 +1,31 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lartie/RedditDownloaderBot/internal/settings"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendTextBlocksLongPoll(t *testing.T) {
+	bot, client := newFakeBot(t)
+	c := &Client{Settings: settings.NewMemoryStore()}
+	poll := reddit.FetchResultPoll{
+		Title:       "Cats?",
+		Description: strings.Repeat("a", 3000) + "\n\n" + strings.Repeat("b", 3000),
+		Options:     []reddit.FetchResultPollOption{{Text: "Yes"}, {Text: "No"}},
+	}
+	// Long texts are split into messages instead of being sent as a file
+	assert.NoError(t, c.sendTextBlocks(bot, poll.Title, pollSummary(settings.LangEN, poll), 1, 10, 5))
+	if assert.Len(t, client.requests, 2) {
+		for _, request := range client.requests {
+			assert.Equal(t, "sendMessage", request.Method)
+			assert.LessOrEqual(t, len([]rune(request.Params["text"])), maxTextSize)
+		}
+		// Only the first message is a reply
+		assert.Contains(t, client.requests[0].Params["reply_parameters"], `"message_id":5`)
+		assert.Empty(t, client.requests[1].Params["reply_parameters"])
+	}
+}
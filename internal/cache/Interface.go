@@ -1,26 +1,60 @@
 package cache
 
 import (
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"time"
+
 	"github.com/go-faster/errors"
 )
 
 // NotFoundErr will be returned if the key does not exist in database
 var NotFoundErr = errors.New("key not found")
 
+// fileIDCacheTTL is the time which file IDs of uploaded files are kept. Telegram keeps
+// the files for a long time, so this can be much more than the callback data TTL.
+const fileIDCacheTTL = 24 * time.Hour
+
 // Interface provides the interface to interface with a cache
 type Interface interface {
+	// Results of reddit.Oauth.StartFetch can be cached in here. The entries of this namespace
+	// have their own ttl and they are not deleted when read.
+	reddit.FetchResultCache
 	// SetMediaCache sets a key and overwrite any old entries in cache. It should be used for
 	// storing media data in cache
 	SetMediaCache(key string, value CallbackDataCached) error
 	// GetAndDeleteMediaCache will atomically get a media cache and delete it from cache.
 	// If it does not exist, returns NotFoundErr as error
 	GetAndDeleteMediaCache(key string) (CallbackDataCached, error)
+	// GetMediaCache gets a media cache without deleting it. Use TouchMediaCache to keep it alive.
+	// If it does not exist, returns NotFoundErr as error
+	GetMediaCache(key string) (CallbackDataCached, error)
+	// TouchMediaCache resets the ttl of a media cache, so it expires ttl after now.
+	// If it does not exist, returns NotFoundErr as error
+	TouchMediaCache(key string) error
 	// SetAlbumCache sets a key and overwrite any old entries in cache. It should be used for
 	// storing album data in cache
 	SetAlbumCache(key string, value CallbackAlbumCached) error
 	// GetAndDeleteAlbumCache will atomically get an album cache and delete it from cache.
 	// If it does not exist, returns NotFoundErr as error
 	GetAndDeleteAlbumCache(key string) (CallbackAlbumCached, error)
+	// GetAlbumCache gets an album cache without deleting it. Use TouchAlbumCache to keep it alive.
+	// If it does not exist, returns NotFoundErr as error
+	GetAlbumCache(key string) (CallbackAlbumCached, error)
+	// TouchAlbumCache resets the ttl of an album cache, so it expires ttl after now.
+	// If it does not exist, returns NotFoundErr as error
+	TouchAlbumCache(key string) error
+	// SetFileIDCache stores the Telegram file ID of an uploaded media. The key must identify
+	// the media link, its quality and the way it was sent to Telegram.
+	SetFileIDCache(key string, value FileIDCached) error
+	// GetFileIDCache gets the file ID of an uploaded media without deleting it.
+	// If it does not exist, returns NotFoundErr as error
+	GetFileIDCache(key string) (FileIDCached, error)
+	// DeleteFileIDCache deletes a file ID. It should be used when Telegram does not accept a
+	// file ID anymore.
+	DeleteFileIDCache(key string) error
+	// Stats gets the hit, miss and eviction counters of the cache. Get and GetAndDelete
+	// methods are counted as lookups.
+	Stats() (Stats, error)
 	// Close must close the underlying database connection
 	Close() error
 }
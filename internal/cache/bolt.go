@@ -0,0 +1,330 @@
+package cache
+
+import (
+	"encoding/binary"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-faster/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ Interface = &BoltCache{}
+
+// Define the buckets of each namespace. Each bucket is named after the schema kind of its values.
+var boltMediaCacheBucket = []byte(cacheSchemaKindMedia)
+var boltAlbumCacheBucket = []byte(cacheSchemaKindAlbum)
+var boltFileIDCacheBucket = []byte(cacheSchemaKindFileID)
+var boltFetchResultCacheBucket = []byte(cacheSchemaKindFetchResult)
+
+// The database is compacted only if it's bigger than this and at least half of it is free.
+// Changed in tests.
+var boltCompactMinSize int64 = 16 << 20
+
+// Maximum size of each transaction which copies the data while compacting
+const boltCompactTxMaxSize = 4 << 20
+
+// BoltCache satisfies Interface backed by an embedded bbolt database file. Unlike MemoryCache,
+// the entries survive the restarts of the bot.
+//
+// Each value is stored as the big endian unix nano time which it expires at, followed by its
+// encoding from encodeCacheValue. Expired entries are not returned and are deleted in the clean-up. bbolt never
+// shrinks its file, so the database is also compacted in the clean-up when most of it is free.
+type BoltCache struct {
+	path string
+	ttl  time.Duration
+	// Held for reading by every operation and for writing while db is replaced by compaction
+	lock sync.RWMutex
+	db   *bolt.DB
+	// Counters which are reported by BoltCache.Stats
+	hits, misses atomic.Uint64
+	// Close this channel to stop the cleanup
+	cleanUpDoneChannel chan struct{}
+}
+
+// NewBoltCache will open (or create) the database file at path.
+//
+// ttl is the time which entries will live and cleanUpInterval is the time which expired values will
+// be purged from the database
+func NewBoltCache(path string, ttl, cleanUpInterval time.Duration) (*BoltCache, error) {
+	db, err := openBoltCache(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &BoltCache{
+		path:               path,
+		ttl:                ttl,
+		db:                 db,
+		cleanUpDoneChannel: make(chan struct{}),
+	}
+	go c.cleanUp(cleanUpInterval)
+	return c, nil
+}
+
+// openBoltCache opens the database and creates its buckets
+func openBoltCache(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open the database")
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{boltMediaCacheBucket, boltAlbumCacheBucket, boltFileIDCacheBucket, boltFetchResultCacheBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "cannot create the buckets")
+	}
+	return db, nil
+}
+
+// cleanUp must be executed in another goroutine. It blocks and waits either for
+// cleanUpInterval seconds or BoltCache.cleanUpDoneChannel is closed
+func (c *BoltCache) cleanUp(cleanUpInterval time.Duration) {
+	cleanUpWait := time.NewTicker(cleanUpInterval)
+	for {
+		select {
+		case <-cleanUpWait.C:
+			if err := c.deleteExpired(); err != nil {
+				log.Println("Cannot delete the expired cache entries:", err)
+			}
+			if err := c.compact(); err != nil {
+				log.Println("Cannot compact the cache database:", err)
+			}
+		case <-c.cleanUpDoneChannel:
+			cleanUpWait.Stop()
+			return
+		}
+	}
+}
+
+// deleteExpired deletes the expired entries of all buckets
+func (c *BoltCache) deleteExpired() error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	now := time.Now()
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, bucket *bolt.Bucket) error {
+			// Deleting keys while iterating over a cursor skips some keys
+			var expiredKeys [][]byte
+			err := bucket.ForEach(func(k, v []byte) error {
+				if boltValueExpired(v, now) {
+					expiredKeys = append(expiredKeys, k)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, k := range expiredKeys {
+				if err = bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// compact copies the database to a new file and replaces the old one if the
+// database is big and mostly free
+func (c *BoltCache) compact() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var size int64
+	_ = c.db.View(func(tx *bolt.Tx) error {
+		size = tx.Size()
+		return nil
+	})
+	stats := c.db.Stats()
+	free := int64(stats.FreePageN+stats.PendingPageN) * int64(c.db.Info().PageSize)
+	if size < boltCompactMinSize || free*2 < size {
+		return nil
+	}
+	// Copy to a new file
+	compactPath := c.path + ".compact"
+	dst, err := bolt.Open(compactPath, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return errors.Wrap(err, "cannot open the compacted database")
+	}
+	if err = bolt.Compact(dst, c.db, boltCompactTxMaxSize); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(compactPath)
+		return errors.Wrap(err, "cannot copy the database")
+	}
+	// Replace the old database with the compacted one. The compacted database is kept open and
+	// used after the rename, so the cache is never left without an open database: If the rename
+	// fails, the old database is still used.
+	if err = os.Rename(compactPath, c.path); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(compactPath)
+		return errors.Wrap(err, "cannot replace the database")
+	}
+	oldDB := c.db
+	c.db = dst
+	if err = oldDB.Close(); err != nil {
+		return errors.Wrap(err, "cannot close the old database")
+	}
+	return nil
+}
+
+func (c *BoltCache) SetMediaCache(key string, value CallbackDataCached) error {
+	return c.set(boltMediaCacheBucket, key, value, c.ttl)
+}
+
+func (c *BoltCache) GetAndDeleteMediaCache(key string) (CallbackDataCached, error) {
+	return boltGet[CallbackDataCached](c, boltMediaCacheBucket, key, true)
+}
+
+func (c *BoltCache) GetMediaCache(key string) (CallbackDataCached, error) {
+	return boltGet[CallbackDataCached](c, boltMediaCacheBucket, key, false)
+}
+
+func (c *BoltCache) TouchMediaCache(key string) error {
+	return c.touch(boltMediaCacheBucket, key, c.ttl)
+}
+
+func (c *BoltCache) SetAlbumCache(key string, value CallbackAlbumCached) error {
+	return c.set(boltAlbumCacheBucket, key, value, c.ttl)
+}
+
+func (c *BoltCache) GetAndDeleteAlbumCache(key string) (CallbackAlbumCached, error) {
+	return boltGet[CallbackAlbumCached](c, boltAlbumCacheBucket, key, true)
+}
+
+func (c *BoltCache) GetAlbumCache(key string) (CallbackAlbumCached, error) {
+	return boltGet[CallbackAlbumCached](c, boltAlbumCacheBucket, key, false)
+}
+
+func (c *BoltCache) TouchAlbumCache(key string) error {
+	return c.touch(boltAlbumCacheBucket, key, c.ttl)
+}
+
+func (c *BoltCache) SetFileIDCache(key string, value FileIDCached) error {
+	return c.set(boltFileIDCacheBucket, key, value, fileIDCacheTTL)
+}
+
+func (c *BoltCache) GetFileIDCache(key string) (FileIDCached, error) {
+	return boltGet[FileIDCached](c, boltFileIDCacheBucket, key, false)
+}
+
+func (c *BoltCache) DeleteFileIDCache(key string) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltFileIDCacheBucket).Delete([]byte(key))
+	})
+}
+
+func (c *BoltCache) SetFetchResultCache(key string, value reddit.CachedFetchResult, ttl time.Duration) error {
+	return c.set(boltFetchResultCacheBucket, key, value, ttl)
+}
+
+func (c *BoltCache) GetFetchResultCache(key string) (reddit.CachedFetchResult, error) {
+	return boltGet[reddit.CachedFetchResult](c, boltFetchResultCacheBucket, key, false)
+}
+
+// Stats gets the hits and misses of the cache. BoltCache has no size limit, so it never evicts
+// entries.
+func (c *BoltCache) Stats() (Stats, error) {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}, nil
+}
+
+// Close will cancel the clean-up goroutine and close the database
+func (c *BoltCache) Close() error {
+	close(c.cleanUpDoneChannel)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.db.Close()
+}
+
+// set stores the encoding of value which expires after ttl
+func (c *BoltCache) set(bucket []byte, key string, value any, ttl time.Duration) error {
+	data, err := encodeCacheValue(value)
+	if err != nil {
+		return err
+	}
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), boltValue(data, time.Now().Add(ttl)))
+	})
+}
+
+// touch resets the expiry time of a key to ttl after now. Returns NotFoundErr if the key
+// does not exist or is expired.
+func (c *BoltCache) touch(bucket []byte, key string, ttl time.Duration) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		value := b.Get([]byte(key))
+		if value == nil || boltValueExpired(value, time.Now()) {
+			return NotFoundErr
+		}
+		// The value is only valid during the transaction and cannot be modified in place
+		return b.Put([]byte(key), boltValue(value[8:], time.Now().Add(ttl)))
+	})
+}
+
+// boltGet gets a key from a bucket and parses it as the generic struct passed to it.
+// If del is true, the key is deleted in the same transaction.
+func boltGet[T any](c *BoltCache, bucket []byte, key string, del bool) (T, error) {
+	var result T
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	getValue := func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		value := b.Get([]byte(key))
+		if value == nil || boltValueExpired(value, time.Now()) {
+			return NotFoundErr
+		}
+		var err error
+		result, err = decodeCacheValue[T](cacheSchemaKind(bucket), value[8:])
+		if err != nil {
+			return err
+		}
+		if del {
+			return b.Delete([]byte(key))
+		}
+		return nil
+	}
+	var err error
+	if del {
+		err = c.db.Update(getValue)
+	} else {
+		err = c.db.View(getValue)
+	}
+	if errors.Is(err, NotFoundErr) {
+		c.misses.Add(1)
+	} else if err == nil {
+		c.hits.Add(1)
+	}
+	return result, err
+}
+
+// boltValue creates the stored value of encoded data which expires at expiresAt
+func boltValue(data []byte, expiresAt time.Time) []byte {
+	value := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(value, uint64(expiresAt.UnixNano()))
+	return append(value, data...)
+}
+
+// boltValueExpired checks if a stored value has expired at now. Malformed values are considered expired.
+func boltValueExpired(value []byte, now time.Time) bool {
+	if len(value) < 8 {
+		return true
+	}
+	return int64(binary.BigEndian.Uint64(value[:8])) < now.UnixNano()
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+// newTestBoltCache creates a BoltCache in a temp directory which is closed when the test ends.
+// The clean-up is never run in the background; Tests run it themselves.
+func newTestBoltCache(t *testing.T, ttl time.Duration) *BoltCache {
+	c, err := NewBoltCache(filepath.Join(t.TempDir(), "cache.db"), ttl, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// boltKeyCount counts the stored keys of a bucket, including the expired ones
+func boltKeyCount(t *testing.T, c *BoltCache, bucket []byte) int {
+	var count int
+	err := c.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(bucket).Stats().KeyN
+		return nil
+	})
+	assert.NoError(t, err)
+	return count
+}
+
+func TestBoltCacheTTL(t *testing.T) {
+	c := newTestBoltCache(t, time.Hour)
+	media := CallbackDataCached{PostLink: "https://redd.it/a", Title: "title", AudioIndex: -1}
+	assert.NoError(t, c.SetMediaCache("alive", media))
+	got, err := c.GetMediaCache("alive")
+	assert.NoError(t, err)
+	assert.Equal(t, media, got)
+	// The fetch results have their own TTL
+	result := reddit.CachedFetchResult{Text: &reddit.FetchResultText{Title: "title"}}
+	assert.NoError(t, c.SetFetchResultCache("expired", result, -time.Second))
+	_, err = c.GetFetchResultCache("expired")
+	assert.ErrorIs(t, err, NotFoundErr)
+	assert.NoError(t, c.SetFetchResultCache("alive", result, time.Hour))
+	cachedResult, err := c.GetFetchResultCache("alive")
+	assert.NoError(t, err)
+	assert.Equal(t, result, cachedResult)
+	// The expired entries stay in the database until the clean-up
+	assert.Equal(t, 2, boltKeyCount(t, c, boltFetchResultCacheBucket))
+	assert.NoError(t, c.deleteExpired())
+	assert.Equal(t, 1, boltKeyCount(t, c, boltFetchResultCacheBucket))
+	assert.Equal(t, 1, boltKeyCount(t, c, boltMediaCacheBucket))
+	// Expired entries cannot be touched
+	expired := newTestBoltCache(t, -time.Second)
+	assert.NoError(t, expired.SetAlbumCache("album", CallbackAlbumCached{PostLink: "https://redd.it/b"}))
+	assert.ErrorIs(t, expired.TouchAlbumCache("album"), NotFoundErr)
+	_, err = expired.GetAlbumCache("album")
+	assert.ErrorIs(t, err, NotFoundErr)
+	// Touching resets the TTL
+	expired.ttl = time.Hour
+	assert.NoError(t, expired.SetAlbumCache("album", CallbackAlbumCached{PostLink: "https://redd.it/b"}))
+	expired.ttl = -time.Second
+	assert.NoError(t, expired.TouchAlbumCache("album"))
+	_, err = expired.GetAlbumCache("album")
+	assert.ErrorIs(t, err, NotFoundErr)
+}
+
+func TestBoltCacheGetAndDelete(t *testing.T) {
+	c := newTestBoltCache(t, time.Hour)
+	media := CallbackDataCached{PostLink: "https://redd.it/a", Links: map[int]Media{0: {Link: "https://i.redd.it/a.jpg"}}, AudioIndex: -1}
+	album := CallbackAlbumCached{PostLink: "https://redd.it/b", Album: reddit.FetchResultAlbum{Title: "album"}}
+	assert.NoError(t, c.SetMediaCache("media", media))
+	assert.NoError(t, c.SetAlbumCache("album", album))
+	gotMedia, err := c.GetAndDeleteMediaCache("media")
+	assert.NoError(t, err)
+	assert.Equal(t, media, gotMedia)
+	_, err = c.GetAndDeleteMediaCache("media")
+	assert.ErrorIs(t, err, NotFoundErr)
+	_, err = c.GetMediaCache("media")
+	assert.ErrorIs(t, err, NotFoundErr)
+	gotAlbum, err := c.GetAndDeleteAlbumCache("album")
+	assert.NoError(t, err)
+	assert.Equal(t, album, gotAlbum)
+	_, err = c.GetAlbumCache("album")
+	assert.ErrorIs(t, err, NotFoundErr)
+	// The file IDs are deleted explicitly
+	assert.NoError(t, c.SetFileIDCache("file", FileIDCached{FileID: "abc"}))
+	assert.NoError(t, c.DeleteFileIDCache("file"))
+	_, err = c.GetFileIDCache("file")
+	assert.ErrorIs(t, err, NotFoundErr)
+	stats, err := c.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, Stats{Hits: 2, Misses: 4}, stats)
+}
+
+func TestBoltCacheCompact(t *testing.T) {
+	oldMinSize := boltCompactMinSize
+	boltCompactMinSize = 1 << 20
+	defer func() { boltCompactMinSize = oldMinSize }()
+	// Closed in the test to be reopened
+	c, err := NewBoltCache(filepath.Join(t.TempDir(), "cache.db"), time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Small databases are not compacted
+	assert.NoError(t, c.compact())
+	_, err = os.Stat(c.path + ".compact")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	// Fill the database and then free most of it
+	description := strings.Repeat("a", 4096)
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, c.SetMediaCache(strconv.Itoa(i), CallbackDataCached{Description: description}))
+	}
+	kept := CallbackDataCached{PostLink: "https://redd.it/kept", AudioIndex: -1}
+	assert.NoError(t, c.SetMediaCache("kept", kept))
+	for i := 0; i < 1000; i++ {
+		_, err = c.GetAndDeleteMediaCache(strconv.Itoa(i))
+		assert.NoError(t, err)
+	}
+	before, err := os.Stat(c.path)
+	assert.NoError(t, err)
+	assert.NoError(t, c.compact())
+	after, err := os.Stat(c.path)
+	assert.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size()/2)
+	_, err = os.Stat(c.path + ".compact")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	// The compacted database is used
+	got, err := c.GetMediaCache("kept")
+	assert.NoError(t, err)
+	assert.Equal(t, kept, got)
+	assert.NoError(t, c.SetFileIDCache("file", FileIDCached{FileID: "abc"}))
+	// And it's the one which is opened after a restart
+	assert.NoError(t, c.Close())
+	reopened, err := NewBoltCache(c.path, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	got, err = reopened.GetMediaCache("kept")
+	assert.NoError(t, err)
+	assert.Equal(t, kept, got)
+	fileID, err := reopened.GetFileIDCache("file")
+	assert.NoError(t, err)
+	assert.Equal(t, FileIDCached{FileID: "abc"}, fileID)
+}
@@ -1,63 +1,241 @@
 package cache
 
 import (
+	"container/list"
+	"encoding/json"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
 	"sync"
 	"time"
 )
 
 var _ Interface = &MemoryCache{}
 
-// singleMemoryCache is a KV cache which it's elements are deleted when cleanUp is called.
-// Data is stored in ram
-type singleMemoryCache[K comparable, V any] struct {
-	// The cache itself
-	cache map[K]memoryCacheElement[V]
+// MemoryCacheLimits bounds the size of a MemoryCache. The limits are shared between all the
+// namespaces of the cache. When either of them is exceeded, the least recently used entries are
+// evicted. Zero means no limit.
+type MemoryCacheLimits struct {
+	// Maximum number of entries in the cache
+	MaxEntries int
+	// Maximum approximate size of the entries in bytes. The size of each entry is the
+	// length of its JSON encoding plus its key.
+	MaxBytes int64
+}
+
+// memoryCacheStore is the state shared between all the namespaces of a MemoryCache.
+// Every namespace uses the lock of its store, so an insertion in one namespace can
+// evict the entries of another one.
+type memoryCacheStore struct {
 	// A mutex to sync stuff
 	lock sync.Mutex
+	// Entries of all namespaces from the most recently used to the least recently used.
+	// Values are *memoryCacheLRUEntry
+	lru *list.List
+	// Sum of the size of all entries
+	bytes  int64
+	limits MemoryCacheLimits
+	// Counters which are reported by MemoryCache.Stats
+	hits, misses, evictions uint64
+}
+
+// memoryCacheLRUEntry is the value of each element in memoryCacheStore.lru
+type memoryCacheLRUEntry struct {
+	size int64
+	// Deletes the entry from the map of its namespace. Must be called with the lock held.
+	remove func()
+}
+
+// newMemoryCacheStore creates an empty store with the given limits
+func newMemoryCacheStore(limits MemoryCacheLimits) *memoryCacheStore {
+	return &memoryCacheStore{
+		lru:    list.New(),
+		limits: limits,
+	}
+}
+
+// sizeOf approximates the size of a key and value. It's zero if there is no limit on bytes.
+func (s *memoryCacheStore) sizeOf(key string, value any) int64 {
+	if s.limits.MaxBytes <= 0 {
+		return 0
+	}
+	data, _ := json.Marshal(value)
+	return int64(len(key) + len(data))
+}
+
+// overLimits reports if the store has exceeded any of its limits
+func (s *memoryCacheStore) overLimits() bool {
+	return (s.limits.MaxEntries > 0 && s.lru.Len() > s.limits.MaxEntries) ||
+		(s.limits.MaxBytes > 0 && s.bytes > s.limits.MaxBytes)
+}
+
+// evict deletes the least recently used entries until the store is within its limits.
+// The most recently used entry is never evicted, even if it's larger than MaxBytes on its own.
+// Must be called with the lock held.
+func (s *memoryCacheStore) evict() {
+	for s.overLimits() && s.lru.Len() > 1 {
+		entry := s.lru.Remove(s.lru.Back()).(*memoryCacheLRUEntry)
+		s.bytes -= entry.size
+		entry.remove()
+		s.evictions++
+	}
+}
+
+// stats gets the counters of the store
+func (s *memoryCacheStore) stats() Stats {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return Stats{
+		Hits:      s.hits,
+		Misses:    s.misses,
+		Evictions: s.evictions,
+	}
+}
+
+// singleMemoryCache is a KV cache which it's elements are deleted when cleanUp is called
+// or when its store needs space for new elements.
+// Data is stored in ram
+type singleMemoryCache[V any] struct {
+	// The cache itself
+	cache map[string]memoryCacheElement[V]
+	// The lock, LRU list and limits which are shared with other namespaces
+	store *memoryCacheStore
 }
 
 // memoryCacheElement is each element in the map of in memory cache
 type memoryCacheElement[V any] struct {
 	data      V
 	addedTime time.Time
+	// If not zero, this element is expired after this duration instead of the ttl of the cache
+	ttl time.Duration
+	// The element of this entry in memoryCacheStore.lru
+	lruElement *list.Element
+}
+
+// expired checks if the element is older than its ttl. The ttl is used if the element
+// does not have its own ttl.
+func (e memoryCacheElement[V]) expired(ttl time.Duration) bool {
+	if e.ttl != 0 {
+		ttl = e.ttl
+	}
+	return time.Since(e.addedTime) > ttl
+}
+
+// newSingleMemoryCache creates an empty namespace in the given store
+func newSingleMemoryCache[V any](store *memoryCacheStore) singleMemoryCache[V] {
+	return singleMemoryCache[V]{
+		cache: make(map[string]memoryCacheElement[V]),
+		store: store,
+	}
 }
 
 // cleanUp will delete old entries from cache
-func (c *singleMemoryCache[K, V]) cleanUp(ttl time.Duration) {
-	c.lock.Lock()
+func (c *singleMemoryCache[V]) cleanUp(ttl time.Duration) {
+	c.store.lock.Lock()
 	for k, v := range c.cache {
-		if time.Since(v.addedTime) > ttl {
-			delete(c.cache, k)
+		if v.expired(ttl) {
+			c.remove(k)
 		}
 	}
-	c.lock.Unlock()
+	c.store.lock.Unlock()
+}
+
+// remove deletes an element from both the cache and the LRU list of the store.
+// Must be called with the lock held.
+func (c *singleMemoryCache[V]) remove(key string) {
+	data, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	entry := c.store.lru.Remove(data.lruElement).(*memoryCacheLRUEntry)
+	c.store.bytes -= entry.size
+	delete(c.cache, key)
 }
 
 // set will set a key and overwrite any old entries in cache
-func (c *singleMemoryCache[K, V]) set(key K, value V) {
-	c.lock.Lock()
+func (c *singleMemoryCache[V]) set(key string, value V) {
+	c.setWithTTL(key, value, 0)
+}
+
+// setWithTTL will set a key which expires after ttl instead of the ttl of the cache.
+// Zero ttl means the ttl of the cache.
+func (c *singleMemoryCache[V]) setWithTTL(key string, value V, ttl time.Duration) {
+	size := c.store.sizeOf(key, value)
+	c.store.lock.Lock()
+	c.remove(key)
 	c.cache[key] = memoryCacheElement[V]{
 		data:      value,
 		addedTime: time.Now(),
+		ttl:       ttl,
+		lruElement: c.store.lru.PushFront(&memoryCacheLRUEntry{
+			size:   size,
+			remove: func() { delete(c.cache, key) },
+		}),
 	}
-	c.lock.Unlock()
+	c.store.bytes += size
+	c.store.evict()
+	c.store.lock.Unlock()
 }
 
 // getAndDelete will atomically get and element and delete it from cache
-func (c *singleMemoryCache[K, V]) getAndDelete(key K) (V, bool) {
-	c.lock.Lock()
+func (c *singleMemoryCache[V]) getAndDelete(key string) (V, bool) {
+	c.store.lock.Lock()
 	data, ok := c.cache[key]
 	if ok {
-		delete(c.cache, key)
+		c.remove(key)
+		c.store.hits++
+	} else {
+		c.store.misses++
 	}
-	c.lock.Unlock()
+	c.store.lock.Unlock()
 	return data.data, ok
 }
 
+// get will get an element without deleting it from cache and marks it as recently used.
+// Elements which have their own ttl are not returned if they are expired but not cleaned up yet.
+func (c *singleMemoryCache[V]) get(key string) (V, bool) {
+	c.store.lock.Lock()
+	defer c.store.lock.Unlock()
+	data, ok := c.cache[key]
+	if !ok || (data.ttl != 0 && data.expired(data.ttl)) {
+		c.store.misses++
+		var empty V
+		return empty, false
+	}
+	c.store.lru.MoveToFront(data.lruElement)
+	c.store.hits++
+	return data.data, true
+}
+
+// touch will reset the added time of an element to now and marks it as recently used.
+// Returns false if it does not exist.
+func (c *singleMemoryCache[V]) touch(key string) bool {
+	c.store.lock.Lock()
+	data, ok := c.cache[key]
+	if ok {
+		data.addedTime = time.Now()
+		c.cache[key] = data
+		c.store.lru.MoveToFront(data.lruElement)
+	}
+	c.store.lock.Unlock()
+	return ok
+}
+
+// delete will delete an element from cache
+func (c *singleMemoryCache[V]) delete(key string) {
+	c.store.lock.Lock()
+	c.remove(key)
+	c.store.lock.Unlock()
+}
+
 // MemoryCache is an in memory cache to handle the callback data
 type MemoryCache struct {
-	mediaCache singleMemoryCache[string, CallbackDataCached]
-	albumCache singleMemoryCache[string, CallbackAlbumCached]
+	// Shared between all the namespaces below
+	store      *memoryCacheStore
+	mediaCache singleMemoryCache[CallbackDataCached]
+	albumCache singleMemoryCache[CallbackAlbumCached]
+	// Entries of this cache live for fileIDCacheTTL instead of the ttl of the cache
+	fileIDCache singleMemoryCache[FileIDCached]
+	// Each entry of this cache has its own ttl
+	fetchResultCache singleMemoryCache[reddit.CachedFetchResult]
 	// Close this channel to stop the cleanup
 	cleanUpDoneChannel chan struct{}
 }
@@ -65,15 +243,15 @@ type MemoryCache struct {
 // NewMemoryCache will create a timed cache of given type of key and value.
 //
 // ttl is the time which entries will live and cleanUpInterval is the time which old values will
-// be purged from cache
-func NewMemoryCache(ttl, cleanUpInterval time.Duration) *MemoryCache {
+// be purged from cache. limits bounds the size of the cache.
+func NewMemoryCache(ttl, cleanUpInterval time.Duration, limits MemoryCacheLimits) *MemoryCache {
+	store := newMemoryCacheStore(limits)
 	c := &MemoryCache{
-		mediaCache: singleMemoryCache[string, CallbackDataCached]{
-			cache: make(map[string]memoryCacheElement[CallbackDataCached]),
-		},
-		albumCache: singleMemoryCache[string, CallbackAlbumCached]{
-			cache: make(map[string]memoryCacheElement[CallbackAlbumCached]),
-		},
+		store:              store,
+		mediaCache:         newSingleMemoryCache[CallbackDataCached](store),
+		albumCache:         newSingleMemoryCache[CallbackAlbumCached](store),
+		fileIDCache:        newSingleMemoryCache[FileIDCached](store),
+		fetchResultCache:   newSingleMemoryCache[reddit.CachedFetchResult](store),
 		cleanUpDoneChannel: make(chan struct{}),
 	}
 	go c.cleanUp(ttl, cleanUpInterval)
@@ -89,6 +267,8 @@ func (c *MemoryCache) cleanUp(ttl, cleanUpInterval time.Duration) {
 		case <-cleanUpWait.C:
 			c.albumCache.cleanUp(ttl)
 			c.mediaCache.cleanUp(ttl)
+			c.fileIDCache.cleanUp(fileIDCacheTTL)
+			c.fetchResultCache.cleanUp(ttl)
 		case <-c.cleanUpDoneChannel:
 			cleanUpWait.Stop()
 			return
@@ -110,6 +290,22 @@ func (c *MemoryCache) GetAndDeleteMediaCache(key string) (CallbackDataCached, er
 	return value, err
 }
 
+func (c *MemoryCache) GetMediaCache(key string) (CallbackDataCached, error) {
+	value, exists := c.mediaCache.get(key)
+	var err error
+	if !exists {
+		err = NotFoundErr
+	}
+	return value, err
+}
+
+func (c *MemoryCache) TouchMediaCache(key string) error {
+	if !c.mediaCache.touch(key) {
+		return NotFoundErr
+	}
+	return nil
+}
+
 func (c *MemoryCache) SetAlbumCache(key string, value CallbackAlbumCached) error {
 	c.albumCache.set(key, value)
 	return nil
@@ -124,6 +320,59 @@ func (c *MemoryCache) GetAndDeleteAlbumCache(key string) (CallbackAlbumCached, e
 	return value, err
 }
 
+func (c *MemoryCache) GetAlbumCache(key string) (CallbackAlbumCached, error) {
+	value, exists := c.albumCache.get(key)
+	var err error
+	if !exists {
+		err = NotFoundErr
+	}
+	return value, err
+}
+
+func (c *MemoryCache) TouchAlbumCache(key string) error {
+	if !c.albumCache.touch(key) {
+		return NotFoundErr
+	}
+	return nil
+}
+
+func (c *MemoryCache) SetFileIDCache(key string, value FileIDCached) error {
+	c.fileIDCache.set(key, value)
+	return nil
+}
+
+func (c *MemoryCache) GetFileIDCache(key string) (FileIDCached, error) {
+	value, exists := c.fileIDCache.get(key)
+	var err error
+	if !exists {
+		err = NotFoundErr
+	}
+	return value, err
+}
+
+func (c *MemoryCache) DeleteFileIDCache(key string) error {
+	c.fileIDCache.delete(key)
+	return nil
+}
+
+func (c *MemoryCache) SetFetchResultCache(key string, value reddit.CachedFetchResult, ttl time.Duration) error {
+	c.fetchResultCache.setWithTTL(key, value, ttl)
+	return nil
+}
+
+func (c *MemoryCache) GetFetchResultCache(key string) (reddit.CachedFetchResult, error) {
+	value, exists := c.fetchResultCache.get(key)
+	var err error
+	if !exists {
+		err = NotFoundErr
+	}
+	return value, err
+}
+
+func (c *MemoryCache) Stats() (Stats, error) {
+	return c.store.stats(), nil
+}
+
 // Close will cancel the clean-up goroutine
 func (c *MemoryCache) Close() error {
 	close(c.cleanUpDoneChannel)
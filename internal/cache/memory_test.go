@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestMemoryCache creates a MemoryCache which is closed when the test ends
+func newTestMemoryCache(t *testing.T, ttl time.Duration, limits MemoryCacheLimits) *MemoryCache {
+	c := NewMemoryCache(ttl, time.Hour, limits)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// memoryEntrySize is the size which memoryCacheStore.sizeOf gives to an entry
+func memoryEntrySize(key string, value any) int64 {
+	data, _ := json.Marshal(value)
+	return int64(len(key) + len(data))
+}
+
+func TestMemoryCacheEvictionOrder(t *testing.T) {
+	c := newTestMemoryCache(t, time.Hour, MemoryCacheLimits{MaxEntries: 3})
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, c.SetFileIDCache(strconv.Itoa(i), FileIDCached{FileID: strconv.Itoa(i)}))
+	}
+	// Reading 0 makes 1 the least recently used
+	_, err := c.GetFileIDCache("0")
+	assert.NoError(t, err)
+	// The namespaces share the limits
+	assert.NoError(t, c.SetMediaCache("media", CallbackDataCached{Title: "media"}))
+	_, err = c.GetFileIDCache("1")
+	assert.ErrorIs(t, err, NotFoundErr)
+	// Then 2 is the least recently used
+	assert.NoError(t, c.SetAlbumCache("album", CallbackAlbumCached{PostLink: "album"}))
+	_, err = c.GetFileIDCache("2")
+	assert.ErrorIs(t, err, NotFoundErr)
+	_, err = c.GetFileIDCache("0")
+	assert.NoError(t, err)
+	_, err = c.GetMediaCache("media")
+	assert.NoError(t, err)
+	_, err = c.GetAlbumCache("album")
+	assert.NoError(t, err)
+	// Setting a key again does not add an entry
+	assert.NoError(t, c.SetMediaCache("media", CallbackDataCached{Title: "new media"}))
+	media, err := c.GetMediaCache("media")
+	assert.NoError(t, err)
+	assert.Equal(t, "new media", media.Title)
+	assert.Equal(t, 3, c.store.lru.Len())
+	stats, err := c.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, Stats{Hits: 5, Misses: 2, Evictions: 2}, stats)
+}
+
+func TestMemoryCacheTouch(t *testing.T) {
+	c := newTestMemoryCache(t, time.Hour, MemoryCacheLimits{MaxEntries: 2})
+	assert.NoError(t, c.SetMediaCache("a", CallbackDataCached{Title: "a"}))
+	assert.NoError(t, c.SetMediaCache("b", CallbackDataCached{Title: "b"}))
+	// Touching makes a the most recently used, so b is evicted
+	assert.NoError(t, c.TouchMediaCache("a"))
+	assert.NoError(t, c.SetAlbumCache("c", CallbackAlbumCached{PostLink: "c"}))
+	_, err := c.GetMediaCache("b")
+	assert.ErrorIs(t, err, NotFoundErr)
+	_, err = c.GetMediaCache("a")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c.TouchMediaCache("b"), NotFoundErr)
+	assert.ErrorIs(t, c.TouchAlbumCache("a"), NotFoundErr)
+	// Touching resets the time which the entry expires at
+	added := c.mediaCache.cache["a"].addedTime
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, c.TouchMediaCache("a"))
+	assert.True(t, c.mediaCache.cache["a"].addedTime.After(added))
+	c.mediaCache.cleanUp(time.Hour)
+	_, err = c.GetMediaCache("a")
+	assert.NoError(t, err)
+	c.mediaCache.cleanUp(0)
+	_, err = c.GetMediaCache("a")
+	assert.ErrorIs(t, err, NotFoundErr)
+}
+
+func TestMemoryCacheBytes(t *testing.T) {
+	small := FileIDCached{FileID: "small"}
+	big := CallbackDataCached{Description: string(make([]byte, 100))}
+	smallSize := memoryEntrySize("small", small)
+	bigSize := memoryEntrySize("big", big)
+	c := newTestMemoryCache(t, time.Hour, MemoryCacheLimits{MaxBytes: 2*smallSize + bigSize})
+	// Set, overwrite and delete keep the size in sync
+	assert.NoError(t, c.SetFileIDCache("small", small))
+	assert.Equal(t, smallSize, c.store.bytes)
+	assert.NoError(t, c.SetFileIDCache("small", small))
+	assert.Equal(t, smallSize, c.store.bytes)
+	assert.NoError(t, c.SetMediaCache("big", big))
+	assert.Equal(t, smallSize+bigSize, c.store.bytes)
+	_, err := c.GetAndDeleteMediaCache("big")
+	assert.NoError(t, err)
+	assert.Equal(t, smallSize, c.store.bytes)
+	assert.NoError(t, c.DeleteFileIDCache("small"))
+	assert.Equal(t, int64(0), c.store.bytes)
+	// Evict until the new entry fits
+	assert.NoError(t, c.SetFileIDCache("small", small))
+	assert.NoError(t, c.SetFileIDCache("small2", small))
+	assert.NoError(t, c.SetMediaCache("big", big))
+	assert.LessOrEqual(t, c.store.bytes, c.store.limits.MaxBytes)
+	_, err = c.GetFileIDCache("small")
+	assert.ErrorIs(t, err, NotFoundErr)
+	_, err = c.GetFileIDCache("small2")
+	assert.NoError(t, err)
+	// An entry which is larger than the limit on its own is kept, but everything else is evicted
+	huge := CallbackDataCached{Description: string(make([]byte, 1000))}
+	assert.NoError(t, c.SetMediaCache("huge", huge))
+	assert.Equal(t, 1, c.store.lru.Len())
+	assert.Equal(t, memoryEntrySize("huge", huge), c.store.bytes)
+	_, err = c.GetMediaCache("huge")
+	assert.NoError(t, err)
+	// The huge entry is evicted for the next one. Expired entries are cleaned up with their size.
+	assert.NoError(t, c.SetFetchResultCache("fetch", reddit.CachedFetchResult{}, time.Nanosecond))
+	assert.Equal(t, memoryEntrySize("fetch", reddit.CachedFetchResult{}), c.store.bytes)
+	time.Sleep(time.Millisecond)
+	_, err = c.GetFetchResultCache("fetch")
+	assert.ErrorIs(t, err, NotFoundErr)
+	c.fetchResultCache.cleanUp(time.Hour)
+	assert.Equal(t, int64(0), c.store.bytes)
+	assert.Equal(t, 0, c.store.lru.Len())
+	stats, err := c.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), stats.Evictions)
+}
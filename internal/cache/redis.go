@@ -1,10 +1,11 @@
 package cache
 
 import (
-	"github.com/lartie/RedditDownloaderBot/pkg/util"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
 	"context"
-	"encoding/json"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-faster/errors"
@@ -16,71 +17,164 @@ var _ Interface = RedisCache{}
 // Define the prefixes of keys
 const redisMediaCachePrefix = "media:"
 const redisAlbumCachePrefix = "album:"
+const redisFileIDCachePrefix = "file:"
+const redisFetchResultCachePrefix = "fetch:"
 
 // RedisCache satisfies Interface backed by a Redis server
 type RedisCache struct {
 	ttl    time.Duration
 	client *redis.Client
+	// Hits and misses of this client. Evictions are reported by the Redis server itself.
+	hits, misses *atomic.Uint64
 }
 
-// NewRedisCache will create a new redis cache
-func NewRedisCache(address, password string, ttl time.Duration) (RedisCache, error) {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     address,
-		Password: password,
-	})
+// NewRedisCache will create a new redis cache from a connected client.
+// The client is closed when RedisCache.Close is called.
+func NewRedisCache(client *redis.Client, ttl time.Duration) RedisCache {
 	return RedisCache{
-		client: rdb,
+		client: client,
 		ttl:    ttl,
-	}, rdb.Ping(context.Background()).Err()
+		hits:   new(atomic.Uint64),
+		misses: new(atomic.Uint64),
+	}
 }
 
 func (r RedisCache) SetMediaCache(key string, value CallbackDataCached) error {
-	return r.client.
-		Set(context.Background(), redisMediaCachePrefix+key, util.ToJsonString(value), r.ttl).
-		Err()
+	return r.set(redisMediaCachePrefix+key, value, r.ttl)
 }
 
 func (r RedisCache) GetAndDeleteMediaCache(key string) (CallbackDataCached, error) {
-	return parseRedisJson[CallbackDataCached](
-		r.client.
-			GetDel(context.Background(), redisMediaCachePrefix+key).
-			Result(),
+	return redisGet[CallbackDataCached](
+		r,
+		cacheSchemaKindMedia,
+		r.client.GetDel(context.Background(), redisMediaCachePrefix+key),
+	)
+}
+
+func (r RedisCache) GetMediaCache(key string) (CallbackDataCached, error) {
+	return redisGet[CallbackDataCached](
+		r,
+		cacheSchemaKindMedia,
+		r.client.Get(context.Background(), redisMediaCachePrefix+key),
 	)
 }
 
+func (r RedisCache) TouchMediaCache(key string) error {
+	return r.touch(redisMediaCachePrefix + key)
+}
+
 func (r RedisCache) SetAlbumCache(key string, value CallbackAlbumCached) error {
+	return r.set(redisAlbumCachePrefix+key, value, r.ttl)
+}
+
+func (r RedisCache) GetAndDeleteAlbumCache(key string) (CallbackAlbumCached, error) {
+	return redisGet[CallbackAlbumCached](
+		r,
+		cacheSchemaKindAlbum,
+		r.client.GetDel(context.Background(), redisAlbumCachePrefix+key),
+	)
+}
+
+func (r RedisCache) GetAlbumCache(key string) (CallbackAlbumCached, error) {
+	return redisGet[CallbackAlbumCached](
+		r,
+		cacheSchemaKindAlbum,
+		r.client.Get(context.Background(), redisAlbumCachePrefix+key),
+	)
+}
+
+func (r RedisCache) TouchAlbumCache(key string) error {
+	return r.touch(redisAlbumCachePrefix + key)
+}
+
+func (r RedisCache) SetFileIDCache(key string, value FileIDCached) error {
+	return r.set(redisFileIDCachePrefix+key, value, fileIDCacheTTL)
+}
+
+func (r RedisCache) GetFileIDCache(key string) (FileIDCached, error) {
+	return redisGet[FileIDCached](
+		r,
+		cacheSchemaKindFileID,
+		r.client.Get(context.Background(), redisFileIDCachePrefix+key),
+	)
+}
+
+func (r RedisCache) DeleteFileIDCache(key string) error {
 	return r.client.
-		Set(context.Background(), redisAlbumCachePrefix+key, util.ToJsonString(value), r.ttl).
+		Del(context.Background(), redisFileIDCachePrefix+key).
 		Err()
 }
 
-func (r RedisCache) GetAndDeleteAlbumCache(key string) (CallbackAlbumCached, error) {
-	return parseRedisJson[CallbackAlbumCached](
-		r.client.
-			GetDel(context.Background(), redisAlbumCachePrefix+key).
-			Result(),
+func (r RedisCache) SetFetchResultCache(key string, value reddit.CachedFetchResult, ttl time.Duration) error {
+	return r.set(redisFetchResultCachePrefix+key, value, ttl)
+}
+
+func (r RedisCache) GetFetchResultCache(key string) (reddit.CachedFetchResult, error) {
+	return redisGet[reddit.CachedFetchResult](
+		r,
+		cacheSchemaKindFetchResult,
+		r.client.Get(context.Background(), redisFetchResultCachePrefix+key),
 	)
 }
 
+// Stats gets the hits and misses of this client. Evictions are the keys which the Redis
+// server has evicted because of its maxmemory limit, so they are shared with the other clients
+// of the same server.
+func (r RedisCache) Stats() (Stats, error) {
+	stats := Stats{
+		Hits:   r.hits.Load(),
+		Misses: r.misses.Load(),
+	}
+	info, err := r.client.Info(context.Background(), "stats").Result()
+	if err != nil {
+		return stats, errors.Wrap(err, "Unable to fetch stats from Redis")
+	}
+	for _, line := range strings.Split(info, "\n") {
+		if value, found := strings.CutPrefix(strings.TrimSpace(line), "evicted_keys:"); found {
+			stats.Evictions, _ = strconv.ParseUint(value, 10, 64)
+			break
+		}
+	}
+	return stats, nil
+}
+
 func (r RedisCache) Close() error {
 	return r.client.Close()
 }
 
-// parseRedisJson will get the value of a key which is in redis + the error message of redis.
-// Then, it tries to parse the data as the generic struct passed to it.
-func parseRedisJson[T any](val string, err error) (T, error) {
+// set encodes a value with its schema version and stores it
+func (r RedisCache) set(key string, value any, ttl time.Duration) error {
+	data, err := encodeCacheValue(value)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(context.Background(), key, data, ttl).Err()
+}
+
+// touch resets the ttl of a key. Returns NotFoundErr if the key does not exist.
+func (r RedisCache) touch(key string) error {
+	exists, err := r.client.Expire(context.Background(), key, r.ttl).Result()
+	if err != nil {
+		return errors.Wrap(err, "Unable to set the expiry in Redis")
+	}
+	if !exists {
+		return NotFoundErr
+	}
+	return nil
+}
+
+// redisGet gets the result of a get command and counts it as a hit or miss.
+// Then, it tries to decode the data of the given kind as the generic struct passed to it.
+func redisGet[T any](r RedisCache, kind cacheSchemaKind, cmd *redis.StringCmd) (T, error) {
 	// Check errors
 	var result T
+	val, err := cmd.Result()
 	if errors.Is(err, redis.Nil) {
+		r.misses.Add(1)
 		return result, NotFoundErr
 	} else if err != nil {
 		return result, errors.Wrap(err, "Unable to fetch data from Redis")
 	}
-	// Parse the json
-	err = json.NewDecoder(strings.NewReader(val)).Decode(&result)
-	if err != nil {
-		return result, errors.Wrap(err, "Unable to parse JSON")
-	}
-	return result, nil
+	r.hits.Add(1)
+	return decodeCacheValue[T](kind, []byte(val))
 }
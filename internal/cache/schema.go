@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/go-faster/errors"
+)
+
+// UnknownSchemaVersionErr is returned when a cached value has a newer schema version than
+// this program knows. This usually happens while a newer version of the bot is being deployed.
+var UnknownSchemaVersionErr = errors.New("unknown cache schema version")
+
+// cacheSchemaVersion is the schema version of the values which are written to the cache.
+// Whenever a stored type (like CallbackDataCached or anything in it) changes, bump this and register
+// an upgrade from the previous version of each changed kind in cacheSchemaUpgrades.
+const cacheSchemaVersion = 5
+
+// cacheSchemaKind is the kind of value which is stored. Each namespace of the cache has its own kind.
+type cacheSchemaKind string
+
+const (
+	cacheSchemaKindMedia       cacheSchemaKind = "media"
+	cacheSchemaKindAlbum       cacheSchemaKind = "album"
+	cacheSchemaKindFileID      cacheSchemaKind = "file"
+	cacheSchemaKindFetchResult cacheSchemaKind = "fetch"
+)
+
+// cacheSchemaUpgrade converts the json of a value from one schema version to the next one
+type cacheSchemaUpgrade func(data json.RawMessage) (json.RawMessage, error)
+
+// cacheSchemaUpgrades holds the upgrades of each kind keyed by the version which they upgrade from.
+// Kinds which have not changed in a version do not need an upgrade for it. The changes which only add
+// fields do not need an upgrade either: The older values are decoded with the zero value of the new
+// fields, and they are kept, not dropped. So far, every version has only added fields, so this is empty.
+//
+// Version 0 is the bare json which was stored before the envelope was added. It is the same as version 1.
+// Version 2 added the metadata of posts to the media values. The older values have empty metadata.
+// Version 3 added the NSFW and spoiler flags of posts to the media, album and fetched values. The older
+// values are not flagged.
+// Version 4 added the audio links and durations of the videos to the album and fetched values. The older
+// videos are sent without audio.
+// Version 5 added the polls to the fetched values. The programs which do not know the polls would read them
+// as empty values.
+var cacheSchemaUpgrades = map[cacheSchemaKind]map[int]cacheSchemaUpgrade{}
+
+// cacheEnvelope wraps every value which is stored in a serialized cache
+type cacheEnvelope struct {
+	Version int             `json:"version"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// encodeCacheValue encodes a value in an envelope with the current schema version
+func encodeCacheValue(value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to encode JSON")
+	}
+	return json.Marshal(cacheEnvelope{
+		Version: cacheSchemaVersion,
+		Data:    data,
+	})
+}
+
+// decodeCacheValue decodes a value which was encoded with encodeCacheValue by this or an older
+// version of the program. Values with older versions are upgraded to the current version before
+// being decoded. If the version is newer than cacheSchemaVersion, UnknownSchemaVersionErr is returned.
+func decodeCacheValue[T any](kind cacheSchemaKind, data []byte) (T, error) {
+	var result T
+	envelope, err := parseCacheEnvelope(data)
+	if err != nil {
+		return result, err
+	}
+	if envelope.Version > cacheSchemaVersion {
+		return result, errors.Wrap(UnknownSchemaVersionErr, "version "+strconv.Itoa(envelope.Version))
+	}
+	// Upgrade step by step
+	for version := envelope.Version; version < cacheSchemaVersion; version++ {
+		if upgrade, exists := cacheSchemaUpgrades[kind][version]; exists {
+			envelope.Data, err = upgrade(envelope.Data)
+			if err != nil {
+				return result, errors.Wrap(err, "cannot upgrade "+string(kind)+" from version "+strconv.Itoa(version))
+			}
+		}
+	}
+	if err = json.Unmarshal(envelope.Data, &result); err != nil {
+		return result, errors.Wrap(err, "Unable to parse JSON")
+	}
+	return result, nil
+}
+
+// parseCacheEnvelope parses the envelope of a value. Values without an envelope are version 0.
+func parseCacheEnvelope(data []byte) (cacheEnvelope, error) {
+	// The keys are checked exactly because json.Unmarshal matches the keys case-insensitively
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return cacheEnvelope{}, errors.Wrap(err, "Unable to parse JSON")
+	}
+	version, hasVersion := fields["version"]
+	payload, hasData := fields["data"]
+	if !hasVersion || !hasData || len(fields) != 2 {
+		return cacheEnvelope{Version: 0, Data: data}, nil
+	}
+	envelope := cacheEnvelope{Data: payload}
+	if err := json.Unmarshal(version, &envelope.Version); err != nil {
+		return cacheEnvelope{}, errors.Wrap(err, "Unable to parse the schema version")
+	}
+	return envelope, nil
+}
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"github.com/go-faster/errors"
+	"github.com/lartie/RedditDownloaderBot/pkg/reddit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheValueRoundTrip(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Value    any
+		Decode   func(data []byte) (any, error)
+	}{
+		{
+			TestName: "Media",
+			Value: CallbackDataCached{
+				PostLink:   "https://redd.it/a",
+				Links:      map[int]Media{0: {Link: "https://v.redd.it/a/DASH_720.mp4", Width: 1280, Height: 720}},
+				AudioIndex: -1,
+				Type:       reddit.FetchResultMediaTypeVideo,
+				Metadata:   reddit.PostMetadata{Subreddit: "a", Score: 1},
+			},
+			Decode: func(data []byte) (any, error) {
+				return decodeCacheValue[CallbackDataCached](cacheSchemaKindMedia, data)
+			},
+		},
+		{
+			TestName: "Album",
+			Value: CallbackAlbumCached{
+				PostLink: "https://redd.it/b",
+				Album:    reddit.FetchResultAlbum{Album: []reddit.FetchResultAlbumEntry{{Link: "https://i.redd.it/b.jpg", Caption: "b"}}},
+			},
+			Decode: func(data []byte) (any, error) {
+				return decodeCacheValue[CallbackAlbumCached](cacheSchemaKindAlbum, data)
+			},
+		},
+		{
+			TestName: "File ID",
+			Value:    FileIDCached{FileID: "abc"},
+			Decode:   func(data []byte) (any, error) { return decodeCacheValue[FileIDCached](cacheSchemaKindFileID, data) },
+		},
+		{
+			TestName: "Fetch Result",
+			Value:    reddit.CachedFetchResult{Text: &reddit.FetchResultText{Title: "title", Text: "text"}},
+			Decode: func(data []byte) (any, error) {
+				return decodeCacheValue[reddit.CachedFetchResult](cacheSchemaKindFetchResult, data)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			data, err := encodeCacheValue(test.Value)
+			assert.NoError(t, err)
+			envelope, err := parseCacheEnvelope(data)
+			assert.NoError(t, err)
+			assert.Equal(t, cacheSchemaVersion, envelope.Version)
+			decoded, err := test.Decode(data)
+			assert.NoError(t, err)
+			assert.Equal(t, test.Value, decoded)
+		})
+	}
+}
+
+func TestParseCacheEnvelope(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Data     string
+		Expected cacheEnvelope
+	}{
+		{
+			TestName: "Envelope",
+			Data:     `{"version":2,"data":{"FileID":"abc"}}`,
+			Expected: cacheEnvelope{Version: 2, Data: json.RawMessage(`{"FileID":"abc"}`)},
+		},
+		{
+			TestName: "Legacy",
+			Data:     `{"FileID":"abc"}`,
+			Expected: cacheEnvelope{Version: 0, Data: json.RawMessage(`{"FileID":"abc"}`)},
+		},
+		{
+			// json.Unmarshal would match these keys
+			TestName: "Legacy With Capitalized Keys",
+			Data:     `{"Version":2,"Data":"abc"}`,
+			Expected: cacheEnvelope{Version: 0, Data: json.RawMessage(`{"Version":2,"Data":"abc"}`)},
+		},
+		{
+			TestName: "Legacy With Extra Keys",
+			Data:     `{"version":2,"data":"abc","Title":"title"}`,
+			Expected: cacheEnvelope{Version: 0, Data: json.RawMessage(`{"version":2,"data":"abc","Title":"title"}`)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			envelope, err := parseCacheEnvelope([]byte(test.Data))
+			assert.NoError(t, err)
+			assert.Equal(t, test.Expected.Version, envelope.Version)
+			assert.JSONEq(t, string(test.Expected.Data), string(envelope.Data))
+		})
+	}
+}
+
+func TestDecodeCacheValue(t *testing.T) {
+	// A kind which only exists in this test, so its upgrades can be checked
+	const kind cacheSchemaKind = "test"
+	appendToFileID := func(suffix string) cacheSchemaUpgrade {
+		return func(data json.RawMessage) (json.RawMessage, error) {
+			var value FileIDCached
+			if err := json.Unmarshal(data, &value); err != nil {
+				return nil, err
+			}
+			value.FileID += suffix
+			return json.Marshal(value)
+		}
+	}
+	// Each upgrade appends its version, so the order of the upgrades is checked as well
+	cacheSchemaUpgrades[kind] = make(map[int]cacheSchemaUpgrade)
+	allUpgrades := ""
+	for version := 0; version < cacheSchemaVersion; version++ {
+		cacheSchemaUpgrades[kind][version] = appendToFileID(strconv.Itoa(version))
+		allUpgrades += strconv.Itoa(version)
+	}
+	defer delete(cacheSchemaUpgrades, kind)
+	previousVersion := strconv.Itoa(cacheSchemaVersion - 1)
+	tests := []struct {
+		TestName      string
+		Data          string
+		Expected      FileIDCached
+		ExpectedError error
+	}{
+		{
+			TestName: "Current Version",
+			Data:     `{"version":` + strconv.Itoa(cacheSchemaVersion) + `,"data":{"FileID":"abc"}}`,
+			Expected: FileIDCached{FileID: "abc"},
+		},
+		{
+			TestName: "Previous Version",
+			Data:     `{"version":` + previousVersion + `,"data":{"FileID":"abc"}}`,
+			Expected: FileIDCached{FileID: "abc" + previousVersion},
+		},
+		{
+			// Every upgrade is applied in order
+			TestName: "Legacy",
+			Data:     `{"FileID":"abc"}`,
+			Expected: FileIDCached{FileID: "abc" + allUpgrades},
+		},
+		{
+			TestName:      "Newer Version",
+			Data:          `{"version":` + strconv.Itoa(cacheSchemaVersion+1) + `,"data":{"FileID":"abc"}}`,
+			ExpectedError: UnknownSchemaVersionErr,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			value, err := decodeCacheValue[FileIDCached](kind, []byte(test.Data))
+			if test.ExpectedError != nil {
+				assert.ErrorIs(t, err, test.ExpectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.Expected, value)
+		})
+	}
+	// The kinds without upgrades are decoded as they are
+	value, err := decodeCacheValue[FileIDCached](cacheSchemaKindFileID, []byte(`{"FileID":"abc"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, FileIDCached{FileID: "abc"}, value)
+	// Failed upgrades and broken values
+	cacheSchemaUpgrades[kind][0] = func(json.RawMessage) (json.RawMessage, error) {
+		return nil, errors.New("broken upgrade")
+	}
+	_, err = decodeCacheValue[FileIDCached](kind, []byte(`{"FileID":"abc"}`))
+	assert.ErrorContains(t, err, "broken upgrade")
+	_, err = decodeCacheValue[FileIDCached](kind, []byte(`not json`))
+	assert.Error(t, err)
+	_, err = decodeCacheValue[FileIDCached](kind, []byte(`{"version":"1","data":{}}`))
+	assert.Error(t, err)
+}
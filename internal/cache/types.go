@@ -23,6 +23,10 @@ type CallbackDataCached struct {
 	Duration int64
 	// What media is this
 	Type reddit.FetchResultMediaType
+	// The metadata of the post which is used in the captions
+	Metadata reddit.PostMetadata
+	// Should the media be hidden behind a spoiler (the post is NSFW or a spoiler)
+	Spoiler bool
 }
 
 // Media holds the information for a media in reddit
@@ -42,3 +46,20 @@ type CallbackAlbumCached struct {
 	// The album data
 	Album reddit.FetchResultAlbum
 }
+
+// FileIDCached is a media which is already uploaded to Telegram and can be sent again
+// without downloading and uploading it.
+type FileIDCached struct {
+	// The file_id which Telegram has given us for the uploaded file
+	FileID string
+}
+
+// Stats holds the counters of a cache since it was created
+type Stats struct {
+	// Number of lookups which have found their key
+	Hits uint64
+	// Number of lookups which have not found their key
+	Misses uint64
+	// Number of entries which were deleted to make room for new entries
+	Evictions uint64
+}
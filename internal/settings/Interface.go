@@ -0,0 +1,14 @@
+package settings
+
+// Store provides the interface to persist the settings of each user
+type Store interface {
+	// Get returns the settings of a user. If the user has never changed any setting,
+	// DefaultUser is returned without any error.
+	Get(userID int64) (User, error)
+	// Exists checks if any setting of a user is stored
+	Exists(userID int64) (bool, error)
+	// Update will atomically load the settings of a user, call update on them and save the result
+	Update(userID int64, update func(*User)) error
+	// Close must close the underlying database connection
+	Close() error
+}
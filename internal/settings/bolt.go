@@ -0,0 +1,96 @@
+package settings
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-faster/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var _ Store = BoltStore{}
+
+// The bucket which all users are stored in
+var boltUsersBucket = []byte("users")
+
+// BoltStore satisfies Store backed by an embedded bbolt database file.
+// Each user is stored as a json object keyed by its ID.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore will open (or create) the database file at path
+func NewBoltStore(path string) (BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return BoltStore{}, errors.Wrap(err, "cannot open the database")
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltUsersBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return BoltStore{}, errors.Wrap(err, "cannot create the users bucket")
+	}
+	return BoltStore{db: db}, nil
+}
+
+func (s BoltStore) Get(userID int64) (User, error) {
+	var user User
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		user, err = decodeBoltUser(tx.Bucket(boltUsersBucket).Get(boltUserKey(userID)))
+		return err
+	})
+	return user, err
+}
+
+func (s BoltStore) Exists(userID int64) (bool, error) {
+	var exists bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(boltUsersBucket).Get(boltUserKey(userID)) != nil
+		return nil
+	})
+	return exists, err
+}
+
+func (s BoltStore) Update(userID int64, update func(*User)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltUsersBucket)
+		key := boltUserKey(userID)
+		user, err := decodeBoltUser(bucket.Get(key))
+		if err != nil {
+			return err
+		}
+		update(&user)
+		data, err := json.Marshal(user)
+		if err != nil {
+			return errors.Wrap(err, "cannot encode user")
+		}
+		return bucket.Put(key, data)
+	})
+}
+
+func (s BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// boltUserKey converts a user ID to the key which it's stored in database
+func boltUserKey(userID int64) []byte {
+	return strconv.AppendInt(nil, userID, 10)
+}
+
+// decodeBoltUser decodes the stored value of a user. Missing fields (or a missing user)
+// are filled from DefaultUser.
+func decodeBoltUser(data []byte) (User, error) {
+	user := DefaultUser()
+	if data == nil {
+		return user, nil
+	}
+	if err := json.Unmarshal(data, &user); err != nil {
+		return DefaultUser(), errors.Wrap(err, "cannot decode user")
+	}
+	return user, nil
+}
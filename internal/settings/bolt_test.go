@@ -0,0 +1,88 @@
+package settings
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+// newTestBoltStore opens the BoltStore of path. The test fails if it cannot be opened.
+func newTestBoltStore(t *testing.T, path string) BoltStore {
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestBoltStore(t *testing.T) {
+	store := newTestBoltStore(t, filepath.Join(t.TempDir(), "settings.db"))
+	defer store.Close()
+	testStore(t, store, 1)
+}
+
+func TestBoltStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.db")
+	store := newTestBoltStore(t, path)
+	assert.NoError(t, store.Update(1, func(user *User) { user.Lang = LangRU }))
+	assert.NoError(t, store.Close())
+	store = newTestBoltStore(t, path)
+	defer store.Close()
+	user, err := store.Get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, LangRU, user.Lang)
+	exists, err := store.Exists(1)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestDecodeBoltUser(t *testing.T) {
+	expected := DefaultUser()
+	expected.Lang = LangRU
+	tests := []struct {
+		TestName      string
+		Data          []byte
+		Expected      User
+		ExpectedError bool
+	}{
+		{
+			TestName: "Missing User",
+			Data:     nil,
+			Expected: DefaultUser(),
+		},
+		{
+			// The fields which are added later are missing in the older records
+			TestName: "Missing Fields",
+			Data:     []byte(`{"lang":"ru"}`),
+			Expected: expected,
+		},
+		{
+			TestName:      "Broken",
+			Data:          []byte(`{"lang":`),
+			Expected:      DefaultUser(),
+			ExpectedError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			user, err := decodeBoltUser(test.Data)
+			if test.ExpectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.Expected, user)
+		})
+	}
+	// The broken records are reported by the store
+	store := newTestBoltStore(t, filepath.Join(t.TempDir(), "settings.db"))
+	defer store.Close()
+	assert.NoError(t, store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltUsersBucket).Put(boltUserKey(1), []byte(`{"lang":`))
+	}))
+	_, err := store.Get(1)
+	assert.Error(t, err)
+	assert.Error(t, store.Update(1, func(*User) {}))
+}
@@ -0,0 +1,52 @@
+package settings
+
+import "sync"
+
+var _ Store = &MemoryStore{}
+
+// MemoryStore keeps the settings of users in RAM. Everything is lost when the bot restarts.
+type MemoryStore struct {
+	users map[int64]User
+	lock  sync.RWMutex
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users: make(map[int64]User),
+	}
+}
+
+func (s *MemoryStore) Get(userID int64) (User, error) {
+	s.lock.RLock()
+	user, ok := s.users[userID]
+	s.lock.RUnlock()
+	if !ok {
+		return DefaultUser(), nil
+	}
+	return user, nil
+}
+
+func (s *MemoryStore) Exists(userID int64) (bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	_, ok := s.users[userID]
+	return ok, nil
+}
+
+func (s *MemoryStore) Update(userID int64, update func(*User)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	user, ok := s.users[userID]
+	if !ok {
+		user = DefaultUser()
+	}
+	update(&user)
+	s.users[userID] = user
+	return nil
+}
+
+// Close does nothing
+func (s *MemoryStore) Close() error {
+	return nil
+}
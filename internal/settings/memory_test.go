@@ -0,0 +1,30 @@
+package settings
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore(), 1)
+}
+
+func TestMemoryStoreConcurrentUpdates(t *testing.T) {
+	store := NewMemoryStore()
+	var wg sync.WaitGroup
+	for i := int64(0); i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, store.Update(i, func(user *User) { user.Lang = LangRU }))
+		}()
+	}
+	wg.Wait()
+	for i := int64(0); i < 100; i++ {
+		user, err := store.Get(i)
+		assert.NoError(t, err)
+		assert.Equal(t, LangRU, user.Lang)
+	}
+}
@@ -0,0 +1,145 @@
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/go-faster/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ Store = RedisStore{}
+
+// redisSettingsPrefix is the prefix of the hash which holds the settings of a user
+const redisSettingsPrefix = "settings:"
+
+// redisUpdateRetries is the number of times which an update is retried if another
+// instance of the bot changes the same user at the same time
+const redisUpdateRetries = 5
+
+// RedisStore satisfies Store backed by a Redis server.
+// Each user is stored as a hash. Each field of User is a field in this hash (named by its json
+// tag) and holds the json value of that field. So, different bot instances can change different
+// fields of a user without overwriting each other.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a new RedisStore from a redis client. The client can be shared with
+// other parts of the program; It's not closed by RedisStore.Close.
+func NewRedisStore(client *redis.Client) RedisStore {
+	return RedisStore{client: client}
+}
+
+func (r RedisStore) Get(userID int64) (User, error) {
+	fields, err := r.client.HGetAll(context.Background(), redisUserKey(userID)).Result()
+	if err != nil {
+		return DefaultUser(), errors.Wrap(err, "Unable to fetch data from Redis")
+	}
+	return decodeRedisUser(fields)
+}
+
+func (r RedisStore) Exists(userID int64) (bool, error) {
+	count, err := r.client.Exists(context.Background(), redisUserKey(userID)).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "Unable to fetch data from Redis")
+	}
+	return count > 0, nil
+}
+
+// Update applies the update in a transaction which is retried if another client changes the
+// user in between. Only the fields which update has changed are written.
+func (r RedisStore) Update(userID int64, update func(*User)) error {
+	ctx := context.Background()
+	key := redisUserKey(userID)
+	txf := func(tx *redis.Tx) error {
+		fields, err := tx.HGetAll(ctx, key).Result()
+		if err != nil {
+			return errors.Wrap(err, "Unable to fetch data from Redis")
+		}
+		user, err := decodeRedisUser(fields)
+		if err != nil {
+			return err
+		}
+		oldFields, err := encodeRedisUser(user)
+		if err != nil {
+			return err
+		}
+		update(&user)
+		newFields, err := encodeRedisUser(user)
+		if err != nil {
+			return err
+		}
+		// Only write the changed fields
+		changed := make([]interface{}, 0, 2*len(newFields))
+		for field, value := range newFields {
+			if oldFields[field] != value {
+				changed = append(changed, field, value)
+			}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, changed...)
+			return nil
+		})
+		return err
+	}
+	for i := 0; i < redisUpdateRetries; i++ {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue // someone else changed the user; Try again
+		}
+		return err
+	}
+	return errors.New("too many concurrent updates")
+}
+
+// Close does nothing because the client might be shared. Whoever has created the client must close it.
+func (r RedisStore) Close() error {
+	return nil
+}
+
+// redisUserKey gets the key of hash which holds the settings of a user
+func redisUserKey(userID int64) string {
+	return redisSettingsPrefix + strconv.FormatInt(userID, 10)
+}
+
+// encodeRedisUser converts a User to the fields of its hash
+func encodeRedisUser(user User) (map[string]string, error) {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot encode user")
+	}
+	var raw map[string]json.RawMessage
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrap(err, "cannot encode user")
+	}
+	fields := make(map[string]string, len(raw))
+	for field, value := range raw {
+		fields[field] = string(value)
+	}
+	return fields, nil
+}
+
+// decodeRedisUser converts the fields of a hash to User. Missing fields are filled from DefaultUser.
+func decodeRedisUser(fields map[string]string) (User, error) {
+	user := DefaultUser()
+	if len(fields) == 0 {
+		return user, nil
+	}
+	raw := make(map[string]json.RawMessage, len(fields))
+	for field, value := range fields {
+		raw[field] = json.RawMessage(value)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return DefaultUser(), errors.Wrap(err, "cannot decode user")
+	}
+	if err = json.Unmarshal(data, &user); err != nil {
+		return DefaultUser(), errors.Wrap(err, "cannot decode user")
+	}
+	return user, nil
+}
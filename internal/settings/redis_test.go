@@ -0,0 +1,89 @@
+package settings
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRedisStore creates a RedisStore which is connected to the server of REDIS_ADDRESS and
+// REDIS_PORT. The test is skipped if they are not set. The settings of the given users are
+// deleted before and after the test.
+func newTestRedisStore(t *testing.T, userIDs ...int64) RedisStore {
+	address, port := os.Getenv("REDIS_ADDRESS"), os.Getenv("REDIS_PORT")
+	if address == "" || port == "" {
+		t.Skip("REDIS_ADDRESS and REDIS_PORT are not set")
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     net.JoinHostPort(address, port),
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		t.Skip("cannot connect to Redis:", err)
+	}
+	keys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		keys[i] = redisUserKey(userID)
+	}
+	client.Del(ctx, keys...)
+	t.Cleanup(func() {
+		client.Del(context.Background(), keys...)
+		_ = client.Close()
+	})
+	return NewRedisStore(client)
+}
+
+func TestRedisStore(t *testing.T) {
+	// Negative IDs are groups, and no group has such a big ID
+	const userID = -1_000_000_000_001
+	store := newTestRedisStore(t, userID, userID+1)
+	testStore(t, store, userID)
+}
+
+func TestRedisStoreChangedFields(t *testing.T) {
+	const userID = -1_000_000_000_001
+	store := newTestRedisStore(t, userID)
+	assert.NoError(t, store.Update(userID, func(user *User) { user.Lang = LangRU }))
+	// Only the changed field is written, so another instance which has changed the language in
+	// between does not lose its change
+	fields, err := store.client.HGetAll(context.Background(), redisUserKey(userID)).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"lang": `"ru"`}, fields)
+	assert.NoError(t, store.Update(userID, func(user *User) { user.DownloadMode = DownloadModeFiles }))
+	fields, err = store.client.HGetAll(context.Background(), redisUserKey(userID)).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"lang": `"ru"`, "mode": "2"}, fields)
+}
+
+func TestRedisUserFields(t *testing.T) {
+	user := DefaultUser()
+	user.Lang = LangRU
+	user.Quality = QualityHigh
+	fields, err := encodeRedisUser(user)
+	assert.NoError(t, err)
+	assert.Equal(t, `"ru"`, fields["lang"])
+	assert.Equal(t, "2", fields["quality"])
+	assert.Equal(t, "true", fields["link"])
+	decoded, err := decodeRedisUser(fields)
+	assert.NoError(t, err)
+	assert.Equal(t, user, decoded)
+	// Missing fields are filled from the defaults
+	decoded, err = decodeRedisUser(map[string]string{"lang": `"ru"`})
+	assert.NoError(t, err)
+	expected := DefaultUser()
+	expected.Lang = LangRU
+	assert.Equal(t, expected, decoded)
+	decoded, err = decodeRedisUser(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultUser(), decoded)
+	_, err = decodeRedisUser(map[string]string{"lang": "ru"})
+	assert.Error(t, err)
+}
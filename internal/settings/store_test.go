@@ -0,0 +1,49 @@
+package settings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testStore checks the behaviour which every Store must have.
+// userID must not be stored in the store before the test.
+func testStore(t *testing.T, store Store, userID int64) {
+	// Unknown users get the defaults
+	exists, err := store.Exists(userID)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	user, err := store.Get(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultUser(), user)
+	// Updates are saved
+	assert.NoError(t, store.Update(userID, func(user *User) {
+		user.Lang = LangRU
+		user.Quality = QualityHigh
+	}))
+	exists, err = store.Exists(userID)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	expected := DefaultUser()
+	expected.Lang = LangRU
+	expected.Quality = QualityHigh
+	user, err = store.Get(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, user)
+	// Update gets the stored settings, and the other fields are kept
+	assert.NoError(t, store.Update(userID, func(user *User) {
+		assert.Equal(t, expected, *user)
+		user.DownloadMode = DownloadModeFiles
+	}))
+	expected.DownloadMode = DownloadModeFiles
+	user, err = store.Get(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, user)
+	// Other users are not changed
+	exists, err = store.Exists(userID + 1)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	user, err = store.Get(userID + 1)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultUser(), user)
+}
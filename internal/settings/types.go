@@ -0,0 +1,217 @@
+package settings
+
+import "strings"
+
+// Lang is the language which the bot talks to a user in
+type Lang string
+
+const (
+	LangEN Lang = "en"
+	LangRU Lang = "ru"
+)
+
+// DownloadMode says how albums should be sent to a user
+type DownloadMode int
+
+const (
+	DownloadModeAsk DownloadMode = iota
+	DownloadModeMedia
+	DownloadModeFiles
+)
+
+func (m DownloadMode) String() string {
+	switch m {
+	case DownloadModeMedia:
+		return "media"
+	case DownloadModeFiles:
+		return "files"
+	default:
+		return "ask"
+	}
+}
+
+// ParseDownloadMode is the inverse of DownloadMode.String. Unknown values are parsed as DownloadModeAsk
+func ParseDownloadMode(s string) DownloadMode {
+	switch strings.ToLower(s) {
+	case "media":
+		return DownloadModeMedia
+	case "files":
+		return DownloadModeFiles
+	default:
+		return DownloadModeAsk
+	}
+}
+
+// MediaQuality is the quality which is automatically selected for the user
+type MediaQuality int
+
+const (
+	QualityAsk MediaQuality = iota
+	QualityOriginal
+	QualityHigh
+	QualityLow
+)
+
+func (q MediaQuality) String() string {
+	switch q {
+	case QualityOriginal:
+		return "original"
+	case QualityHigh:
+		return "high"
+	case QualityLow:
+		return "low"
+	default:
+		return "ask"
+	}
+}
+
+// ParseMediaQuality is the inverse of MediaQuality.String. Unknown values are parsed as QualityAsk
+func ParseMediaQuality(s string) MediaQuality {
+	switch strings.ToLower(s) {
+	case "original":
+		return QualityOriginal
+	case "high":
+		return QualityHigh
+	case "low":
+		return QualityLow
+	default:
+		return QualityAsk
+	}
+}
+
+// PollMode says how Reddit polls are sent to a user
+type PollMode int
+
+const (
+	// PollModeNative sends polls as Telegram polls
+	PollModeNative PollMode = iota
+	// PollModeText sends polls as a text with the votes of each option
+	PollModeText
+)
+
+func (m PollMode) String() string {
+	switch m {
+	case PollModeText:
+		return "text"
+	default:
+		return "native"
+	}
+}
+
+// ParsePollMode is the inverse of PollMode.String. Unknown values are parsed as PollModeNative
+func ParsePollMode(s string) PollMode {
+	switch strings.ToLower(s) {
+	case "text":
+		return PollModeText
+	default:
+		return PollModeNative
+	}
+}
+
+// LongTextMode says how texts which do not fit in a message are sent to a user
+type LongTextMode int
+
+const (
+	// LongTextModeSplit splits the text into several messages
+	LongTextModeSplit LongTextMode = iota
+	// LongTextModeTelegraph publishes the text as a Telegraph page
+	LongTextModeTelegraph
+)
+
+func (m LongTextMode) String() string {
+	switch m {
+	case LongTextModeTelegraph:
+		return "telegraph"
+	default:
+		return "split"
+	}
+}
+
+// ParseLongTextMode is the inverse of LongTextMode.String. Unknown values are parsed as LongTextModeSplit
+func ParseLongTextMode(s string) LongTextMode {
+	switch strings.ToLower(s) {
+	case "telegraph":
+		return LongTextModeTelegraph
+	default:
+		return LongTextModeSplit
+	}
+}
+
+// CommentsMode says if and how the top comments of posts are sent to a user
+type CommentsMode int
+
+const (
+	// CommentsModeOff does not send the comments
+	CommentsModeOff CommentsMode = iota
+	// CommentsModeMessage sends the comments as messages after the post
+	CommentsModeMessage
+	// CommentsModeDocument sends the comments as a text file after the post
+	CommentsModeDocument
+)
+
+func (m CommentsMode) String() string {
+	switch m {
+	case CommentsModeMessage:
+		return "message"
+	case CommentsModeDocument:
+		return "document"
+	default:
+		return "off"
+	}
+}
+
+// ParseCommentsMode is the inverse of CommentsMode.String. Unknown values are parsed as CommentsModeOff
+func ParseCommentsMode(s string) CommentsMode {
+	switch strings.ToLower(s) {
+	case "message":
+		return CommentsModeMessage
+	case "document":
+		return CommentsModeDocument
+	default:
+		return CommentsModeOff
+	}
+}
+
+// User is the settings record of a single user.
+// The json tags are the field names in the persistent stores, so they should never be changed.
+type User struct {
+	// The language of the bot
+	Lang Lang `json:"lang"`
+	// How albums are sent
+	DownloadMode DownloadMode `json:"mode"`
+	// Which quality should be automatically chosen
+	Quality MediaQuality `json:"quality"`
+	// Should we attach the original reddit link in captions/texts
+	AttachLink bool `json:"link"`
+	// How polls are sent
+	PollMode PollMode `json:"poll"`
+	// How texts which do not fit in a message are sent
+	LongTextMode LongTextMode `json:"long_text"`
+	// How the comments of posts are sent
+	CommentsMode CommentsMode `json:"comments"`
+	// The number of comments in each level of the comments
+	CommentsCount int `json:"comments_count"`
+	// The number of levels of the comments. 1 means only the top level comments.
+	CommentsDepth int `json:"comments_depth"`
+	// The number of parents which are sent with a linked comment. 0 means only the comment.
+	CommentContext int `json:"comment_context"`
+	// The template of the captions of media. Empty means the default template of the bot.
+	// The records of groups use this for the template of the group.
+	CaptionTemplate string `json:"caption_template"`
+}
+
+// DefaultUser returns the settings of a user which has never changed anything
+func DefaultUser() User {
+	return User{
+		Lang:           LangEN,
+		DownloadMode:   DownloadModeAsk,
+		Quality:        QualityAsk,
+		AttachLink:     true, // keep the old behavior and attach
+		PollMode:       PollModeNative,
+		LongTextMode:   LongTextModeSplit,
+		CommentsMode:   CommentsModeOff,
+		CommentsCount:  5,
+		CommentsDepth:  1,
+		CommentContext: 0,
+	}
+}
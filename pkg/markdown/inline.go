@@ -0,0 +1,394 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// redditLinkRegex matches the subreddit and user mentions which Reddit converts to links
+var redditLinkRegex = regexp.MustCompile(`^/?([ru])/[A-Za-z0-9_][A-Za-z0-9_-]{1,20}`)
+
+// The characters which Reddit lets to be escaped with a backslash
+const escapableCharacters = "\\`*_{}[]()#+-.!|~^<>&\"'"
+
+// superscriptCharacters maps the characters to their Unicode superscript. Telegram does not
+// support superscript, so these are used instead.
+var superscriptCharacters = map[rune]rune{
+	'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶', '7': '⁷', '8': '⁸', '9': '⁹',
+	'+': '⁺', '-': '⁻', '=': '⁼', '(': '⁽', ')': '⁾',
+	'a': 'ᵃ', 'b': 'ᵇ', 'c': 'ᶜ', 'd': 'ᵈ', 'e': 'ᵉ', 'f': 'ᶠ', 'g': 'ᵍ', 'h': 'ʰ', 'i': 'ⁱ', 'j': 'ʲ',
+	'k': 'ᵏ', 'l': 'ˡ', 'm': 'ᵐ', 'n': 'ⁿ', 'o': 'ᵒ', 'p': 'ᵖ', 'r': 'ʳ', 's': 'ˢ', 't': 'ᵗ', 'u': 'ᵘ',
+	'v': 'ᵛ', 'w': 'ʷ', 'x': 'ˣ', 'y': 'ʸ', 'z': 'ᶻ',
+	'A': 'ᴬ', 'B': 'ᴮ', 'D': 'ᴰ', 'E': 'ᴱ', 'G': 'ᴳ', 'H': 'ᴴ', 'I': 'ᴵ', 'J': 'ᴶ', 'K': 'ᴷ', 'L': 'ᴸ',
+	'M': 'ᴹ', 'N': 'ᴺ', 'O': 'ᴼ', 'P': 'ᴾ', 'R': 'ᴿ', 'T': 'ᵀ', 'U': 'ᵁ', 'V': 'ⱽ', 'W': 'ᵂ',
+}
+
+// inlineRenderer converts the inline markdown (like emphasis, links and code spans) of a text.
+// If plain is true, the formatting is removed instead of being converted to HTML. If inLink is
+// true, the mentions are not converted to links because links cannot be nested.
+type inlineRenderer struct {
+	plain  bool
+	inLink bool
+	sb     strings.Builder
+}
+
+// renderInline converts the inline markdown of a text to Telegram HTML
+func renderInline(text string) string {
+	r := &inlineRenderer{}
+	r.render([]rune(text))
+	return r.sb.String()
+}
+
+// renderPlain removes the inline markdown of a text. The result is not escaped.
+func renderPlain(text string) string {
+	r := &inlineRenderer{plain: true}
+	r.render([]rune(text))
+	return r.sb.String()
+}
+
+// sub renders a part of the text with the same mode and returns it
+func (r *inlineRenderer) sub(s []rune) string {
+	inner := &inlineRenderer{plain: r.plain, inLink: r.inLink}
+	inner.render(s)
+	return inner.sb.String()
+}
+
+// text writes a text which has no markdown
+func (r *inlineRenderer) text(text string) {
+	if r.plain {
+		r.sb.WriteString(text)
+	} else {
+		r.sb.WriteString(html.EscapeString(text))
+	}
+}
+
+// wrap surrounds a formatted content with a tag. The tag is not added in the plain mode.
+func (r *inlineRenderer) wrap(tag, content string) string {
+	if r.plain {
+		return content
+	}
+	return "<" + tag + ">" + content + "</" + tag + ">"
+}
+
+func (r *inlineRenderer) render(s []rune) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.ContainsRune(escapableCharacters, s[i+1]):
+			r.text(string(s[i+1]))
+			i += 2
+		case c == '`':
+			i = r.codeSpan(s, i)
+		case c == '>' && i+1 < len(s) && s[i+1] == '!':
+			i = r.delimited(s, i, ">!", "!<", "tg-spoiler")
+		case c == '~' && i+1 < len(s) && s[i+1] == '~':
+			i = r.delimited(s, i, "~~", "~~", "s")
+		case c == '*' || c == '_':
+			i = r.emphasis(s, i)
+		case c == '[':
+			i = r.link(s, i)
+		case c == '<':
+			i = r.autoLink(s, i)
+		case c == '^':
+			i = r.superscript(s, i)
+		case !r.inLink && (c == 'r' || c == 'u' || c == '/') && (i == 0 || !isWordCharacter(s[i-1]) && s[i-1] != '/'):
+			i = r.redditLink(s, i)
+		default:
+			r.text(string(c))
+			i++
+		}
+	}
+}
+
+// skip returns the index after an escaped character or a code span at i. Returns i if there is
+// none of them at i. Delimiters inside them must not be matched.
+func skip(s []rune, i int) int {
+	if s[i] == '\\' && i+1 < len(s) {
+		return i + 2
+	}
+	if s[i] == '`' {
+		run := runLength(s, i)
+		if end := findBacktickRun(s, i+run, run); end != -1 {
+			return end + run
+		}
+		return i + run
+	}
+	return i
+}
+
+// runLength counts how many times s[i] is repeated from i
+func runLength(s []rune, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// findBacktickRun finds a run of exactly n backticks from start. Returns -1 if there is none.
+func findBacktickRun(s []rune, start, n int) int {
+	for j := start; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		run := runLength(s, j)
+		if run == n {
+			return j
+		}
+		j += run
+	}
+	return -1
+}
+
+// codeSpan writes the code span which starts at i and returns the index after it
+func (r *inlineRenderer) codeSpan(s []rune, i int) int {
+	run := runLength(s, i)
+	end := findBacktickRun(s, i+run, run)
+	if end == -1 {
+		r.text(string(s[i : i+run]))
+		return i + run
+	}
+	code := string(s[i+run : end])
+	if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+		code = code[1 : len(code)-1]
+	}
+	if r.plain {
+		r.sb.WriteString(code)
+	} else {
+		r.sb.WriteString("<code>" + html.EscapeString(code) + "</code>")
+	}
+	return end + run
+}
+
+// hasPrefixAt checks if s has the prefix at i
+func hasPrefixAt(s []rune, i int, prefix string) bool {
+	for _, c := range prefix {
+		if i >= len(s) || s[i] != c {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// delimited writes a text which is between the opener and the closer (like a spoiler) and returns
+// the index after it. The opener is written as text if the closer is not found.
+func (r *inlineRenderer) delimited(s []rune, i int, opener, closer, tag string) int {
+	start := i + len([]rune(opener))
+	if start < len(s) && !unicode.IsSpace(s[start]) {
+		for j := start; j < len(s); {
+			if next := skip(s, j); next != j {
+				j = next
+				continue
+			}
+			if j > start && hasPrefixAt(s, j, closer) && !unicode.IsSpace(s[j-1]) {
+				r.sb.WriteString(r.wrap(tag, r.sub(s[start:j])))
+				return j + len([]rune(closer))
+			}
+			j++
+		}
+	}
+	r.text(opener)
+	return start
+}
+
+// emphasis writes an italic or bold text which starts with a run of * or _ at i and returns the
+// index after it. Like Reddit, _ does not make emphasis inside a word.
+func (r *inlineRenderer) emphasis(s []rune, i int) int {
+	run := runLength(s, i)
+	after := i + run
+	canOpen := after < len(s) && !unicode.IsSpace(s[after])
+	if s[i] == '_' && i > 0 && isWordCharacter(s[i-1]) {
+		canOpen = false
+	}
+	if canOpen {
+		// A run of three is either both bold and italic or an italic which starts with a bold text
+		lengths := []int{run}
+		if run >= 3 {
+			lengths = []int{3, 1, 2}
+		}
+		for _, length := range lengths {
+			end, closerStart := findEmphasisCloser(s, i+length, s[i], length)
+			if end == -1 {
+				continue
+			}
+			content := r.sub(s[i+length : closerStart])
+			switch length {
+			case 1:
+				r.sb.WriteString(r.wrap("i", content))
+			case 2:
+				r.sb.WriteString(r.wrap("b", content))
+			case 3:
+				r.sb.WriteString(r.wrap("b", r.wrap("i", content)))
+			}
+			return end
+		}
+	}
+	r.text(string(s[i:after]))
+	return after
+}
+
+// findEmphasisCloser finds the closer of an emphasis with the delimiter and length from start.
+// Returns the index after the closer and the index which the closer starts at. Both are -1 if
+// there is no closer. Shorter and longer runs which do not close the emphasis are skipped because
+// they belong to the emphasis inside this one.
+func findEmphasisCloser(s []rune, start int, delimiter rune, length int) (int, int) {
+	for j := start; j < len(s); {
+		if next := skip(s, j); next != j {
+			j = next
+			continue
+		}
+		if s[j] != delimiter {
+			j++
+			continue
+		}
+		run := runLength(s, j)
+		canClose := j > start && !unicode.IsSpace(s[j-1])
+		if canClose && delimiter == '_' && j+run < len(s) && isWordCharacter(s[j+run]) {
+			canClose = false
+		}
+		if canClose && (run == length || run == 3 && length < 3) {
+			return j + run, j + run - length
+		}
+		j += run
+	}
+	return -1, -1
+}
+
+// link writes a link like [text](url) which starts at i and returns the index after it
+func (r *inlineRenderer) link(s []rune, i int) int {
+	textEnd := findClosingBracket(s, i, '[', ']')
+	if textEnd == -1 || textEnd+1 >= len(s) || s[textEnd+1] != '(' {
+		r.text("[")
+		return i + 1
+	}
+	urlEnd := findClosingBracket(s, textEnd+1, '(', ')')
+	if urlEnd == -1 {
+		r.text("[")
+		return i + 1
+	}
+	linkText := &inlineRenderer{plain: r.plain, inLink: true}
+	linkText.render(s[i+1 : textEnd])
+	text := linkText.sb.String()
+	url := strings.TrimSpace(string(s[textEnd+2 : urlEnd]))
+	// Remove the title
+	if index := strings.IndexAny(url, " \t"); index != -1 {
+		url = url[:index]
+	}
+	url = normalizeURL(strings.TrimSuffix(strings.TrimPrefix(url, "<"), ">"))
+	switch {
+	case url == "":
+		r.sb.WriteString(text)
+	case r.plain:
+		r.sb.WriteString(text)
+	default:
+		r.sb.WriteString(`<a href="` + html.EscapeString(url) + `">` + text + "</a>")
+	}
+	return urlEnd + 1
+}
+
+// findClosingBracket finds the bracket which closes the one at i. Nested brackets are skipped.
+// Returns -1 if the bracket is not closed.
+func findClosingBracket(s []rune, i int, open, close rune) int {
+	depth := 0
+	for j := i; j < len(s); {
+		if next := skip(s, j); next != j {
+			j = next
+			continue
+		}
+		switch s[j] {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+		j++
+	}
+	return -1
+}
+
+// normalizeURL makes the relative links of Reddit absolute
+func normalizeURL(url string) string {
+	if strings.HasPrefix(url, "/") {
+		return "https://www.reddit.com" + url
+	}
+	return url
+}
+
+// autoLink writes a link like <https://example.com> which starts at i and returns the index after it.
+// Telegram shows the URLs as links, so only the brackets are removed.
+func (r *inlineRenderer) autoLink(s []rune, i int) int {
+	end := -1
+	for j := i + 1; j < len(s) && !unicode.IsSpace(s[j]); j++ {
+		if s[j] == '>' {
+			end = j
+			break
+		}
+	}
+	if end == -1 || !strings.Contains(string(s[i+1:end]), "://") {
+		r.text("<")
+		return i + 1
+	}
+	r.text(string(s[i+1 : end]))
+	return end + 1
+}
+
+// superscript writes a superscript like ^word or ^(some words) which starts at i and returns the
+// index after it. The characters which have a Unicode superscript are replaced with it.
+func (r *inlineRenderer) superscript(s []rune, i int) int {
+	var content []rune
+	end := i + 1
+	if end < len(s) && s[end] == '(' {
+		closing := findClosingBracket(s, end, '(', ')')
+		if closing != -1 {
+			content = s[end+1 : closing]
+			end = closing + 1
+		}
+	}
+	if content == nil {
+		for end < len(s) && !unicode.IsSpace(s[end]) && s[end] != '^' {
+			end++
+		}
+		content = s[i+1 : end]
+	}
+	if len(content) == 0 {
+		r.text("^")
+		return i + 1
+	}
+	raised := []rune(renderPlain(string(content)))
+	for j, c := range raised {
+		if superscript, exists := superscriptCharacters[c]; exists {
+			raised[j] = superscript
+		}
+	}
+	r.text(string(raised))
+	return end
+}
+
+// redditLink writes the mentions of subreddits and users like r/golang and /u/spez as links and
+// returns the index after them
+func (r *inlineRenderer) redditLink(s []rune, i int) int {
+	match := redditLinkRegex.FindString(string(s[i:min(len(s), i+24)]))
+	end := i + len([]rune(match))
+	if match == "" || end < len(s) && (isWordCharacter(s[end]) || s[end] == '/') {
+		r.text(string(s[i]))
+		return i + 1
+	}
+	if r.plain {
+		r.sb.WriteString(match)
+	} else {
+		r.sb.WriteString(`<a href="https://www.reddit.com/` + strings.TrimPrefix(match, "/") + `">` + match + "</a>")
+	}
+	return end
+}
+
+// isWordCharacter checks if a character is a letter, a digit or an underscore
+func isWordCharacter(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
@@ -0,0 +1,423 @@
+// Package markdown converts the markdown which Reddit uses to the HTML which Telegram supports.
+// See https://core.telegram.org/bots/api#html-style for the supported tags.
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	headerRegex        = regexp.MustCompile(`^ {0,3}(#{1,6})\s*(.*?)\s*#*\s*$`)
+	ruleRegex          = regexp.MustCompile(`^ {0,3}([-*_])(?:\s*([-*_])){2,}\s*$`)
+	fenceRegex         = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`\\s]*)")
+	listItemRegex      = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])(?:\s+(.*)|$)`)
+	tableDelimiterCell = regexp.MustCompile(`^\s*:?-+:?\s*$`)
+)
+
+// The text which a horizontal rule is converted to
+const horizontalRule = "——————————"
+
+// The markers of the unordered lists in each level of nesting
+var bulletMarkers = []string{"•", "◦", "▪"}
+
+// ToHTML converts a Reddit markdown text to Telegram HTML
+func ToHTML(text string) string {
+	return strings.Join(Blocks(text), "\n\n")
+}
+
+// Blocks converts a Reddit markdown text to Telegram HTML and returns each top level block (like
+// a paragraph, a list or a table) separately. Each block has balanced tags, so it can be sent
+// in its own message.
+func Blocks(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return parseBlocks(strings.Split(text, "\n"), false)
+}
+
+// parseBlocks converts the lines to blocks. If inQuote is true, the quotes are not nested because
+// Telegram does not allow nested blockquotes.
+func parseBlocks(lines []string, inQuote bool) []string {
+	var blocks []string
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+		case fenceRegex.MatchString(line):
+			var block string
+			block, i = parseFencedCode(lines, i)
+			blocks = append(blocks, block)
+		case isIndentedCode(line):
+			var block string
+			block, i = parseIndentedCode(lines, i)
+			blocks = append(blocks, block)
+		case headerRegex.MatchString(line):
+			if header := parseHeader(line); header != "" {
+				blocks = append(blocks, header)
+			}
+			i++
+		case ruleRegex.MatchString(line):
+			blocks = append(blocks, horizontalRule)
+			i++
+		case isQuote(line):
+			var quoteLines []string
+			for ; i < len(lines) && isQuote(lines[i]); i++ {
+				quoteLines = append(quoteLines, stripQuote(lines[i]))
+			}
+			inner := parseBlocks(quoteLines, true)
+			if len(inner) == 0 {
+				break
+			}
+			if inQuote {
+				blocks = append(blocks, inner...)
+			} else {
+				blocks = append(blocks, "<blockquote>"+strings.Join(inner, "\n\n")+"</blockquote>")
+			}
+		case isTableStart(lines, i):
+			var block string
+			block, i = parseTable(lines, i)
+			blocks = append(blocks, block)
+		case listItemRegex.MatchString(line):
+			var block string
+			block, i = parseList(lines, i)
+			blocks = append(blocks, block)
+		default:
+			var paragraph []string
+			for ; i < len(lines) && !isBlank(lines[i]) && (len(paragraph) == 0 || !interruptsParagraph(lines, i)); i++ {
+				paragraph = append(paragraph, lines[i])
+			}
+			blocks = append(blocks, renderInline(joinLines(paragraph)))
+		}
+	}
+	return blocks
+}
+
+// interruptsParagraph checks if the line at i starts a new block without a blank line before it
+func interruptsParagraph(lines []string, i int) bool {
+	line := lines[i]
+	return fenceRegex.MatchString(line) || headerRegex.MatchString(line) || ruleRegex.MatchString(line) ||
+		isQuote(line) || isTableStart(lines, i) || listItemRegex.MatchString(line)
+}
+
+// joinLines joins the lines of a paragraph. Like Reddit, a single line break is a space unless
+// the line ends with two spaces or a backslash.
+func joinLines(lines []string) string {
+	var sb strings.Builder
+	for i, line := range lines {
+		hardBreak := strings.HasSuffix(line, "  ") || strings.HasSuffix(line, "\\")
+		line = strings.TrimSpace(line)
+		if i == len(lines)-1 {
+			sb.WriteString(line)
+			break
+		}
+		if hardBreak {
+			sb.WriteString(strings.TrimSuffix(line, "\\") + "\n")
+		} else {
+			sb.WriteString(line + " ")
+		}
+	}
+	return sb.String()
+}
+
+// isBlank checks if a line has only whitespace
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// isQuote checks if a line is a part of a quote. Lines which start with a spoiler are not quotes.
+func isQuote(line string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	return strings.HasPrefix(trimmed, ">") && !strings.HasPrefix(trimmed, ">!")
+}
+
+// stripQuote removes the quote marker of a line
+func stripQuote(line string) string {
+	line = strings.TrimPrefix(strings.TrimLeft(line, " "), ">")
+	return strings.TrimPrefix(line, " ")
+}
+
+// parseHeader converts a header to a bold text. The first level headers are also underlined.
+// Returns an empty string if the header is empty.
+func parseHeader(line string) string {
+	match := headerRegex.FindStringSubmatch(line)
+	content := renderInline(match[2])
+	if content == "" {
+		return ""
+	}
+	if len(match[1]) == 1 {
+		return "<b><u>" + content + "</u></b>"
+	}
+	return "<b>" + content + "</b>"
+}
+
+// codeBlock creates a pre block from a code
+func codeBlock(code, language string) string {
+	if language == "" {
+		return "<pre>" + html.EscapeString(code) + "</pre>"
+	}
+	return `<pre><code class="language-` + html.EscapeString(language) + `">` + html.EscapeString(code) + "</code></pre>"
+}
+
+// parseFencedCode parses a code block which starts at the fence in lines[start]. Returns the
+// block and the index of the line after it.
+func parseFencedCode(lines []string, start int) (string, int) {
+	match := fenceRegex.FindStringSubmatch(lines[start])
+	fence := match[1]
+	var code []string
+	i := start + 1
+	for ; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, lines[i])
+	}
+	return codeBlock(strings.Join(code, "\n"), match[2]), i
+}
+
+// isIndentedCode checks if a line is indented with at least four spaces or a tab
+func isIndentedCode(line string) bool {
+	return !isBlank(line) && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t"))
+}
+
+// parseIndentedCode parses a code block which is made of indented lines. Returns the block and
+// the index of the line after it.
+func parseIndentedCode(lines []string, start int) (string, int) {
+	var code []string
+	i := start
+	for ; i < len(lines); i++ {
+		if isIndentedCode(lines[i]) {
+			code = append(code, strings.TrimPrefix(strings.TrimPrefix(lines[i], "\t"), "    "))
+			continue
+		}
+		// Blank lines are a part of the code only if the code continues after them
+		if !isBlank(lines[i]) {
+			break
+		}
+		j := i
+		for j < len(lines) && isBlank(lines[j]) {
+			j++
+		}
+		if j == len(lines) || !isIndentedCode(lines[j]) {
+			break
+		}
+		for ; i < j; i++ {
+			code = append(code, "")
+		}
+		i--
+	}
+	return codeBlock(strings.Join(code, "\n"), ""), i
+}
+
+// listItem is an item of a list with the lines of its content
+type listItem struct {
+	indent  int
+	ordered bool
+	number  int
+	lines   []string
+}
+
+// parseList parses a list which starts at lines[start]. Returns the block and the index of
+// the line after it. Nested items are indented.
+func parseList(lines []string, start int) (string, int) {
+	var items []*listItem
+	i := start
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if match := listItemRegex.FindStringSubmatch(line); match != nil && !ruleRegex.MatchString(line) {
+			item := &listItem{indent: indentWidth(match[1])}
+			if number, err := strconv.Atoi(match[2][:len(match[2])-1]); err == nil {
+				item.ordered = true
+				item.number = number
+			}
+			// A list of another type starts a new list
+			if len(items) > 0 && item.indent <= items[0].indent && item.ordered != items[0].ordered {
+				break
+			}
+			if match[3] != "" {
+				item.lines = append(item.lines, match[3])
+			}
+			items = append(items, item)
+			continue
+		}
+		if isBlank(line) {
+			// The list continues if the next non-blank line is an item or is indented
+			j := i
+			for j < len(lines) && isBlank(lines[j]) {
+				j++
+			}
+			if j == len(lines) || (!listItemRegex.MatchString(lines[j]) && indentWidth(lines[j]) <= items[0].indent) {
+				break
+			}
+			i = j - 1
+			continue
+		}
+		// Indented lines and lazy continuations are a part of the last item
+		if interruptsParagraph(lines, i) && indentWidth(line) <= items[0].indent {
+			break
+		}
+		last := items[len(items)-1]
+		last.lines = append(last.lines, line)
+	}
+	// Render the items
+	var result []string
+	var indents []int
+	numbers := make(map[int]int)
+	for _, item := range items {
+		for len(indents) > 0 && indents[len(indents)-1] > item.indent {
+			indents = indents[:len(indents)-1]
+		}
+		if len(indents) == 0 || indents[len(indents)-1] < item.indent {
+			indents = append(indents, item.indent)
+		}
+		level := len(indents) - 1
+		// Reset the numbers of the deeper lists
+		for l := range numbers {
+			if l > level {
+				delete(numbers, l)
+			}
+		}
+		var marker string
+		if item.ordered {
+			if number, exists := numbers[level]; exists {
+				numbers[level] = number + 1
+			} else {
+				numbers[level] = item.number
+			}
+			marker = strconv.Itoa(numbers[level]) + "."
+		} else {
+			marker = bulletMarkers[level%len(bulletMarkers)]
+		}
+		result = append(result, strings.Repeat("    ", level)+marker+" "+renderInline(joinLines(item.lines)))
+	}
+	return strings.Join(result, "\n"), i
+}
+
+// indentWidth gets the width of the leading whitespace of a line. Tabs are four spaces.
+func indentWidth(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// The alignments of the columns of a table
+const (
+	alignLeft = iota
+	alignCenter
+	alignRight
+)
+
+// isTableStart checks if a table starts at lines[i]. A table is a row followed by a delimiter row.
+func isTableStart(lines []string, i int) bool {
+	if i+1 >= len(lines) || !strings.Contains(lines[i], "|") || !strings.Contains(lines[i+1], "-") {
+		return false
+	}
+	for _, cell := range splitTableRow(lines[i+1]) {
+		if !tableDelimiterCell.MatchString(cell) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitTableRow splits a row of a table into its cells. Escaped pipes do not split the cells.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, "\\|") {
+		line = line[:len(line)-1]
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// parseTable converts a table to a monospace block because Telegram does not support tables.
+// Returns the block and the index of the line after the table.
+func parseTable(lines []string, start int) (string, int) {
+	header := splitTableRow(lines[start])
+	alignments := make([]int, len(header))
+	for column, cell := range splitTableRow(lines[start+1]) {
+		if column >= len(alignments) {
+			break
+		}
+		left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			alignments[column] = alignCenter
+		case right:
+			alignments[column] = alignRight
+		}
+	}
+	rows := [][]string{header}
+	i := start + 2
+	for ; i < len(lines) && !isBlank(lines[i]) && strings.Contains(lines[i], "|"); i++ {
+		rows = append(rows, splitTableRow(lines[i]))
+	}
+	// Render the cells as plain text, make the rows as wide as the header and find the width of each column
+	widths := make([]int, len(header))
+	for r, row := range rows {
+		cells := make([]string, len(header))
+		for column := range cells {
+			if column < len(row) {
+				cells[column] = renderPlain(row[column])
+			}
+			widths[column] = max(widths[column], utf8.RuneCountInString(cells[column]))
+		}
+		rows[r] = cells
+	}
+	var result []string
+	for r, row := range rows {
+		cells := make([]string, len(row))
+		for column, cell := range row {
+			cells[column] = alignCell(cell, widths[column], alignments[column])
+		}
+		result = append(result, strings.TrimRight(strings.Join(cells, " | "), " "))
+		if r == 0 {
+			separators := make([]string, len(widths))
+			for column, width := range widths {
+				separators[column] = strings.Repeat("-", width)
+			}
+			result = append(result, strings.Join(separators, "-+-"))
+		}
+	}
+	return codeBlock(strings.Join(result, "\n"), ""), i
+}
+
+// alignCell pads a cell to width with the given alignment
+func alignCell(cell string, width, alignment int) string {
+	padding := width - utf8.RuneCountInString(cell)
+	switch alignment {
+	case alignRight:
+		return strings.Repeat(" ", padding) + cell
+	case alignCenter:
+		return strings.Repeat(" ", padding/2) + cell + strings.Repeat(" ", padding-padding/2)
+	default:
+		return cell + strings.Repeat(" ", padding)
+	}
+}
@@ -0,0 +1,475 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Markdown string
+		Expected string
+	}{
+		// Paragraphs
+		{
+			TestName: "Plain text",
+			Markdown: "Hello world",
+			Expected: "Hello world",
+		},
+		{
+			TestName: "Empty",
+			Markdown: "",
+			Expected: "",
+		},
+		{
+			TestName: "HTML escape",
+			Markdown: "a < b && c > d",
+			Expected: "a &lt; b &amp;&amp; c &gt; d",
+		},
+		{
+			TestName: "Paragraphs",
+			Markdown: "First\n\n\n\nSecond",
+			Expected: "First\n\nSecond",
+		},
+		{
+			TestName: "Soft line break",
+			Markdown: "One\ntwo",
+			Expected: "One two",
+		},
+		{
+			TestName: "Hard line break with spaces",
+			Markdown: "One  \ntwo",
+			Expected: "One\ntwo",
+		},
+		{
+			TestName: "Hard line break with backslash",
+			Markdown: "One\\\ntwo",
+			Expected: "One\ntwo",
+		},
+		{
+			TestName: "Windows line endings",
+			Markdown: "One\r\n\r\nTwo",
+			Expected: "One\n\nTwo",
+		},
+		// Emphasis
+		{
+			TestName: "Italic",
+			Markdown: "*italic* and _italic_",
+			Expected: "<i>italic</i> and <i>italic</i>",
+		},
+		{
+			TestName: "Bold",
+			Markdown: "**bold** and __bold__",
+			Expected: "<b>bold</b> and <b>bold</b>",
+		},
+		{
+			TestName: "Bold italic",
+			Markdown: "***both***",
+			Expected: "<b><i>both</i></b>",
+		},
+		{
+			TestName: "Bold inside italic",
+			Markdown: "*a **b** c*",
+			Expected: "<i>a <b>b</b> c</i>",
+		},
+		{
+			TestName: "Italic at the end of bold",
+			Markdown: "**a *b***",
+			Expected: "<b>a <i>b</i></b>",
+		},
+		{
+			TestName: "Bold at the end of italic",
+			Markdown: "*a **b***",
+			Expected: "<i>a <b>b</b></i>",
+		},
+		{
+			TestName: "Underscores inside words",
+			Markdown: "snake_case_name and __init__",
+			Expected: "snake_case_name and <b>init</b>",
+		},
+		{
+			TestName: "Unclosed emphasis",
+			Markdown: "2 * 3 = 6 and **not closed",
+			Expected: "2 * 3 = 6 and **not closed",
+		},
+		{
+			TestName: "Emphasis with spaces",
+			Markdown: "a * b * c",
+			Expected: "a * b * c",
+		},
+		{
+			TestName: "Strikethrough",
+			Markdown: "~~gone~~ and ~ tilde",
+			Expected: "<s>gone</s> and ~ tilde",
+		},
+		{
+			TestName: "Formatting inside strikethrough",
+			Markdown: "~~**bold** gone~~",
+			Expected: "<s><b>bold</b> gone</s>",
+		},
+		{
+			TestName: "Spoiler",
+			Markdown: "The end: >!everyone dies!<",
+			Expected: "The end: <tg-spoiler>everyone dies</tg-spoiler>",
+		},
+		{
+			TestName: "Spoiler at the start of a line",
+			Markdown: ">!hidden!< text",
+			Expected: "<tg-spoiler>hidden</tg-spoiler> text",
+		},
+		{
+			TestName: "Formatting inside spoiler",
+			Markdown: ">!*very* hidden!<",
+			Expected: "<tg-spoiler><i>very</i> hidden</tg-spoiler>",
+		},
+		{
+			TestName: "Unclosed spoiler",
+			Markdown: "a >!b",
+			Expected: "a &gt;!b",
+		},
+		// Escapes and code
+		{
+			TestName: "Escaped characters",
+			Markdown: "\\*not italic\\* and \\>!not spoiler",
+			Expected: "*not italic* and &gt;!not spoiler",
+		},
+		{
+			TestName: "Backslash which does not escape",
+			Markdown: "C:\\Users",
+			Expected: "C:\\Users",
+		},
+		{
+			TestName: "Inline code",
+			Markdown: "Run `go test ./...` now",
+			Expected: "Run <code>go test ./...</code> now",
+		},
+		{
+			TestName: "Markdown inside inline code",
+			Markdown: "`**not bold** <tag>`",
+			Expected: "<code>**not bold** &lt;tag&gt;</code>",
+		},
+		{
+			TestName: "Inline code with backticks",
+			Markdown: "`` a ` b ``",
+			Expected: "<code>a ` b</code>",
+		},
+		{
+			TestName: "Emphasis around inline code",
+			Markdown: "*a `*` b*",
+			Expected: "<i>a <code>*</code> b</i>",
+		},
+		{
+			TestName: "Unclosed inline code",
+			Markdown: "a ` b",
+			Expected: "a ` b",
+		},
+		// Links
+		{
+			TestName: "Link",
+			Markdown: "[Go](https://go.dev)",
+			Expected: `<a href="https://go.dev">Go</a>`,
+		},
+		{
+			TestName: "Link with title",
+			Markdown: `[Go](https://go.dev "The Go website")`,
+			Expected: `<a href="https://go.dev">Go</a>`,
+		},
+		{
+			TestName: "Link with query",
+			Markdown: "[search](https://example.com/?a=1&b=\"2\")",
+			Expected: `<a href="https://example.com/?a=1&amp;b=&#34;2&#34;">search</a>`,
+		},
+		{
+			TestName: "Link with parentheses",
+			Markdown: "[Go](https://en.wikipedia.org/wiki/Go_(programming_language))",
+			Expected: `<a href="https://en.wikipedia.org/wiki/Go_(programming_language)">Go</a>`,
+		},
+		{
+			TestName: "Formatted link",
+			Markdown: "[**bold** link](https://go.dev)",
+			Expected: `<a href="https://go.dev"><b>bold</b> link</a>`,
+		},
+		{
+			TestName: "Relative link",
+			Markdown: "[wiki](/r/golang/wiki)",
+			Expected: `<a href="https://www.reddit.com/r/golang/wiki">wiki</a>`,
+		},
+		{
+			TestName: "Not a link",
+			Markdown: "[not a link] (really)",
+			Expected: "[not a link] (really)",
+		},
+		{
+			TestName: "Auto link",
+			Markdown: "<https://go.dev>",
+			Expected: "https://go.dev",
+		},
+		{
+			TestName: "Bare URL",
+			Markdown: "https://www.reddit.com/r/golang/",
+			Expected: "https://www.reddit.com/r/golang/",
+		},
+		{
+			TestName: "Subreddit and user mentions",
+			Markdown: "Ask r/golang or /u/spez",
+			Expected: `Ask <a href="https://www.reddit.com/r/golang">r/golang</a> or <a href="https://www.reddit.com/u/spez">/u/spez</a>`,
+		},
+		{
+			TestName: "Mention inside a link",
+			Markdown: "[r/golang](https://www.reddit.com/r/golang)",
+			Expected: `<a href="https://www.reddit.com/r/golang">r/golang</a>`,
+		},
+		{
+			TestName: "Not a mention",
+			Markdown: "our/path and r/x",
+			Expected: "our/path and r/x",
+		},
+		// Superscript
+		{
+			TestName: "Superscript word",
+			Markdown: "x^2 and ^super script",
+			Expected: "x² and ˢᵘᵖᵉʳ script",
+		},
+		{
+			TestName: "Superscript with parentheses",
+			Markdown: "^(two words) after",
+			Expected: "ᵗʷᵒ ʷᵒʳᵈˢ after",
+		},
+		{
+			TestName: "Superscript without Unicode characters",
+			Markdown: "^(Q? ok)",
+			Expected: "Q? ᵒᵏ",
+		},
+		{
+			TestName: "Lone caret",
+			Markdown: "a ^ b",
+			Expected: "a ^ b",
+		},
+		// Headers and rules
+		{
+			TestName: "First level header",
+			Markdown: "# Title",
+			Expected: "<b><u>Title</u></b>",
+		},
+		{
+			TestName: "Other headers",
+			Markdown: "## Second ##\n###Third",
+			Expected: "<b>Second</b>\n\n<b>Third</b>",
+		},
+		{
+			TestName: "Formatted header",
+			Markdown: "## A *great* day",
+			Expected: "<b>A <i>great</i> day</b>",
+		},
+		{
+			TestName: "Empty header",
+			Markdown: "#\ntext",
+			Expected: "text",
+		},
+		{
+			TestName: "Header after paragraph",
+			Markdown: "text\n# Title",
+			Expected: "text\n\n<b><u>Title</u></b>",
+		},
+		{
+			TestName: "Horizontal rules",
+			Markdown: "a\n\n---\n\n* * *\n\n___",
+			Expected: "a\n\n——————————\n\n——————————\n\n——————————",
+		},
+		// Lists
+		{
+			TestName: "Unordered list",
+			Markdown: "- a\n* b\n+ c",
+			Expected: "• a\n• b\n• c",
+		},
+		{
+			TestName: "Ordered list",
+			Markdown: "1. a\n2. b\n3) c",
+			Expected: "1. a\n2. b\n3. c",
+		},
+		{
+			TestName: "Ordered list which is numbered like Reddit",
+			Markdown: "3. a\n1. b\n1. c",
+			Expected: "3. a\n4. b\n5. c",
+		},
+		{
+			TestName: "Nested list",
+			Markdown: "- a\n  - b\n    - c\n  - d\n- e",
+			Expected: "• a\n    ◦ b\n        ▪ c\n    ◦ d\n• e",
+		},
+		{
+			TestName: "Nested ordered list",
+			Markdown: "1. a\n   1. b\n   1. c\n2. d\n   1. e",
+			Expected: "1. a\n    1. b\n    2. c\n2. d\n    1. e",
+		},
+		{
+			TestName: "Formatted list items",
+			Markdown: "- **bold** item\n- [link](https://go.dev)",
+			Expected: "• <b>bold</b> item\n• <a href=\"https://go.dev\">link</a>",
+		},
+		{
+			TestName: "List item with continuation",
+			Markdown: "- first line\n  second line\n- b",
+			Expected: "• first line second line\n• b",
+		},
+		{
+			TestName: "Loose list",
+			Markdown: "- a\n\n- b\n\nafter",
+			Expected: "• a\n• b\n\nafter",
+		},
+		{
+			TestName: "Lists of different types",
+			Markdown: "- a\n\n1. b",
+			Expected: "• a\n\n1. b",
+		},
+		{
+			TestName: "List after paragraph",
+			Markdown: "Items:\n- a\n- b",
+			Expected: "Items:\n\n• a\n• b",
+		},
+		{
+			TestName: "Empty list item",
+			Markdown: "-\n- b",
+			Expected: "• \n• b",
+		},
+		// Quotes
+		{
+			TestName: "Quote",
+			Markdown: "> quoted\n> text\n\nafter",
+			Expected: "<blockquote>quoted text</blockquote>\n\nafter",
+		},
+		{
+			TestName: "Quote with paragraphs",
+			Markdown: "> one\n>\n> two",
+			Expected: "<blockquote>one\n\ntwo</blockquote>",
+		},
+		{
+			TestName: "Nested quote",
+			Markdown: "> outer\n>> inner",
+			Expected: "<blockquote>outer\n\ninner</blockquote>",
+		},
+		{
+			TestName: "Quote with markdown",
+			Markdown: "> # Title\n> - *a*\n> - b",
+			Expected: "<blockquote><b><u>Title</u></b>\n\n• <i>a</i>\n• b</blockquote>",
+		},
+		{
+			TestName: "Empty quote",
+			Markdown: ">\n\ntext",
+			Expected: "text",
+		},
+		// Code blocks
+		{
+			TestName: "Fenced code",
+			Markdown: "```\nif a < b {\n    **x**\n}\n```",
+			Expected: "<pre>if a &lt; b {\n    **x**\n}</pre>",
+		},
+		{
+			TestName: "Fenced code with language",
+			Markdown: "```go\nfmt.Println()\n```",
+			Expected: "<pre><code class=\"language-go\">fmt.Println()</code></pre>",
+		},
+		{
+			TestName: "Fenced code with tildes",
+			Markdown: "~~~\n```\n~~~",
+			Expected: "<pre>```</pre>",
+		},
+		{
+			TestName: "Unclosed fenced code",
+			Markdown: "```\ncode\n\nmore",
+			Expected: "<pre>code\n\nmore</pre>",
+		},
+		{
+			TestName: "Indented code",
+			Markdown: "text\n\n    a := 1\n\n    b := 2\n\nafter",
+			Expected: "text\n\n<pre>a := 1\n\nb := 2</pre>\n\nafter",
+		},
+		{
+			TestName: "Indented code with tab",
+			Markdown: "\tx <- y",
+			Expected: "<pre>x &lt;- y</pre>",
+		},
+		// Tables
+		{
+			TestName: "Table",
+			Markdown: "| Name | Age |\n|------|-----|\n| Alice | 30 |\n| Bob | 4 |",
+			Expected: "<pre>Name  | Age\n------+----\nAlice | 30\nBob   | 4</pre>",
+		},
+		{
+			TestName: "Table with alignment",
+			Markdown: "Left|Center|Right\n:--|:-:|--:\na|b|c\nlonger|longer|longer",
+			Expected: "<pre>Left   | Center |  Right\n-------+--------+-------\na      |   b    |      c\nlonger | longer | longer</pre>",
+		},
+		{
+			TestName: "Table with markdown",
+			Markdown: "| **a** | [b](https://go.dev) |\n|---|---|\n| `<c>` | ~~d~~ |",
+			Expected: "<pre>a   | b\n----+--\n&lt;c&gt; | d</pre>",
+		},
+		{
+			TestName: "Table with missing and extra cells",
+			Markdown: "| a | b |\n|---|---|\n| 1 |\n| 2 | 3 | 4 |",
+			Expected: "<pre>a | b\n--+--\n1 |\n2 | 3</pre>",
+		},
+		{
+			TestName: "Table with escaped pipe",
+			Markdown: "| a |\n|---|\n| x \\| y |",
+			Expected: "<pre>a\n-----\nx | y</pre>",
+		},
+		{
+			TestName: "Table after paragraph",
+			Markdown: "Scores:\n| a | b |\n|---|---|\n| 1 | 2 |\n\nafter",
+			Expected: "Scores:\n\n<pre>a | b\n--+--\n1 | 2</pre>\n\nafter",
+		},
+		{
+			TestName: "Not a table",
+			Markdown: "a | b\nc | d",
+			Expected: "a | b c | d",
+		},
+		// Everything together
+		{
+			TestName: "Post",
+			Markdown: "# Update\n\nThanks r/golang! Here is the **changelog**:\n\n1. Fixed `nil` panics\n2. >!Secret!< feature\n\n> Edit: ~~typo~~\n\n|Version|Date|\n|:-|:-|\n|1.0|today|",
+			Expected: "<b><u>Update</u></b>\n\n" +
+				"Thanks <a href=\"https://www.reddit.com/r/golang\">r/golang</a>! Here is the <b>changelog</b>:\n\n" +
+				"1. Fixed <code>nil</code> panics\n2. <tg-spoiler>Secret</tg-spoiler> feature\n\n" +
+				"<blockquote>Edit: <s>typo</s></blockquote>\n\n" +
+				"<pre>Version | Date\n--------+------\n1.0     | today</pre>",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Equal(t, test.Expected, ToHTML(test.Markdown))
+		})
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Markdown string
+		Expected []string
+	}{
+		{
+			TestName: "Empty",
+			Markdown: "\n\n",
+			Expected: nil,
+		},
+		{
+			TestName: "Paragraphs",
+			Markdown: "*one*\n\ntwo\nthree",
+			Expected: []string{"<i>one</i>", "two three"},
+		},
+		{
+			TestName: "Mixed blocks",
+			Markdown: "# Title\n- a\n- b\n```\ncode\n```\n> quote",
+			Expected: []string{"<b><u>Title</u></b>", "• a\n• b", "<pre>code</pre>", "<blockquote>quote</blockquote>"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Equal(t, test.Expected, Blocks(test.Markdown))
+		})
+	}
+}
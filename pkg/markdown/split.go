@@ -0,0 +1,198 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+	"unicode/utf16"
+)
+
+// htmlTag is a tag which is open while a block is being split
+type htmlTag struct {
+	// The whole opening tag like <a href="...">
+	open string
+	name string
+}
+
+// PlainText removes the tags of an HTML text which ToHTML or Blocks has created and unescapes it
+func PlainText(text string) string {
+	var sb strings.Builder
+	for len(text) > 0 {
+		if text[0] == '<' {
+			end := strings.IndexByte(text, '>')
+			if end == -1 {
+				break
+			}
+			text = text[end+1:]
+			continue
+		}
+		end := strings.IndexByte(text, '<')
+		if end == -1 {
+			end = len(text)
+		}
+		sb.WriteString(html.UnescapeString(text[:end]))
+		text = text[end:]
+	}
+	return sb.String()
+}
+
+// TextLength gets the length of an HTML text like Telegram counts it. This is the number of
+// UTF-16 code units of the text without the tags.
+func TextLength(text string) int {
+	return utf16Length(PlainText(text))
+}
+
+// utf16Length gets the number of UTF-16 code units of a text
+func utf16Length(text string) int {
+	length := 0
+	for _, c := range text {
+		length += utf16.RuneLen(c)
+	}
+	return length
+}
+
+// Split joins the blocks which Blocks has created into texts which have at most limit characters
+// (counted by TextLength). The blocks are only split when a block does not fit in a text on its own.
+// In that case, it is split at a line break or a space and the tags which are open at the split point
+// are closed in the first part and opened again in the second one.
+func Split(blocks []string, limit int) []string {
+	var result []string
+	var current []string
+	currentLength := 0
+	for _, block := range blocks {
+		length := TextLength(block)
+		separatorLength := 0
+		if len(current) > 0 {
+			separatorLength = 2 // the "\n\n" between the blocks
+		}
+		if currentLength+separatorLength+length <= limit {
+			current = append(current, block)
+			currentLength += separatorLength + length
+			continue
+		}
+		if len(current) > 0 {
+			result = append(result, strings.Join(current, "\n\n"))
+			current, currentLength = nil, 0
+		}
+		if length <= limit {
+			current, currentLength = []string{block}, length
+			continue
+		}
+		result = append(result, splitBlock(block, limit)...)
+	}
+	if len(current) > 0 {
+		result = append(result, strings.Join(current, "\n\n"))
+	}
+	return result
+}
+
+// splitBlock splits a block which is longer than limit
+func splitBlock(block string, limit int) []string {
+	var result []string
+	var current strings.Builder
+	var open []htmlTag
+	length := 0
+	// The last points which the block can be split at
+	type splitPoint struct {
+		index, length int
+		open          []htmlTag
+	}
+	var lastLineBreak, lastSpace *splitPoint
+	for len(block) > 0 {
+		// Tags
+		if block[0] == '<' {
+			end := strings.IndexByte(block, '>') + 1
+			if end == 0 {
+				end = len(block)
+			}
+			tag := block[:end]
+			block = block[end:]
+			current.WriteString(tag)
+			if strings.HasPrefix(tag, "</") {
+				if len(open) > 0 {
+					open = open[:len(open)-1]
+				}
+			} else {
+				name := strings.TrimSuffix(strings.TrimPrefix(tag, "<"), ">")
+				if index := strings.IndexByte(name, ' '); index != -1 {
+					name = name[:index]
+				}
+				open = append(open, htmlTag{open: tag, name: name})
+			}
+			continue
+		}
+		// A character or an entity
+		unitLength := 1
+		if block[0] == '&' {
+			if end := strings.IndexByte(block, ';'); end != -1 {
+				unitLength = end + 1
+			}
+		} else {
+			for unitLength < len(block) && !isRuneStart(block[unitLength]) {
+				unitLength++
+			}
+		}
+		unit := block[:unitLength]
+		block = block[unitLength:]
+		plain := html.UnescapeString(unit)
+		unitTextLength := utf16Length(plain)
+		if length+unitTextLength > limit && length > 0 {
+			// Prefer the line breaks unless they make the part too short. If this is a space
+			// itself, the block is split here and the space is dropped.
+			isSpace := plain == " " || plain == "\n"
+			split := &splitPoint{index: current.Len(), length: length, open: open}
+			switch {
+			case isSpace:
+			case lastLineBreak != nil && lastLineBreak.length >= limit/2:
+				split = lastLineBreak
+			case lastSpace != nil:
+				split = lastSpace
+			case lastLineBreak != nil:
+				split = lastLineBreak
+			}
+			text := current.String()
+			result = append(result, text[:split.index]+closeTags(split.open))
+			current.Reset()
+			current.WriteString(openTags(split.open) + text[split.index:])
+			length -= split.length
+			lastLineBreak, lastSpace = nil, nil
+			if isSpace {
+				continue
+			}
+		}
+		current.WriteString(unit)
+		length += unitTextLength
+		switch plain {
+		case "\n":
+			lastLineBreak = &splitPoint{index: current.Len(), length: length, open: append([]htmlTag(nil), open...)}
+		case " ":
+			lastSpace = &splitPoint{index: current.Len(), length: length, open: append([]htmlTag(nil), open...)}
+		}
+	}
+	if current.Len() > 0 {
+		result = append(result, current.String())
+	}
+	return result
+}
+
+// isRuneStart checks if a byte is the first byte of a UTF-8 character
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// openTags opens the tags again
+func openTags(tags []htmlTag) string {
+	var sb strings.Builder
+	for _, tag := range tags {
+		sb.WriteString(tag.open)
+	}
+	return sb.String()
+}
+
+// closeTags closes the tags in the reverse order
+func closeTags(tags []htmlTag) string {
+	var sb strings.Builder
+	for i := len(tags) - 1; i >= 0; i-- {
+		sb.WriteString("</" + tags[i].name + ">")
+	}
+	return sb.String()
+}
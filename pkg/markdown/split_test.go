@@ -0,0 +1,143 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlainText(t *testing.T) {
+	assert.Equal(t, "bold & <code> link", PlainText(`<b>bold</b> &amp; <code>&lt;code&gt;</code> <a href="https://go.dev">link</a>`))
+}
+
+func TestTextLength(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Text     string
+		Expected int
+	}{
+		{
+			TestName: "Tags",
+			Text:     "<b>abc</b>",
+			Expected: 3,
+		},
+		{
+			TestName: "Entities",
+			Text:     "&lt;&amp;&#34;",
+			Expected: 3,
+		},
+		{
+			TestName: "Non-ASCII",
+			Text:     "سلام",
+			Expected: 4,
+		},
+		{
+			TestName: "Surrogate pairs",
+			Text:     "🔗",
+			Expected: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Equal(t, test.Expected, TextLength(test.Text))
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Blocks   []string
+		Limit    int
+		Expected []string
+	}{
+		{
+			TestName: "Fits",
+			Blocks:   []string{"<b>a</b>", "b"},
+			Limit:    10,
+			Expected: []string{"<b>a</b>\n\nb"},
+		},
+		{
+			TestName: "Empty",
+			Blocks:   nil,
+			Limit:    10,
+			Expected: nil,
+		},
+		{
+			TestName: "Split at blocks",
+			Blocks:   []string{"aaaa", "<i>bbbb</i>", "cccc"},
+			Limit:    10,
+			Expected: []string{"aaaa\n\n<i>bbbb</i>", "cccc"},
+		},
+		{
+			TestName: "Tags do not count",
+			Blocks:   []string{`<a href="https://example.com/long/link">aaaa</a>`, "bbbb"},
+			Limit:    10,
+			Expected: []string{`<a href="https://example.com/long/link">aaaa</a>` + "\n\nbbbb"},
+		},
+		{
+			TestName: "Split a long block at spaces",
+			Blocks:   []string{"aaa bbb ccc ddd"},
+			Limit:    8,
+			Expected: []string{"aaa bbb ", "ccc ddd"},
+		},
+		{
+			TestName: "Split a long block at line breaks",
+			Blocks:   []string{"<pre>aaa bbb\nccc ddd</pre>"},
+			Limit:    10,
+			Expected: []string{"<pre>aaa bbb\n</pre>", "<pre>ccc ddd</pre>"},
+		},
+		{
+			TestName: "Reopen nested tags",
+			Blocks:   []string{`<blockquote><a href="https://go.dev">aaa <b>bbb ccc</b></a> ddd</blockquote>`},
+			Limit:    8,
+			Expected: []string{
+				`<blockquote><a href="https://go.dev">aaa <b>bbb </b></a></blockquote>`,
+				`<blockquote><a href="https://go.dev"><b>ccc</b></a> ddd</blockquote>`,
+			},
+		},
+		{
+			TestName: "Do not split entities",
+			Blocks:   []string{"&lt;&lt;&lt;&lt;"},
+			Limit:    3,
+			Expected: []string{"&lt;&lt;&lt;", "&lt;"},
+		},
+		{
+			TestName: "Words longer than limit",
+			Blocks:   []string{"abcdefgh"},
+			Limit:    3,
+			Expected: []string{"abc", "def", "gh"},
+		},
+		{
+			TestName: "Blocks around a long block",
+			Blocks:   []string{"a", "bb bb bb", "c"},
+			Limit:    5,
+			Expected: []string{"a", "bb bb", "bb", "c"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Equal(t, test.Expected, Split(test.Blocks, test.Limit))
+		})
+	}
+}
+
+func TestSplitLimit(t *testing.T) {
+	text := strings.Repeat("**Lorem ipsum** dolor sit amet, >!consectetur!< adipiscing elit.\n", 300) +
+		"\n\n| a | b |\n|---|---|\n" + strings.Repeat("| 1 | 2 |\n", 300)
+	blocks := Blocks(text)
+	messages := Split(blocks, 4096)
+	assert.Greater(t, len(messages), 1)
+	var plain []string
+	for _, message := range messages {
+		assert.LessOrEqual(t, TextLength(message), 4096)
+		// The tags must be balanced
+		assert.Equal(t, strings.Count(message, "<b>"), strings.Count(message, "</b>"))
+		assert.Equal(t, strings.Count(message, "<tg-spoiler>"), strings.Count(message, "</tg-spoiler>"))
+		assert.Equal(t, strings.Count(message, "<pre>"), strings.Count(message, "</pre>"))
+		plain = append(plain, PlainText(message))
+	}
+	// Nothing is lost
+	assert.Equal(t, strings.Join(strings.Fields(PlainText(strings.Join(blocks, " "))), " "), strings.Join(strings.Fields(strings.Join(plain, " ")), " "))
+}
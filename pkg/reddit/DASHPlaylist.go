@@ -4,6 +4,7 @@ import (
 	"github.com/lartie/RedditDownloaderBot/pkg/common"
 	"encoding/xml"
 	"io"
+	"math"
 	"net/http"
 	"regexp"
 	"sort"
@@ -16,10 +17,15 @@ import (
 // numberRegex will only match numbers in a string
 var numberRegex = regexp.MustCompile("(\\d+)")
 
+// dashDurationRegex matches the durations of DASH playlists like PT1H2M3.5S
+var dashDurationRegex = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?$`)
+
 // DashPlaylistXML is the root of
 type DashPlaylistXML struct {
 	XMLName xml.Name `xml:"MPD"`
-	Period  struct {
+	// The duration of the video like PT1M2.5S
+	Duration string `xml:"mediaPresentationDuration,attr"`
+	Period   struct {
 		XMLName    xml.Name                     `xml:"Period"`
 		MediaTypes []DashPlaylistApplicationSet `xml:"AdaptationSet"`
 	}
@@ -73,6 +79,8 @@ type AvailableAudio string
 type AvailableMedia struct {
 	AvailableVideos []AvailableVideo
 	AvailableAudios []AvailableAudio
+	// The duration of the video in seconds. Zero if unknown.
+	Duration int64
 }
 
 // parseDashPlaylist will parse the DashPlaylist file from Reddit
@@ -85,6 +93,7 @@ func parseDashPlaylist(r io.Reader) (AvailableMedia, error) {
 	}
 	// Convert to result
 	var result AvailableMedia
+	result.Duration = parseDashDuration(parsedXML.Duration)
 	for _, media := range parsedXML.Period.MediaTypes {
 		switch media.ContentType {
 		case "video":
@@ -113,6 +122,19 @@ func parseDashPlaylist(r io.Reader) (AvailableMedia, error) {
 	return result, nil
 }
 
+// parseDashDuration parses the duration of a DASH playlist and rounds it to seconds.
+// Returns zero if the duration cannot be parsed.
+func parseDashDuration(duration string) int64 {
+	match := dashDurationRegex.FindStringSubmatch(duration)
+	if match == nil {
+		return 0
+	}
+	hours, _ := strconv.ParseInt(match[1], 10, 64)
+	minutes, _ := strconv.ParseInt(match[2], 10, 64)
+	seconds, _ := strconv.ParseFloat(match[3], 64)
+	return hours*3600 + minutes*60 + int64(math.Round(seconds))
+}
+
 // ParseDashPlaylistFromID will parse the dash playlist file for a DASHPlaylist.mpd url
 func ParseDashPlaylistFromID(dashURL string) (AvailableMedia, error) {
 	// Check if vidID is empty
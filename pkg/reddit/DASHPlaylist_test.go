@@ -56,6 +56,15 @@ func TestSortVideoQualities(t *testing.T) {
 	}
 }
 
+func TestParseDashDuration(t *testing.T) {
+	assert.Equal(t, int64(13), parseDashDuration("PT13S"))
+	assert.Equal(t, int64(29), parseDashDuration("PT28.5S"))
+	assert.Equal(t, int64(125), parseDashDuration("PT2M5S"))
+	assert.Equal(t, int64(3723), parseDashDuration("PT1H2M3.013S"))
+	assert.Equal(t, int64(0), parseDashDuration(""))
+	assert.Equal(t, int64(0), parseDashDuration("P1D"))
+}
+
 func TestStructParser(t *testing.T) {
 	tests := []struct {
 		Name     string
@@ -98,6 +107,7 @@ func TestStructParser(t *testing.T) {
 					},
 				},
 				AvailableAudios: []AvailableAudio{"DASH_AUDIO_64.mp4", "DASH_AUDIO_128.mp4"},
+				Duration:        13,
 			},
 		},
 		{ // From https://v.redd.it/dbelx9ulpacb1/DASHPlaylist.mpd
@@ -142,6 +152,7 @@ func TestStructParser(t *testing.T) {
 					},
 				},
 				AvailableAudios: []AvailableAudio{"DASH_audio.mp4"},
+				Duration:        9,
 			},
 		},
 		{ // From https://v.redd.it/jzsvg42m78eb1/DASHPlaylist.mpd
@@ -186,6 +197,7 @@ func TestStructParser(t *testing.T) {
 					},
 				},
 				AvailableAudios: nil,
+				Duration:        125,
 			},
 		},
 		{ // From https://v.redd.it/l81cm9bcwtp41/DASHPlaylist.mpd
@@ -223,6 +235,7 @@ func TestStructParser(t *testing.T) {
 					},
 				},
 				AvailableAudios: []AvailableAudio{"audio"},
+				Duration:        29,
 			},
 		},
 		{ // From https://v.redd.it/o8y2x0z8jsq41/DASHPlaylist.mpd
@@ -253,6 +266,7 @@ func TestStructParser(t *testing.T) {
 					},
 				},
 				AvailableAudios: nil,
+				Duration:        31,
 			},
 		},
 	}
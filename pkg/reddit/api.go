@@ -0,0 +1,175 @@
+package reddit
+
+import "encoding/json"
+
+// This file contains the models of the JSON which the Reddit API returns. Only the fields which
+// we use are decoded. Fields which we cannot work without are pointers (or slices and maps), so
+// we can tell if they are missing and report them with missingFieldError.
+
+// apiListing is the root of the responses of listing endpoints like /api/info
+type apiListing[T any] struct {
+	Data *apiListingData[T] `json:"data"`
+}
+
+// apiListingData is the data of a listing
+type apiListingData[T any] struct {
+	Children []apiListingChild[T] `json:"children"`
+	// The fullname of the last thing in the listing which the next page starts after
+	After string `json:"after"`
+}
+
+// apiListingChild is a thing in a listing. Data is either apiLink (t3) or apiComment (t1).
+type apiListingChild[T any] struct {
+	Kind string `json:"kind"`
+	Data *T     `json:"data"`
+}
+
+// firstChild gets the data of the first child of a listing. The error names the missing node.
+func (l apiListing[T]) firstChild() (*T, *FetchError) {
+	if l.Data == nil {
+		return nil, missingFieldError("data")
+	}
+	if len(l.Data.Children) == 0 {
+		return nil, missingFieldError("data->children")
+	}
+	if l.Data.Children[0].Data == nil {
+		return nil, missingFieldError("data->children[0]->data")
+	}
+	return l.Data.Children[0].Data, nil
+}
+
+// apiLink is a post (t3) in Reddit
+type apiLink struct {
+	Title               *string                     `json:"title"`
+	Selftext            *string                     `json:"selftext"`
+	URL                 *string                     `json:"url"`
+	Domain              *string                     `json:"domain"`
+	PostHint            *string                     `json:"post_hint"`
+	Over18              bool                        `json:"over_18"`
+	Spoiler             bool                        `json:"spoiler"`
+	Thumbnail           string                      `json:"thumbnail"`
+	Preview             *apiPreview                 `json:"preview"`
+	Media               *apiMedia                   `json:"media"`
+	MediaMetadata       map[string]apiMediaMetadata `json:"media_metadata"`
+	GalleryData         *apiGalleryData             `json:"gallery_data"`
+	CrosspostParentList []apiLink                   `json:"crosspost_parent_list"`
+	PollData            *apiPollData                `json:"poll_data"`
+	Permalink           string                      `json:"permalink"`
+	Stickied            bool                        `json:"stickied"`
+	Subreddit           string                      `json:"subreddit"`
+	Author              string                      `json:"author"`
+	Score               int64                       `json:"score"`
+	LinkFlairText       string                      `json:"link_flair_text"`
+	CreatedUTC          float64                     `json:"created_utc"`
+}
+
+// apiComment is a comment (t1) in Reddit
+type apiComment struct {
+	Body     *string    `json:"body"`
+	Author   string     `json:"author"`
+	Score    int64      `json:"score"`
+	Stickied bool       `json:"stickied"`
+	Replies  apiReplies `json:"replies"`
+	ParentID string     `json:"parent_id"`
+	LinkID   string     `json:"link_id"`
+}
+
+// apiReplies is the replies of a comment. Reddit sends an empty string instead of a listing
+// if a comment has no replies (or they are not loaded).
+type apiReplies struct {
+	apiListing[apiComment]
+}
+
+func (r *apiReplies) UnmarshalJSON(data []byte) error {
+	if string(data) == `""` {
+		return nil
+	}
+	return json.Unmarshal(data, &r.apiListing)
+}
+
+// apiPreview is the preview field of a link which contains the resized images of it
+type apiPreview struct {
+	Images             []apiPreviewImage `json:"images"`
+	RedditVideoPreview *apiRedditVideo   `json:"reddit_video_preview"`
+}
+
+// apiPreviewImage is an image in preview with all of its resolutions
+type apiPreviewImage struct {
+	Source      *apiPreviewSource  `json:"source"`
+	Resolutions []apiPreviewSource `json:"resolutions"`
+	Variants    struct {
+		MP4 *apiPreviewImage `json:"mp4"`
+	} `json:"variants"`
+}
+
+// apiPreviewSource is one resolution of a preview image
+type apiPreviewSource struct {
+	URL    *string `json:"url"`
+	Width  int64   `json:"width"`
+	Height int64   `json:"height"`
+}
+
+// apiMedia is the media field of a link
+type apiMedia struct {
+	RedditVideo *apiRedditVideo `json:"reddit_video"`
+}
+
+// apiRedditVideo is a video which is hosted on v.redd.it
+type apiRedditVideo struct {
+	FallbackURL *string `json:"fallback_url"`
+	DashURL     *string `json:"dash_url"`
+	Duration    float64 `json:"duration"`
+}
+
+// apiMediaMetadata is a media of a gallery. Type is the "e" field and says what kind of media is this.
+type apiMediaMetadata struct {
+	Status string  `json:"status"`
+	Type   string  `json:"e"`
+	ID     *string `json:"id"`
+	// The source of images and animated images
+	Source *struct {
+		URL *string `json:"u"`
+		MP4 *string `json:"mp4"`
+	} `json:"s"`
+	// The dimensions of videos
+	Width  *int64 `json:"x"`
+	Height *int64 `json:"y"`
+	// The DASH playlist of videos
+	DashURL *string `json:"dashUrl"`
+	// The HLS playlist of videos. Some videos only have this one.
+	HlsURL *string `json:"hlsUrl"`
+}
+
+// apiGalleryData is the order and captions of the media of a gallery
+type apiGalleryData struct {
+	Items []apiGalleryItem `json:"items"`
+}
+
+// apiGalleryItem is an entry in the gallery_data. MediaID is the key of this media in media_metadata.
+type apiGalleryItem struct {
+	MediaID     string `json:"media_id"`
+	Caption     string `json:"caption"`
+	OutboundURL string `json:"outbound_url"`
+}
+
+// apiPollData is the poll of a link. VotingEndTimestamp is in milliseconds.
+type apiPollData struct {
+	Options            []apiPollOption `json:"options"`
+	TotalVoteCount     int64           `json:"total_vote_count"`
+	VotingEndTimestamp int64           `json:"voting_end_timestamp"`
+}
+
+// apiPollOption is an option of a poll. VoteCount does not exist if Reddit hides the votes.
+type apiPollOption struct {
+	Text      *string `json:"text"`
+	VoteCount *int64  `json:"vote_count"`
+}
+
+// missingFieldError creates the error which is returned when a field which we need
+// does not exist in the JSON of Reddit
+func missingFieldError(field string) *FetchError {
+	return &FetchError{
+		NormalError: "Unable to parse the page data: couldn’t find node `" + field + "`",
+		BotError:    "Unable to parse the page data: couldn’t find node `" + field + "`",
+	}
+}
@@ -0,0 +1,81 @@
+package reddit
+
+import (
+	"html"
+	"strconv"
+)
+
+// commentsApiPoint is the endpoint format which we should get the comments of posts from
+const commentsApiPoint = "https://api.reddit.com/comments/"
+
+// commentsRequestLimit is the maximum number of comments which are requested from Reddit. The
+// top comments are selected from them.
+const commentsRequestLimit = 100
+
+// GetComments gets the post and its comments from reddit. depth is the number of levels of
+// the replies which Reddit returns.
+func (o *Oauth) GetComments(id string, depth int) ([]apiListing[apiComment], error) {
+	var listings []apiListing[apiComment]
+	err := o.doGetJsonRequest(commentsApiPoint+id+"?sort=top&limit="+strconv.Itoa(commentsRequestLimit)+"&depth="+strconv.Itoa(depth), &listings)
+	return listings, err
+}
+
+// FetchComments gets the top comments of the post in postUrl. count is the number of comments
+// in each level and depth is the number of levels which are returned (1 means no replies).
+// The result is empty if the link is a comment.
+func (o *Oauth) FetchComments(postUrl string, count, depth int) ([]Comment, *FetchError) {
+	postId, _, isComment, fetchError := o.getPostID(postUrl)
+	if fetchError != nil {
+		return nil, fetchError
+	}
+	if isComment {
+		return nil, nil
+	}
+	listings, err := o.GetComments(postId, depth)
+	if err != nil {
+		return nil, &FetchError{
+			NormalError: "Unable to get the comments: " + err.Error(),
+			BotError:    "Unable to get the comments",
+		}
+	}
+	return getComments(listings, count, depth)
+}
+
+// getComments gets the top comments from the response of the comments endpoint. The first listing
+// of the response is the post and the second one is the comments.
+func getComments(listings []apiListing[apiComment], count, depth int) ([]Comment, *FetchError) {
+	if len(listings) < 2 {
+		return nil, missingFieldError("[1]")
+	}
+	if listings[1].Data == nil {
+		return nil, missingFieldError("[1]->data")
+	}
+	return convertComments(listings[1].Data.Children, count, depth), nil
+}
+
+// convertComments converts the comments of a listing and their replies. The stickied comments
+// (which are usually from the moderators) and the links to load more comments are skipped.
+func convertComments(children []apiListingChild[apiComment], count, depth int) []Comment {
+	if depth <= 0 {
+		return nil
+	}
+	var result []Comment
+	for _, child := range children {
+		if len(result) == count {
+			break
+		}
+		if child.Kind != "t1" || child.Data == nil || child.Data.Body == nil || child.Data.Stickied {
+			continue
+		}
+		comment := Comment{
+			Author: child.Data.Author,
+			Score:  child.Data.Score,
+			Text:   html.UnescapeString(*child.Data.Body),
+		}
+		if replies := child.Data.Replies.Data; replies != nil {
+			comment.Replies = convertComments(replies.Children, count, depth-1)
+		}
+		result = append(result, comment)
+	}
+	return result
+}
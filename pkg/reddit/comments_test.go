@@ -0,0 +1,89 @@
+package reddit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// commentsTestData is a response of the comments endpoint with the post removed
+const commentsTestData = `[
+	{"kind":"Listing","data":{"children":[{"kind":"t3","data":{"title":"Post"}}]}},
+	{"kind":"Listing","data":{"children":[
+		{"kind":"t1","data":{"author":"AutoModerator","score":1,"stickied":true,"body":"Rules","replies":""}},
+		{"kind":"t1","data":{"author":"alice","score":120,"body":"First &amp; best","replies":{"kind":"Listing","data":{"children":[
+			{"kind":"t1","data":{"author":"bob","score":30,"body":"Reply 1","replies":{"kind":"Listing","data":{"children":[
+				{"kind":"t1","data":{"author":"carol","score":5,"body":"Deep reply","replies":""}}
+			]}}}},
+			{"kind":"t1","data":{"author":"dave","score":-2,"body":"Reply 2","replies":""}},
+			{"kind":"more","data":{"count":10,"children":["abc"]}}
+		]}}}},
+		{"kind":"t1","data":{"author":"erin","score":50,"body":"Second","replies":""}},
+		{"kind":"t1","data":{"author":"frank","score":10,"body":"Third","replies":""}},
+		{"kind":"more","data":{"count":100,"children":["def"]}}
+	]}}
+]`
+
+func TestGetComments(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Count    int
+		Depth    int
+		Expected []Comment
+	}{
+		{
+			TestName: "Top comments",
+			Count:    2,
+			Depth:    1,
+			Expected: []Comment{
+				{Author: "alice", Score: 120, Text: "First & best"},
+				{Author: "erin", Score: 50, Text: "Second"},
+			},
+		},
+		{
+			TestName: "Threads",
+			Count:    1,
+			Depth:    2,
+			Expected: []Comment{
+				{Author: "alice", Score: 120, Text: "First & best", Replies: []Comment{
+					{Author: "bob", Score: 30, Text: "Reply 1"},
+				}},
+			},
+		},
+		{
+			TestName: "Deep threads",
+			Count:    5,
+			Depth:    3,
+			Expected: []Comment{
+				{Author: "alice", Score: 120, Text: "First & best", Replies: []Comment{
+					{Author: "bob", Score: 30, Text: "Reply 1", Replies: []Comment{
+						{Author: "carol", Score: 5, Text: "Deep reply"},
+					}},
+					{Author: "dave", Score: -2, Text: "Reply 2"},
+				}},
+				{Author: "erin", Score: 50, Text: "Second"},
+				{Author: "frank", Score: 10, Text: "Third"},
+			},
+		},
+	}
+	var listings []apiListing[apiComment]
+	assert.NoError(t, json.Unmarshal([]byte(commentsTestData), &listings))
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			comments, fetchError := getComments(listings, test.Count, test.Depth)
+			assert.Nil(t, fetchError)
+			assert.Equal(t, test.Expected, comments)
+		})
+	}
+}
+
+func TestGetCommentsMissingField(t *testing.T) {
+	var listings []apiListing[apiComment]
+	assert.NoError(t, json.Unmarshal([]byte(`[{"data":{"children":[]}}]`), &listings))
+	_, fetchError := getComments(listings, 5, 1)
+	assert.Equal(t, missingFieldError("[1]"), fetchError)
+	assert.NoError(t, json.Unmarshal([]byte(`[{"data":{"children":[]}},{"kind":"Listing"}]`), &listings))
+	_, fetchError = getComments(listings, 5, 1)
+	assert.Equal(t, missingFieldError("[1]->data"), fetchError)
+}
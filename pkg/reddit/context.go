@@ -0,0 +1,101 @@
+package reddit
+
+import (
+	"html"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MaxCommentContext is the maximum number of parents which are fetched for a comment. Each parent
+// is a request to Reddit.
+const MaxCommentContext = 8
+
+// CommentThread is a comment with its parents and the title of the post which it's on
+type CommentThread struct {
+	// The title of the post. It's empty if the post cannot be found.
+	PostTitle string
+	// The parents of the comment from the farthest one to the direct parent. Their replies are empty.
+	Parents []Comment
+	Comment Comment
+}
+
+// FetchCommentThread walks up the parents of a comment which StartFetch has returned. At most
+// depth parents are fetched.
+func (o *Oauth) FetchCommentThread(comment FetchResultComment, depth int) (CommentThread, *FetchError) {
+	return getCommentThread(comment, depth, o.GetComment, o.GetPost)
+}
+
+// getCommentThread creates the thread of a comment. getComment and getPost fetch a comment and a
+// post by their IDs (without the t1_ and t3_ prefixes).
+//
+// This function is seperated from Oauth.FetchCommentThread to write tests for it
+func getCommentThread(comment FetchResultComment, depth int,
+	getComment func(string) (apiListing[apiComment], error),
+	getPost func(string) (apiListing[apiLink], error)) (CommentThread, *FetchError) {
+	depth = min(depth, MaxCommentContext)
+	thread := CommentThread{
+		Comment: Comment{Author: comment.Author, Score: comment.Score, Text: comment.Text},
+	}
+	// The parent of a top level comment is the post
+	parentID := comment.ParentID
+	for len(thread.Parents) < depth && strings.HasPrefix(parentID, "t1_") {
+		listing, err := getComment(strings.TrimPrefix(parentID, "t1_"))
+		if err != nil {
+			return CommentThread{}, &FetchError{
+				NormalError: "Unable to fetch the parent comment: " + err.Error(),
+				BotError:    "Unable to fetch the parent comment",
+			}
+		}
+		parent, fetchError := listing.firstChild()
+		if fetchError != nil {
+			return CommentThread{}, fetchError
+		}
+		var text string
+		if parent.Body != nil {
+			text = html.UnescapeString(*parent.Body)
+		}
+		thread.Parents = append(thread.Parents, Comment{Author: parent.Author, Score: parent.Score, Text: text})
+		parentID = parent.ParentID
+	}
+	slices.Reverse(thread.Parents)
+	if strings.HasPrefix(comment.LinkID, "t3_") {
+		listing, err := getPost(strings.TrimPrefix(comment.LinkID, "t3_"))
+		if err != nil {
+			return CommentThread{}, &FetchError{
+				NormalError: "Unable to get the post data: " + err.Error(),
+				BotError:    "Unable to get the post data",
+			}
+		}
+		post, fetchError := listing.firstChild()
+		if fetchError != nil {
+			return CommentThread{}, fetchError
+		}
+		if post.Title != nil {
+			thread.PostTitle = html.UnescapeString(*post.Title)
+		}
+	}
+	return thread, nil
+}
+
+// CommentContext gets the context query parameter of the first Reddit link in the text like
+// ?context=3. This is the number of parents which Reddit shows with a comment. Returns false if
+// the link has no valid context parameter.
+func CommentContext(text string) (int, bool) {
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(line, "http://") && !strings.HasPrefix(line, "https://") {
+			line = "https://" + line
+		}
+		u, err := url.Parse(line)
+		if err != nil || (u.Host != "www.reddit.com" && u.Host != "reddit.com" && u.Host != "old.reddit.com") {
+			continue
+		}
+		context, err := strconv.Atoi(u.Query().Get("context"))
+		if err != nil || context < 0 {
+			return 0, false
+		}
+		return context, true
+	}
+	return 0, false
+}
@@ -0,0 +1,114 @@
+package reddit
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCommentThread(t *testing.T) {
+	comments := map[string]string{
+		"c2": `{"data":{"children":[{"kind":"t1","data":{"author":"b","score":5,"body":"second \u0026gt; first","parent_id":"t1_c1","link_id":"t3_p"}}]}}`,
+		"c1": `{"data":{"children":[{"kind":"t1","data":{"author":"a","score":10,"body":"first","parent_id":"t3_p","link_id":"t3_p"}}]}}`,
+	}
+	getComment := func(id string) (listing apiListing[apiComment], err error) {
+		data, ok := comments[id]
+		if !ok {
+			return listing, errors.New("not found")
+		}
+		err = json.Unmarshal([]byte(data), &listing)
+		return
+	}
+	getPost := func(id string) (listing apiListing[apiLink], err error) {
+		assert.Equal(t, "p", id)
+		err = json.Unmarshal([]byte(`{"data":{"children":[{"kind":"t3","data":{"title":"Post &amp; title"}}]}}`), &listing)
+		return
+	}
+	comment := FetchResultComment{Text: "third", Author: "c", Score: 3, ParentID: "t1_c2", LinkID: "t3_p"}
+	tests := []struct {
+		TestName string
+		Depth    int
+		Expected CommentThread
+	}{
+		{
+			TestName: "No context",
+			Depth:    0,
+			Expected: CommentThread{
+				PostTitle: "Post & title",
+				Comment:   Comment{Author: "c", Score: 3, Text: "third"},
+			},
+		},
+		{
+			TestName: "Partial context",
+			Depth:    1,
+			Expected: CommentThread{
+				PostTitle: "Post & title",
+				Parents:   []Comment{{Author: "b", Score: 5, Text: "second > first"}},
+				Comment:   Comment{Author: "c", Score: 3, Text: "third"},
+			},
+		},
+		{
+			TestName: "Stop at the post",
+			Depth:    5,
+			Expected: CommentThread{
+				PostTitle: "Post & title",
+				Parents:   []Comment{{Author: "a", Score: 10, Text: "first"}, {Author: "b", Score: 5, Text: "second > first"}},
+				Comment:   Comment{Author: "c", Score: 3, Text: "third"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			thread, fetchError := getCommentThread(comment, test.Depth, getComment, getPost)
+			assert.Nil(t, fetchError)
+			assert.Equal(t, test.Expected, thread)
+		})
+	}
+	t.Run("Missing parent", func(t *testing.T) {
+		_, fetchError := getCommentThread(FetchResultComment{ParentID: "t1_missing", LinkID: "t3_p"}, 1, getComment, getPost)
+		assert.NotNil(t, fetchError)
+	})
+}
+
+func TestCommentContext(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Text     string
+		Context  int
+		Found    bool
+	}{
+		{
+			TestName: "Context",
+			Text:     "https://www.reddit.com/r/whenthe/comments/wq2fpi/comment/ikkn4sr/?utm_source=share&context=3",
+			Context:  3,
+			Found:    true,
+		},
+		{
+			TestName: "Title line",
+			Text:     "Title\nreddit.com/r/a/comments/b/c/d/?context=1",
+			Context:  1,
+			Found:    true,
+		},
+		{
+			TestName: "No context",
+			Text:     "https://www.reddit.com/r/whenthe/comments/wq2fpi/comment/ikkn4sr/",
+		},
+		{
+			TestName: "Invalid context",
+			Text:     "https://www.reddit.com/r/a/comments/b/c/d/?context=x",
+		},
+		{
+			TestName: "Other host",
+			Text:     "https://example.com/?context=3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			context, found := CommentContext(test.Text)
+			assert.Equal(t, test.Context, context)
+			assert.Equal(t, test.Found, found)
+		})
+	}
+}
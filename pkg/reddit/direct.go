@@ -0,0 +1,291 @@
+package reddit
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/lartie/RedditDownloaderBot/pkg/common"
+	"github.com/lartie/RedditDownloaderBot/pkg/util"
+)
+
+// directMediaExtensions are the extensions of the links which are probed as direct media
+var directMediaExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".mp4": true, ".webm": true,
+}
+
+// directMediaTypes maps the content types of direct media to their media types
+var directMediaTypes = map[string]FetchResultMediaType{
+	"image/jpeg": FetchResultMediaTypePhoto,
+	"image/png":  FetchResultMediaTypePhoto,
+	"image/webp": FetchResultMediaTypePhoto,
+	"video/mp4":  FetchResultMediaTypeVideo,
+	"video/webm": FetchResultMediaTypeVideo,
+}
+
+// directMediaSniffLength is the number of bytes which are requested to sniff the content type
+const directMediaSniffLength = 512
+
+// If not empty, only the direct media of these hosts (and their subdomains) are downloaded
+var directMediaAllowedHosts = parseHostList(os.Getenv("DIRECT_MEDIA_ALLOWED_HOSTS"))
+
+// The direct media of these hosts (and their subdomains) are never downloaded
+var directMediaDeniedHosts = parseHostList(os.Getenv("DIRECT_MEDIA_DENIED_HOSTS"))
+
+// LocalAddressError is returned when a download would connect to a local address
+var LocalAddressError = errors.New("connecting to local addresses is not allowed")
+
+// publicHttpClient is the client which the media are downloaded with. It refuses to connect to the
+// local addresses, even if a public host resolves to them or redirects to them.
+// The proxies of the environment (HTTP_PROXY and HTTPS_PROXY) are still allowed.
+var publicHttpClient = http.Client{
+	Timeout: common.GlobalHttpClient.Timeout,
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           publicDialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	},
+}
+
+// knownMediaHosts are the hosts (and their subdomains) of the media which Reddit and the extractors
+// give. The media of the other hosts come from the direct links of the posts.
+var knownMediaHosts = []string{"reddit.com", "redd.it", "redditmedia.com", "redditstatic.com", "imgur.com",
+	"redgifs.com", "streamable.com"}
+
+// environmentProxyAddresses are the addresses of the proxies of the environment
+var environmentProxyAddresses = getEnvironmentProxyAddresses()
+
+// directMediaProbe is the result of probing a link
+type directMediaProbe struct {
+	ContentType string
+	// The size of the file. -1 if unknown.
+	Size int64
+}
+
+// parseHostList parses a comma-separated list of hosts
+func parseHostList(list string) []string {
+	var hosts []string
+	for _, host := range strings.Split(list, ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// directMedia gets the media of a link to an image or video file on a host which no extractor
+// supports. The link is probed before the download to make sure it's a media which is not too
+// large. base holds the information of the post which is added to the result. ok is false if the
+// link is not a media, its host is not allowed, its size is unknown, or it cannot be probed.
+func (o *Oauth) directMedia(link string, base FetchResultMedia) (fetchResult interface{}, fetchError *FetchError, ok bool) {
+	if !isDirectMediaLink(link) {
+		return nil, nil, false
+	}
+	probe, err := probeDirectMedia(o.downloadHttpClientFor(link), link)
+	if err != nil {
+		return nil, nil, false
+	}
+	if !isDirectMediaType(probe.ContentType) {
+		return nil, nil, false
+	}
+	// The WebM videos must be converted before they are uploaded
+	if probe.ContentType == "video/webm" && !util.DoesFfmpegExists() {
+		return nil, nil, false
+	}
+	// The files with unknown sizes cannot be downloaded anyway, so they are sent as links
+	if probe.Size < 0 {
+		return nil, nil, false
+	}
+	if probe.Size > maxDownloadSize {
+		return nil, &FetchError{
+			NormalError: "",
+			BotError:    "The file is too large to upload on Telegram.\nHere is the link: " + link,
+		}, true
+	}
+	base.Medias = FetchResultMediaEntries{{
+		Link:    link,
+		Quality: "Original",
+		Dim:     Dimension{}, // We cannot get the dimension unless we download it
+	}}
+	base.Type = directMediaTypes[probe.ContentType]
+	return base, nil, true
+}
+
+// downloadHttpClientFor gets the HTTP client which a media is downloaded or probed with. The direct
+// links of the posts are requested with publicHttpClient. The media of the known hosts, and the
+// other links which are not direct links (like the ones of yt-dlp), use the client of httpClientFor.
+func (o *Oauth) downloadHttpClientFor(link string) *http.Client {
+	u, err := url.Parse(link)
+	if err != nil || !directMediaExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return o.httpClientFor(link)
+	}
+	for _, host := range knownMediaHosts {
+		if hostIs(u, host) {
+			return o.httpClientFor(link)
+		}
+	}
+	return &publicHttpClient
+}
+
+// isDirectMediaLink checks if a link looks like a media file which can be downloaded. The link is
+// not probed, so it might not be a media at all.
+func isDirectMediaLink(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") &&
+		directMediaExtensions[strings.ToLower(path.Ext(u.Path))] && directMediaHostAllowed(u)
+}
+
+// directMediaHostAllowed checks if the direct media of the host of a link can be downloaded.
+// The hosts which are local addresses are never allowed. The hosts which resolve to the local
+// addresses are refused by publicHttpClient.
+func directMediaHostAllowed(link *url.URL) bool {
+	host := strings.ToLower(link.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil && isLocalAddress(ip) {
+		return false
+	}
+	for _, denied := range directMediaDeniedHosts {
+		if hostIs(link, denied) {
+			return false
+		}
+	}
+	if len(directMediaAllowedHosts) == 0 {
+		return true
+	}
+	for _, allowed := range directMediaAllowedHosts {
+		if hostIs(link, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalAddress checks if an IP is not reachable from the internet
+func isLocalAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified()
+}
+
+// publicDialContext connects to an address unless it resolves to a local address.
+// The check is done on the resolved IP right before connecting, so it cannot be bypassed with DNS
+// or redirects.
+func publicDialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !environmentProxyAddresses[address] {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isLocalAddress(ip) {
+				return LocalAddressError
+			}
+			return nil
+		}
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// getEnvironmentProxyAddresses gets the host:port of the proxies which are set in the environment
+func getEnvironmentProxyAddresses() map[string]bool {
+	addresses := make(map[string]bool)
+	for _, scheme := range []string{"http", "https"} {
+		proxy, err := http.ProxyFromEnvironment(&http.Request{URL: &url.URL{Scheme: scheme, Host: "example.com"}})
+		if err != nil || proxy == nil {
+			continue
+		}
+		port := proxy.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxy.Scheme]
+		}
+		addresses[net.JoinHostPort(proxy.Hostname(), port)] = true
+	}
+	return addresses
+}
+
+// probeDirectMedia gets the content type and the size of a link. The content type and size are
+// read from the headers of a HEAD request. If the server does not give them, the first bytes of
+// the file are requested with a range request and the content type is sniffed from them.
+func probeDirectMedia(client *http.Client, link string) (directMediaProbe, error) {
+	probe := directMediaProbe{Size: -1}
+	req, err := http.NewRequest(http.MethodHead, link, nil)
+	if err != nil {
+		return probe, errors.Wrap(err, "cannot create request")
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err == nil {
+		_ = resp.Body.Close()
+		if resp.StatusCode/100 == 2 {
+			probe.ContentType = mediaContentType(resp.Header.Get("Content-Type"))
+			probe.Size = resp.ContentLength
+		}
+	}
+	if isDirectMediaType(probe.ContentType) && probe.Size >= 0 {
+		return probe, nil
+	}
+	// Some servers do not answer HEAD requests correctly
+	req, err = http.NewRequest(http.MethodGet, link, nil)
+	if err != nil {
+		return probe, errors.Wrap(err, "cannot create request")
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Range", "bytes=0-"+strconv.Itoa(directMediaSniffLength-1))
+	resp, err = client.Do(req)
+	if err != nil {
+		return probe, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// Content-Range: bytes 0-511/12345
+		contentRange := resp.Header.Get("Content-Range")
+		if i := strings.LastIndexByte(contentRange, '/'); i != -1 {
+			if size, err := strconv.ParseInt(contentRange[i+1:], 10, 64); err == nil {
+				probe.Size = size
+			}
+		}
+	case http.StatusOK:
+		// The server does not support ranges, so the whole file is in the body
+		probe.Size = resp.ContentLength
+	default:
+		return probe, errors.New("non 2xx status: " + resp.Status)
+	}
+	head, err := io.ReadAll(io.LimitReader(resp.Body, directMediaSniffLength))
+	if err != nil {
+		return probe, errors.Wrap(err, "cannot read the file")
+	}
+	if contentType := mediaContentType(resp.Header.Get("Content-Type")); isDirectMediaType(contentType) {
+		probe.ContentType = contentType
+	} else {
+		probe.ContentType = mediaContentType(http.DetectContentType(head))
+	}
+	return probe, nil
+}
+
+// mediaContentType removes the parameters of a content type
+func mediaContentType(contentType string) string {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// isDirectMediaType checks if a content type is one of directMediaTypes
+func isDirectMediaType(contentType string) bool {
+	_, ok := directMediaTypes[contentType]
+	return ok
+}
@@ -0,0 +1,183 @@
+package reddit
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lartie/RedditDownloaderBot/pkg/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// The beginning of the files which are sniffed
+var (
+	pngHeader  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	mp4Header  = []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom")
+	webmHeader = []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01webm")
+)
+
+func TestDirectMediaHostAllowed(t *testing.T) {
+	oldAllowed, oldDenied := directMediaAllowedHosts, directMediaDeniedHosts
+	defer func() { directMediaAllowedHosts, directMediaDeniedHosts = oldAllowed, oldDenied }()
+	tests := []struct {
+		TestName string
+		Allowed  string
+		Denied   string
+		Link     string
+		Expected bool
+	}{
+		{TestName: "Any host", Link: "https://example.com/a.jpg", Expected: true},
+		{TestName: "Localhost", Link: "http://localhost:8080/a.jpg"},
+		{TestName: "Loopback", Link: "http://127.0.0.1/a.jpg"},
+		{TestName: "Private", Link: "http://192.168.1.1/a.jpg"},
+		{TestName: "Private IPv6", Link: "http://[fd00::1]/a.jpg"},
+		{TestName: "Public IP", Link: "http://93.184.216.34/a.jpg", Expected: true},
+		{TestName: "Denied", Denied: "example.com", Link: "https://cdn.example.com/a.jpg"},
+		{TestName: "Not denied", Denied: "example.com", Link: "https://example.org/a.jpg", Expected: true},
+		{TestName: "Allowed", Allowed: "example.com, example.org", Link: "https://example.org/a.jpg", Expected: true},
+		{TestName: "Not allowed", Allowed: "example.com", Link: "https://example.org/a.jpg"},
+		{TestName: "Allowed and denied", Allowed: "example.com", Denied: "cdn.example.com", Link: "https://cdn.example.com/a.jpg"},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			directMediaAllowedHosts = parseHostList(test.Allowed)
+			directMediaDeniedHosts = parseHostList(test.Denied)
+			link, err := url.Parse(test.Link)
+			assert.NoError(t, err)
+			assert.Equal(t, test.Expected, directMediaHostAllowed(link))
+		})
+	}
+}
+
+func TestProbeDirectMedia(t *testing.T) {
+	bigVideo := append(append([]byte{}, mp4Header...), make([]byte, 2048)...)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(pngHeader))
+		case "/octet.mp4":
+			// The content type must be sniffed
+			w.Header().Set("Content-Type", "application/octet-stream")
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(bigVideo))
+		case "/no-head.mp4":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "video/mp4; codecs=avc1")
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(bigVideo))
+		case "/page.jpg":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte("<html><body>Not an image</body></html>"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	tests := []struct {
+		Path     string
+		Expected directMediaProbe
+	}{
+		{"/image.png", directMediaProbe{ContentType: "image/png", Size: int64(len(pngHeader))}},
+		{"/octet.mp4", directMediaProbe{ContentType: "video/mp4", Size: int64(len(bigVideo))}},
+		{"/no-head.mp4", directMediaProbe{ContentType: "video/mp4", Size: int64(len(bigVideo))}},
+		{"/page.jpg", directMediaProbe{ContentType: "text/html", Size: int64(len("<html><body>Not an image</body></html>"))}},
+	}
+	for _, test := range tests {
+		t.Run(strings.TrimPrefix(test.Path, "/"), func(t *testing.T) {
+			probe, err := probeDirectMedia(server.Client(), server.URL+test.Path)
+			assert.NoError(t, err)
+			assert.Equal(t, test.Expected, probe)
+		})
+	}
+	_, err := probeDirectMedia(server.Client(), server.URL+"/deleted.jpg")
+	assert.Error(t, err)
+}
+
+func TestDirectMedia(t *testing.T) {
+	// Not probed
+	for _, link := range []string{
+		"https://example.com/page.html",
+		"ftp://example.com/a.jpg",
+		"http://127.0.0.1/a.jpg",
+	} {
+		_, _, ok := (&Oauth{}).directMedia(link, FetchResultMedia{})
+		assert.False(t, ok, link)
+	}
+}
+
+func TestPublicHttpClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(pngHeader)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	for _, link := range []string{
+		server.URL,
+		// A host name which resolves to a local address
+		"http://localhost:" + serverURL.Port(),
+	} {
+		_, err := publicHttpClient.Get(link)
+		assert.ErrorIs(t, err, LocalAddressError, link)
+	}
+	// The proxies of the environment are allowed
+	environmentProxyAddresses[serverURL.Host] = true
+	defer delete(environmentProxyAddresses, serverURL.Host)
+	resp, err := publicHttpClient.Get(server.URL)
+	if assert.NoError(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestDownloadHttpClientFor(t *testing.T) {
+	proxyClient := &http.Client{}
+	oauth := &Oauth{imgurHTTPClient: proxyClient}
+	tests := []struct {
+		TestName string
+		Link     string
+		Expected *http.Client
+	}{
+		{"Direct Link", "https://example.com/a.jpg", &publicHttpClient},
+		{"Direct Link Upper Case", "https://example.com/a.MP4", &publicHttpClient},
+		{"Reddit", "https://i.redd.it/a.jpg", &common.GlobalHttpClient},
+		{"Reddit Preview", "https://preview.redd.it/a.png?width=108", &common.GlobalHttpClient},
+		{"Imgur", "https://i.imgur.com/a.jpg", proxyClient},
+		{"Redgifs", "https://media.redgifs.com/A.mp4", &common.GlobalHttpClient},
+		{"Not Direct Link", "https://rr1.googlevideo.com/videoplayback?id=a", &common.GlobalHttpClient},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Same(t, test.Expected, oauth.downloadHttpClientFor(test.Link))
+		})
+	}
+}
+
+func TestIsWebmFile(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Content  []byte
+		Expected bool
+	}{
+		{"WebM", webmHeader, true},
+		{"MP4", mp4Header, false},
+		{"Short", webmHeader[:2], false},
+		{"Empty", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			f, err := os.CreateTemp(t.TempDir(), "*.video")
+			assert.NoError(t, err)
+			defer f.Close()
+			_, err = f.Write(test.Content)
+			assert.NoError(t, err)
+			assert.Equal(t, test.Expected, isWebmFile(f))
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"strconv"
 
 	"github.com/go-faster/errors"
@@ -28,7 +29,7 @@ func (o *Oauth) DownloadPhoto(link string) (*os.File, error) {
 		if err != nil {
 			return nil, err
 		}
-		fileName = u.Path[1:]
+		fileName = path.Base(u.Path)
 	}
 	// Generate a temp file
 	tmpFile, err := os.CreateTemp("", "*."+fileName)
@@ -47,7 +48,12 @@ func (o *Oauth) DownloadPhoto(link string) (*os.File, error) {
 
 // DownloadVideo downloads a video from reddit
 // If necessary, it will merge the audio and video with ffmpeg
+// The WebM videos are converted to MP4 with ffmpeg, because Telegram does not play them.
+// The videos of the yt-dlp extractor are downloaded with yt-dlp.
 func (o *Oauth) DownloadVideo(vidUrl, audioUrl string) (videoFile *os.File, err error) {
+	if link, format, ok := parseYtdlpLink(vidUrl); ok {
+		return downloadWithYtdlp(link, format)
+	}
 	// Download the video in a temp file
 	videoFile, err = os.CreateTemp("", "*.mp4")
 	if err != nil {
@@ -66,6 +72,16 @@ func (o *Oauth) DownloadVideo(vidUrl, audioUrl string) (videoFile *os.File, err
 		err = errors.Wrap(err, "Unable to download the file")
 		return
 	}
+	if isWebmFile(videoFile) {
+		var convertedFile *os.File
+		convertedFile, err = convertWebmToMp4(videoFile)
+		if err != nil {
+			return
+		}
+		_ = videoFile.Close()
+		_ = os.Remove(videoFile.Name())
+		videoFile = convertedFile
+	}
 	// Otherwise, search for an audio file
 	hasAudio := audioUrl != ""
 	audFile, err := os.CreateTemp("", "*.mp4")
@@ -124,6 +140,49 @@ func (o *Oauth) DownloadVideo(vidUrl, audioUrl string) (videoFile *os.File, err
 	return videoFile, nil
 }
 
+// webmMagic is the first bytes of the WebM (Matroska) files
+var webmMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+// isWebmFile checks if a file is a WebM video by its first bytes
+func isWebmFile(f *os.File) bool {
+	head := make([]byte, len(webmMagic))
+	n, _ := f.ReadAt(head, 0)
+	return bytes.Equal(head[:n], webmMagic)
+}
+
+// convertWebmToMp4 transcodes a WebM video to an H.264 and AAC video which Telegram can play.
+// The result is a new temp file. If the result is too large to upload, FileTooBigError is returned.
+func convertWebmToMp4(webmFile *os.File) (*os.File, error) {
+	if !util.DoesFfmpegExists() {
+		return nil, errors.New("ffmpeg is needed to convert WebM videos")
+	}
+	finalFile, err := os.CreateTemp("", "*.mp4")
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create a temporary file for the converted video")
+	}
+	cmd := exec.Command("ffmpeg",
+		"-i", webmFile.Name(),
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-movflags", "+faststart",
+		finalFile.Name(), "-y")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		_ = finalFile.Close()
+		_ = os.Remove(finalFile.Name())
+		return nil, errors.Wrap(errors.New(stderr.String()), "Unable to convert the WebM video")
+	}
+	if stat, err := finalFile.Stat(); err != nil || stat.Size() > maxDownloadSize {
+		_ = finalFile.Close()
+		_ = os.Remove(finalFile.Name())
+		return nil, FileTooBigError
+	}
+	return finalFile, nil
+}
+
 // DownloadGif downloads a gif from reddit
 func (o *Oauth) DownloadGif(link string) (*os.File, error) {
 	tmpFile, err := os.CreateTemp("", "*.mp4")
@@ -0,0 +1,146 @@
+package reddit
+
+import (
+	"context"
+	"github.com/go-faster/errors"
+	"github.com/lartie/RedditDownloaderBot/pkg/common"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Extractor gets the media of the links to a host other than Reddit. To support a new host, add a
+// file with its extractor and add the extractor to extractors.
+type Extractor interface {
+	// Match checks if the extractor can get the media of a link
+	Match(link *url.URL) bool
+	// Extract gets the media of a link which Match has matched. The title, description and metadata
+	// of the post are filled by the caller; so are the thumbnails if the result does not have them.
+	// The errors which should be shown to the user can be returned as *FetchError.
+	Extract(ctx context.Context, request ExtractRequest) (FetchResultMedia, error)
+}
+
+// AlbumExtractor is an Extractor of the links which can have more than one media. If the matched
+// extractor implements it, ExtractAlbum is used instead of Extract. The albums which have only one
+// media are sent like the other media.
+type AlbumExtractor interface {
+	Extractor
+	// ExtractAlbum gets the media of a link which Match has matched in order
+	ExtractAlbum(ctx context.Context, request ExtractRequest) ([]FetchResultAlbumEntry, error)
+}
+
+// ExtractRequest is a link which an Extractor gets the media of
+type ExtractRequest struct {
+	// The link of the media
+	URL string
+	// The link of the Reddit post which links to URL. Only used in the errors.
+	PostURL string
+	// The preview which Reddit has made of the media. Might be nil.
+	Preview *apiPreview
+	// The path of the post in the JSON of Reddit. Used to report the missing fields of Preview.
+	Path string
+	// The HTTP client which URL must be requested with. Might be nil.
+	Client *http.Client
+}
+
+// httpClient gets the HTTP client which the URL of the request must be requested with
+func (r ExtractRequest) httpClient() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return &common.GlobalHttpClient
+}
+
+// extractors are the extractors which the links are checked against in order. The first one which
+// matches a link is used, so yt-dlp, which can get the media of many hosts, is the last one.
+var extractors = []Extractor{
+	gfycatExtractor{},
+	imgurExtractor{},
+	newImgurAlbumExtractor(),
+	&redgifsExtractor{apiPoint: redgifsApiPoint},
+	streamableExtractor{},
+	newYtdlpExtractor(),
+}
+
+// findExtractor finds the first registered extractor which matches a link. Returns nil if there is none.
+func findExtractor(link string) Extractor {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil
+	}
+	for _, extractor := range extractors {
+		if extractor.Match(u) {
+			return extractor
+		}
+	}
+	return nil
+}
+
+// extractMedia gets the media of a link with the extractor which matches it. base holds the
+// information of the post (title, description and thumbnails) which is added to the result.
+// The result is a FetchResultAlbum if the link is an album with more than one media; otherwise,
+// it's a FetchResultMedia. ok is false if no extractor matches the link.
+func (o *Oauth) extractMedia(request ExtractRequest, base FetchResultMedia) (fetchResult interface{}, fetchError *FetchError, ok bool) {
+	extractor := findExtractor(request.URL)
+	if extractor == nil {
+		return nil, nil, false
+	}
+	request.Client = o.httpClientFor(request.URL)
+	var media FetchResultMedia
+	var err error
+	if albumExtractor, isAlbum := extractor.(AlbumExtractor); isAlbum {
+		var album []FetchResultAlbumEntry
+		album, err = albumExtractor.ExtractAlbum(context.Background(), request)
+		if err == nil && len(album) == 0 {
+			err = errors.New("the album is empty")
+		}
+		if err == nil && len(album) > 1 {
+			return FetchResultAlbum{
+				Album:       album,
+				Title:       base.Title,
+				Description: base.Description,
+			}, nil, true
+		}
+		if err == nil {
+			media = albumEntryMedia(album[0])
+		}
+	} else {
+		media, err = extractor.Extract(context.Background(), request)
+	}
+	if err != nil {
+		if !errors.As(err, &fetchError) {
+			fetchError = &FetchError{
+				NormalError: "Unable to extract " + request.URL + " of " + request.PostURL + ": " + err.Error(),
+				BotError:    "Unable to get the media.\nHere is the link: " + request.URL,
+			}
+		}
+		return nil, fetchError, true
+	}
+	base.Medias = media.Medias
+	base.Duration = media.Duration
+	base.Type = media.Type
+	// Some hosts only have NSFW media
+	base.NSFW = media.NSFW
+	if len(media.ThumbnailLinks) != 0 {
+		base.ThumbnailLinks = media.ThumbnailLinks
+	}
+	return base, nil, true
+}
+
+// albumEntryMedia converts a media of an album to a FetchResultMedia which only has the original quality
+func albumEntryMedia(entry FetchResultAlbumEntry) FetchResultMedia {
+	return FetchResultMedia{
+		Medias: FetchResultMediaEntries{{
+			Link:    entry.Link,
+			Quality: "Original",
+			Dim:     Dimension{}, // The albums do not have the dimensions
+		}},
+		Type: entry.Type,
+	}
+}
+
+// hostIs checks if the host of a link is domain or one of its subdomains
+func hostIs(link *url.URL, domain string) bool {
+	host := strings.ToLower(link.Hostname())
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,140 @@
+package reddit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExtractor matches the links of a host and returns result or err
+type fakeExtractor struct {
+	host   string
+	result FetchResultMedia
+	err    error
+}
+
+func (e fakeExtractor) Match(link *url.URL) bool {
+	return link.Hostname() == e.host
+}
+
+func (e fakeExtractor) Extract(context.Context, ExtractRequest) (FetchResultMedia, error) {
+	return e.result, e.err
+}
+
+// fakeAlbumExtractor matches the links of a host and returns album. The client of the last
+// request is written to client.
+type fakeAlbumExtractor struct {
+	fakeExtractor
+	album  []FetchResultAlbumEntry
+	client **http.Client
+}
+
+func (e fakeAlbumExtractor) ExtractAlbum(_ context.Context, request ExtractRequest) ([]FetchResultAlbumEntry, error) {
+	*e.client = request.Client
+	return e.album, e.err
+}
+
+func TestFindExtractor(t *testing.T) {
+	assert.IsType(t, imgurExtractor{}, findExtractor("https://i.imgur.com/a.gifv"))
+	assert.IsType(t, streamableExtractor{}, findExtractor("https://streamable.com/u2jzoo"))
+	assert.IsType(t, gfycatExtractor{}, findExtractor("https://gfycat.com/a"))
+	assert.Nil(t, findExtractor("https://www.youtube.com/watch?v=a"))
+	assert.Nil(t, findExtractor("https://i.imgur.com/a.jpg"))
+}
+
+func TestExtractMedia(t *testing.T) {
+	oldExtractors := extractors
+	defer func() { extractors = oldExtractors }()
+	extractors = append(extractors, fakeExtractor{host: "media.example", result: FetchResultMedia{
+		Medias: FetchResultMediaEntries{{Link: "https://media.example/a.mp4", Quality: "Original"}},
+		Type:   FetchResultMediaTypeVideo,
+	}})
+	extractors = append(extractors, fakeExtractor{host: "broken.example", err: errors.New("broken")})
+	extractors = append(extractors, fakeExtractor{host: "error.example", err: &FetchError{BotError: "bot error"}})
+	base := FetchResultMedia{
+		ThumbnailLinks: FetchedThumbnails{{Link: "https://preview.redd.it/a.jpg"}},
+		Title:          "title",
+		Description:    "description",
+	}
+	// The result has the information of the post
+	result, fetchError, ok := (&Oauth{}).extractMedia(ExtractRequest{URL: "https://media.example/a"}, base)
+	assert.True(t, ok)
+	assert.Nil(t, fetchError)
+	assert.Equal(t, FetchResultMedia{
+		Medias:         FetchResultMediaEntries{{Link: "https://media.example/a.mp4", Quality: "Original"}},
+		ThumbnailLinks: FetchedThumbnails{{Link: "https://preview.redd.it/a.jpg"}},
+		Title:          "title",
+		Description:    "description",
+		Type:           FetchResultMediaTypeVideo,
+	}, result)
+	// Other errors are wrapped
+	result, fetchError, ok = (&Oauth{}).extractMedia(ExtractRequest{URL: "https://broken.example/a"}, base)
+	assert.True(t, ok)
+	assert.Nil(t, result)
+	assert.Equal(t, "Unable to get the media.\nHere is the link: https://broken.example/a", fetchError.BotError)
+	// Fetch errors are returned as they are
+	_, fetchError, ok = (&Oauth{}).extractMedia(ExtractRequest{URL: "https://error.example/a"}, base)
+	assert.True(t, ok)
+	assert.Equal(t, &FetchError{BotError: "bot error"}, fetchError)
+	// No extractor
+	_, _, ok = (&Oauth{}).extractMedia(ExtractRequest{URL: "https://other.example/a"}, base)
+	assert.False(t, ok)
+}
+
+func TestExtractMediaAlbum(t *testing.T) {
+	oldExtractors := extractors
+	defer func() { extractors = oldExtractors }()
+	var client *http.Client
+	photo := FetchResultAlbumEntry{Link: "https://i.imgur.com/a.jpeg", Caption: "a", Type: FetchResultMediaTypePhoto}
+	gif := FetchResultAlbumEntry{Link: "https://i.imgur.com/b.mp4", Type: FetchResultMediaTypeGif}
+	extractors = append(extractors, fakeAlbumExtractor{
+		fakeExtractor: fakeExtractor{host: "imgur.com"},
+		album:         []FetchResultAlbumEntry{photo, gif},
+		client:        &client,
+	})
+	extractors = append(extractors, fakeAlbumExtractor{
+		fakeExtractor: fakeExtractor{host: "single.example"},
+		album:         []FetchResultAlbumEntry{gif},
+		client:        &client,
+	})
+	extractors = append(extractors, fakeAlbumExtractor{
+		fakeExtractor: fakeExtractor{host: "empty.example"},
+		client:        &client,
+	})
+	base := FetchResultMedia{
+		ThumbnailLinks: FetchedThumbnails{{Link: "https://preview.redd.it/a.jpg"}},
+		Title:          "title",
+		Description:    "description",
+	}
+	// The Imgur links are requested through the proxy
+	proxyClient := &http.Client{}
+	result, fetchError, ok := (&Oauth{imgurHTTPClient: proxyClient}).extractMedia(ExtractRequest{URL: "https://imgur.com/a/abc"}, base)
+	assert.True(t, ok)
+	assert.Nil(t, fetchError)
+	assert.Equal(t, FetchResultAlbum{
+		Album:       []FetchResultAlbumEntry{photo, gif},
+		Title:       "title",
+		Description: "description",
+	}, result)
+	assert.Same(t, proxyClient, client)
+	// The albums with one media are sent like the other media
+	result, fetchError, ok = (&Oauth{imgurHTTPClient: proxyClient}).extractMedia(ExtractRequest{URL: "https://single.example/a"}, base)
+	assert.True(t, ok)
+	assert.Nil(t, fetchError)
+	assert.Equal(t, FetchResultMedia{
+		Medias:         FetchResultMediaEntries{{Link: "https://i.imgur.com/b.mp4", Quality: "Original"}},
+		ThumbnailLinks: FetchedThumbnails{{Link: "https://preview.redd.it/a.jpg"}},
+		Title:          "title",
+		Description:    "description",
+		Type:           FetchResultMediaTypeGif,
+	}, result)
+	assert.NotSame(t, proxyClient, client)
+	// Empty
+	_, fetchError, ok = (&Oauth{}).extractMedia(ExtractRequest{URL: "https://empty.example/a"}, base)
+	assert.True(t, ok)
+	assert.Equal(t, "Unable to get the media.\nHere is the link: https://empty.example/a", fetchError.BotError)
+}
@@ -1,7 +1,6 @@
 package reddit
 
 import (
-	"github.com/lartie/RedditDownloaderBot/pkg/common"
 	"github.com/lartie/RedditDownloaderBot/pkg/util"
 	"fmt"
 	"html"
@@ -10,8 +9,9 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/PuerkitoBio/goquery"
+	"github.com/go-faster/errors"
 )
 
 // If this variable is true, it means that we don't allow nsfw posts to be downloaded
@@ -23,6 +23,9 @@ var nsfwNotAllowedErr = &FetchError{
 	BotError:    "NSFW posts are disabled.",
 }
 
+// pollFetchCacheTTL is the maximum time which the results of the polls which have not ended are cached
+const pollFetchCacheTTL = time.Minute
+
 var giphyCommentRegex = regexp.MustCompile(`!\[gif]\(giphy\|(\w+)(?:\|downsized)?\)`)
 
 // StartFetch gets the post info from url
@@ -31,21 +34,23 @@ var giphyCommentRegex = regexp.MustCompile(`!\[gif]\(giphy\|(\w+)(?:\|downsized)
 // FetchResultComment
 // FetchResultMedia
 // FetchResultAlbum
+// FetchResultPoll
 func (o *Oauth) StartFetch(postUrl string) (fetchResult interface{}, realPostUrl string, fetchError *FetchError) {
-	// Don't crash the whole application
-	defer func() {
-		if r := recover(); r != nil {
-			fetchError = &FetchError{
-				NormalError: fmt.Sprintf("Recovering from panic in StartFetch. Error encountered: %v; URL: %v", r, postUrl),
-				BotError:    "Unable to get the data.\nMaybe a deleted post or invalid URL?",
-			}
-		}
-	}()
 	// Get the post ID
 	postId, realPostUrl, isComment, fetchError := o.getPostID(postUrl)
 	if fetchError != nil {
 		return
 	}
+	// Check the cache
+	cacheKey := fetchResultCacheKey(postId, isComment)
+	if cached, ok := o.getCachedFetchResult(cacheKey); ok {
+		return cached.Result(), cached.RealPostUrl, nil
+	}
+	defer func() {
+		if fetchError == nil {
+			o.cacheFetchResult(cacheKey, fetchResult, realPostUrl)
+		}
+	}()
 	if isComment {
 		root, err := o.GetComment(postId)
 		if err != nil {
@@ -54,7 +59,8 @@ func (o *Oauth) StartFetch(postUrl string) (fetchResult interface{}, realPostUrl
 				BotError:    "Unable to fetch the comment",
 			}
 		}
-		return getCommentFromRoot(root), realPostUrl, nil
+		fetchResult, fetchError = getCommentFromRoot(root)
+		return fetchResult, realPostUrl, fetchError
 	}
 	// Now download the json
 	root, err := o.GetPost(postId)
@@ -65,10 +71,54 @@ func (o *Oauth) StartFetch(postUrl string) (fetchResult interface{}, realPostUrl
 		}
 		return
 	}
-	fetchResult, fetchError = getPost(postUrl, root)
+	fetchResult, fetchError = o.getPost(postUrl, root)
 	return
 }
 
+// fetchResultCacheKey gets the key of a post or comment in the fetch result cache.
+// This is the fullname of it in reddit.
+func fetchResultCacheKey(id string, isComment bool) string {
+	if isComment {
+		return "t1_" + id
+	}
+	return "t3_" + id
+}
+
+// getCachedFetchResult gets a result of StartFetch from the cache if it exists
+func (o *Oauth) getCachedFetchResult(key string) (CachedFetchResult, bool) {
+	if o.fetchCache == nil {
+		return CachedFetchResult{}, false
+	}
+	cached, err := o.fetchCache.GetFetchResultCache(key)
+	if err != nil || cached.Result() == nil {
+		return CachedFetchResult{}, false
+	}
+	return cached, true
+}
+
+// cacheFetchResult stores a result of StartFetch in the cache if it's enabled
+func (o *Oauth) cacheFetchResult(key string, result interface{}, realPostUrl string) {
+	if o.fetchCache == nil {
+		return
+	}
+	cached, ok := NewCachedFetchResult(result, realPostUrl)
+	if !ok {
+		return
+	}
+	if err := o.fetchCache.SetFetchResultCache(key, cached, o.fetchResultCacheTTL(result)); err != nil {
+		log.Println("Cannot set the fetch result cache:", err)
+	}
+}
+
+// fetchResultCacheTTL gets the time which a result of StartFetch is cached for. The votes of the
+// open polls change, so they are cached for at most pollFetchCacheTTL.
+func (o *Oauth) fetchResultCacheTTL(result interface{}) time.Duration {
+	if poll, isPoll := result.(FetchResultPoll); isPoll && !poll.Ended() && o.fetchCacheTTL > pollFetchCacheTTL {
+		return pollFetchCacheTTL
+	}
+	return o.fetchCacheTTL
+}
+
 // Gets the post ID from a post URL.
 // If you use this function, pass false for secondPass.
 func (o *Oauth) getPostID(postUrl string) (postID, realPostUrl string, isComment bool, err *FetchError) {
@@ -153,9 +203,16 @@ func (o *Oauth) getPostID(postUrl string) (postID, realPostUrl string, isComment
 
 // getCommentFromRoot gets the comment content from root of the JSON API.
 // The result is either a FetchResultMedia with gif type or FetchResultComment
-func getCommentFromRoot(root map[string]interface{}) interface{} {
+func getCommentFromRoot(root apiListing[apiComment]) (interface{}, *FetchError) {
+	comment, fetchError := root.firstChild()
+	if fetchError != nil {
+		return nil, fetchError
+	}
+	if comment.Body == nil {
+		return nil, missingFieldError("data->children[0]->data->body")
+	}
 	// Check gif comments
-	text := root["data"].(map[string]interface{})["children"].([]interface{})[0].(map[string]interface{})["data"].(map[string]interface{})["body"].(string)
+	text := html.UnescapeString(*comment.Body)
 	if matches := giphyCommentRegex.FindStringSubmatch(text); len(matches) == 2 {
 		return FetchResultMedia{
 			Medias: []FetchResultMediaEntry{{
@@ -165,10 +222,16 @@ func getCommentFromRoot(root map[string]interface{}) interface{} {
 			}},
 			Type:  FetchResultMediaTypeGif,
 			Title: strings.ReplaceAll(text, matches[0], ""),
-		}
+		}, nil
 	}
 	// Normal comment
-	return FetchResultComment{text}
+	return FetchResultComment{
+		Text:     text,
+		Author:   comment.Author,
+		Score:    comment.Score,
+		ParentID: comment.ParentID,
+		LinkID:   comment.LinkID,
+	}, nil
 }
 
 // getPost will get the post from the parsed root API.
@@ -176,88 +239,87 @@ func getCommentFromRoot(root map[string]interface{}) interface{} {
 // FetchResultText
 // FetchResultMedia
 // FetchResultAlbum
+// FetchResultPoll
 //
 // This function is seperated from Oauth.StartFetch to write tests for it
-func getPost(postUrl string, root map[string]interface{}) (fetchResult interface{}, fetchError *FetchError) {
+func (o *Oauth) getPost(postUrl string, listing apiListing[apiLink]) (fetchResult interface{}, fetchError *FetchError) {
 	// Get post type
 	// To do so, I check data->children[0]->data->post_hint
-	{
-		data, exists := root["data"]
-		if !exists {
-			fetchError = &FetchError{
-				NormalError: "Unable to parse the page data: couldn’t find node `data`",
-				BotError:    "Unable to parse the page data: couldn’t find node `data`",
-			}
-			return
-		}
-		children, exists := data.(map[string]interface{})["children"]
-		if !exists {
-			fetchError = &FetchError{
-				NormalError: "Unable to parse the page data: couldn’t find node `data->children`",
-				BotError:    "Unable to parse the page data: couldn’t find node `data->children`",
-			}
-			return
-		}
-		data = children.([]interface{})[0]
-		data, exists = data.(map[string]interface{})["data"]
-		if !exists {
-			fetchError = &FetchError{
-				NormalError: "Unable to parse the page data: couldn’t find node `data->children[0]->data`",
-				BotError:    "Unable to parse the page data: couldn’t find node `data->children[0]->data`",
-			}
-			return
-		}
-		root = data.(map[string]interface{})
+	root, fetchError := listing.firstChild()
+	if fetchError != nil {
+		return
 	}
+	// Add the information about the post (and not the crossposted one) to the media
+	metadata := getPostMetadata(root)
+	flags := postFlags{NSFW: root.Over18, Spoiler: root.Spoiler}
+	defer func() {
+		fetchResult = withPostInfo(fetchResult, metadata, flags)
+	}()
+	// The path of root in the JSON. Used to report the missing fields.
+	path := "data->children[0]->data"
 	// Check if the post is nsfw and bot forbids them
-	nsfw, _ := root["over_18"].(bool)
-	if denyNsfw && nsfw {
+	if denyNsfw && root.Over18 {
 		return nil, nsfwNotAllowedErr
 	}
 	// Get the title
-	title := root["title"].(string)
-	title = html.UnescapeString(title)
+	if root.Title == nil {
+		return nil, missingFieldError(path + "->title")
+	}
+	title := html.UnescapeString(*root.Title)
 	title = strings.TrimSpace(title)
 	// Get the description (selftext) if it exists
-	description, _ := root["selftext"].(string)
-	description = strings.TrimSpace(description)
+	var description string
+	if root.Selftext != nil {
+		description = strings.TrimSpace(*root.Selftext)
+	}
 	// Check thumbnail; This must be done before checking cross posts
 	thumbnails := extractThumbnails(root)
 	// Check cross post
-	if _, crossPost := root["crosspost_parent_list"]; crossPost {
-		c := root["crosspost_parent_list"].([]interface{})
-		if len(c) != 0 {
-			root = c[0].(map[string]interface{})
-		}
+	if len(root.CrosspostParentList) != 0 {
+		root = &root.CrosspostParentList[0]
+		path += "->crosspost_parent_list[0]"
+		// The crossposted post might be marked while the crosspost is not
+		flags.NSFW = flags.NSFW || root.Over18
+		flags.Spoiler = flags.Spoiler || root.Spoiler
+	}
+	// Polls are text posts with poll_data
+	if root.PollData != nil {
+		return getPoll(*root.PollData, path+"->poll_data", title, description)
 	}
 	// Check it
-	if hint, exists := root["post_hint"]; exists {
-		switch hint.(string) {
+	if root.PostHint != nil {
+		switch *root.PostHint {
 		case "image": // image or gif
+			if root.URL == nil {
+				return nil, missingFieldError(path + "->url")
+			}
+			link := *root.URL
 			result := FetchResultMedia{
 				ThumbnailLinks: thumbnails,
 				Title:          title,
 				Description:    description,
 			}
-			if root["url"].(string)[len(root["url"].(string))-3:] == "gif" {
+			image, fetchError := firstPreviewImage(root, path)
+			if strings.HasSuffix(link, "gif") {
+				// Check the hosts which have the original gif (like Imgur)
+				if media, fetchError, ok := o.extractMedia(ExtractRequest{URL: link, PostURL: postUrl, Preview: root.Preview, Path: path}, result); ok {
+					return media, fetchError
+				}
 				result.Type = FetchResultMediaTypeGif
-				// Check imgur gifs
-				if strings.HasPrefix(root["url"].(string), "https://i.imgur.com") { // Example: https://www.reddit.com/r/dankmemes/comments/gag117/you_daughter_of_a_bitch_im_in/
-					gifDownloadUrl := root["url"].(string)
-					lastSlash := strings.LastIndex(gifDownloadUrl, "/")
-					gifDownloadUrl = gifDownloadUrl[:lastSlash+1] + "download" + gifDownloadUrl[lastSlash:]
-					result.Medias = []FetchResultMediaEntry{{
-						Link:    gifDownloadUrl,
-						Quality: "Imgur",     // It doesn't matter
-						Dim:     Dimension{}, // We cannot get the dimension unless we download it
-					}}
-				} else {
-					result.Medias = extractPhotoGifQualities(root["preview"].(map[string]interface{})["images"].([]interface{})[0].(map[string]interface{})["variants"].(map[string]interface{})["mp4"].(map[string]interface{}))
+				if fetchError != nil {
+					return nil, fetchError
+				}
+				if image.Variants.MP4 == nil {
+					return nil, missingFieldError(path + "->preview->images[0]->variants->mp4")
+				}
+				result.Medias, fetchError = extractPhotoGifQualities(*image.Variants.MP4, path+"->preview->images[0]->variants->mp4")
+				if fetchError != nil {
+					return nil, fetchError
 				}
 			} else {
 				result.Type = FetchResultMediaTypePhoto
 				// Send the original file as well if it's on reddit or imgur
-				if link, ok := root["url"].(string); ok && (strings.HasPrefix(link, "https://i.redd.it/") || strings.HasPrefix(link, "https://i.imgur.com/")) {
+				if strings.HasPrefix(link, "https://i.redd.it/") || strings.HasPrefix(link, "https://i.imgur.com/") {
 					result.Medias = []FetchResultMediaEntry{
 						{
 							Link:    link,
@@ -266,145 +328,91 @@ func getPost(postUrl string, root map[string]interface{}) (fetchResult interface
 						},
 					}
 				}
-				result.Medias = append(result.Medias, extractPhotoGifQualities(root["preview"].(map[string]interface{})["images"].([]interface{})[0].(map[string]interface{}))...)
+				if fetchError != nil {
+					return nil, fetchError
+				}
+				qualities, fetchError := extractPhotoGifQualities(image, path+"->preview->images[0]")
+				if fetchError != nil {
+					return nil, fetchError
+				}
+				result.Medias = append(result.Medias, qualities...)
 			}
 			return result, nil
 		case "link": // link
-			u := root["url"].(string)
-			if strings.HasSuffix(u, ".gifv") && strings.HasPrefix(u, "https://i.imgur.com") { // imgur gif
-				return FetchResultMedia{
-					Medias: []FetchResultMediaEntry{{
-						Link:    u[:len(u)-4] + "mp4",
-						Quality: "Imgur",     // It doesn't matter
-						Dim:     Dimension{}, // Must be downloaded
-					}},
-					ThumbnailLinks: thumbnails,
-					Title:          title,
-					Description:    description,
-					Type:           FetchResultMediaTypeGif,
-				}, nil
+			if root.URL == nil {
+				return nil, missingFieldError(path + "->url")
+			}
+			u := *root.URL
+			if media, fetchError, ok := o.extractMedia(ExtractRequest{URL: u, PostURL: postUrl, Preview: root.Preview, Path: path}, FetchResultMedia{
+				ThumbnailLinks: thumbnails,
+				Title:          title,
+				Description:    description,
+			}); ok {
+				return media, fetchError
+			}
+			// Check the links to image and video files
+			if media, fetchError, ok := o.directMedia(u, FetchResultMedia{
+				ThumbnailLinks: thumbnails,
+				Title:          title,
+				Description:    description,
+			}); ok {
+				return media, fetchError
 			}
+			// The title is sent on its own, so the text is only the link
 			return FetchResultText{
 				Title: title,
-				Text:  html.UnescapeString(title + "\n" + u),
+				Text:  html.UnescapeString(u),
 			}, nil
 		case "hosted:video": // v.reddit
-			redditVideo := root["media"].(map[string]interface{})["reddit_video"].(map[string]interface{})
-			duration, _ := redditVideo["duration"].(float64) // Do not panic if duration does not exist. Just let the Telegram handle it
-			fallbackURL := redditVideo["fallback_url"].(string)
-			dashURL := redditVideo["dash_url"].(string)
-			qualities, err := extractVideoQualities(dashURL)
+			if root.Media == nil || root.Media.RedditVideo == nil {
+				return nil, missingFieldError(path + "->media->reddit_video")
+			}
+			redditVideo := root.Media.RedditVideo
+			if redditVideo.FallbackURL == nil {
+				return nil, missingFieldError(path + "->media->reddit_video->fallback_url")
+			}
+			if redditVideo.DashURL == nil {
+				return nil, missingFieldError(path + "->media->reddit_video->dash_url")
+			}
+			qualities, err := extractVideoQualities(*redditVideo.DashURL)
 			if err != nil {
 				return nil, &FetchError{
 					NormalError: "Unable to get qualities for video. The main URL was " + postUrl + "; Error was " + err.Error(),
-					BotError:    "Unable to get the video. Here is the direct link to video:\n" + fallbackURL,
+					BotError:    "Unable to get the video. Here is the direct link to video:\n" + *redditVideo.FallbackURL,
 				}
 			}
 			return FetchResultMedia{
 				Medias:         qualities,
 				ThumbnailLinks: thumbnails,
 				Title:          title,
-				Duration:       int64(duration),
+				Duration:       int64(redditVideo.Duration), // Zero if duration does not exist. Just let the Telegram handle it
 				Type:           FetchResultMediaTypeVideo,
 				Description:    description,
 			}, nil
-		case "rich:video": // files hosted other than reddit; This bot currently supports Gfycat.com
-			if urlObject, domainExists := root["domain"]; domainExists {
-				switch urlObject.(string) {
-				case "gfycat.com": // just act like gif
-					images := root["preview"].(map[string]interface{})["images"].([]interface{})[0].(map[string]interface{})
-					if _, hasVariants := images["variants"]; hasVariants {
-						if mp4, hasMp4 := images["variants"].(map[string]interface{})["mp4"]; hasMp4 {
-							return FetchResultMedia{
-								Medias:         extractPhotoGifQualities(mp4.(map[string]interface{})),
-								ThumbnailLinks: thumbnails,
-								Title:          title,
-								Type:           FetchResultMediaTypeGif,
-								Description:    description,
-							}, nil
-						}
-					}
-					// Check reddit_video_preview
-					if vid, hasVid := root["preview"].(map[string]interface{})["reddit_video_preview"]; hasVid {
-						fallback, hasUrl := vid.(map[string]interface{})["fallback_url"].(string)
-						dashURL, hasDash := vid.(map[string]interface{})["dash_url"].(string)
-						if hasUrl && hasDash {
-							qualities, err := extractVideoQualities(dashURL)
-							if err != nil {
-								return nil, &FetchError{
-									NormalError: "Unable to get the qualities for Gfycat. The original link: " + postUrl + ". Error encountered: " + err.Error(),
-									BotError:    "Unable to get the video.\nHere is the link:" + fallback,
-								}
-							}
-							return FetchResultMedia{
-								Medias:         qualities,
-								ThumbnailLinks: thumbnails,
-								Title:          title,
-								Type:           FetchResultMediaTypeVideo,
-								Description:    description,
-							}, nil
-						}
-					}
-					return nil, &FetchError{
-						NormalError: "Unable to get the media from Gfycat. The original link: " + postUrl,
-						BotError:    "Unable to get the video.\nHere is the link:" + root["url"].(string),
-					}
-				case "streamable.com": // example: https://streamable.com/u2jzoo
-					// Download the source at first
-					source, err := common.GlobalHttpClient.Get(root["url"].(string))
-					if err != nil {
-						return nil, &FetchError{
-							NormalError: "Unable to get the source code of " + root["url"].(string) + ": " + err.Error(),
-							BotError:    "Unable to get the source code of " + root["url"].(string),
-						}
-					}
-					defer source.Body.Close()
-					// Get the meta tag og:video
-					doc, err := goquery.NewDocumentFromReader(source.Body)
-					if err != nil {
-						return nil, &FetchError{
-							NormalError: "Unable to get the parse code of " + root["url"].(string) + ": " + err.Error(),
-							BotError:    "Unable to get the parse code of " + root["url"].(string),
-						}
-					}
-					result := FetchResultMedia{
-						Medias: []FetchResultMediaEntry{{
-							Link:    "",
-							Quality: "streamable",
-							Dim:     Dimension{}, // Nope again. We have to download
-						}},
-						ThumbnailLinks: thumbnails,
-						Title:          title,
-						Description:    description,
-						Type:           FetchResultMediaTypeVideo,
-					}
-					doc.Find("meta").Each(func(i int, s *goquery.Selection) {
-						if name, _ := s.Attr("property"); name == "og:video" {
-							result.Medias[0].Link, _ = s.Attr("content")
-						}
-					})
-					return result, nil
-				default:
-					return nil, &FetchError{
-						NormalError: "",
-						BotError:    "This bot doesn’t support downloading from " + urlObject.(string) + "\nThe URL field in JSON is " + root["url"].(string),
-					}
-				}
-			} else {
+		case "rich:video": // files hosted other than reddit; The hosts are supported by the extractors
+			if root.Domain == nil {
 				return nil, &FetchError{
 					NormalError: "",
 					BotError:    "The type of this post is rich:video but it does not contains `domain`",
 				}
 			}
+			if root.URL == nil {
+				return nil, missingFieldError(path + "->url")
+			}
+			if media, fetchError, ok := o.extractMedia(ExtractRequest{URL: *root.URL, PostURL: postUrl, Preview: root.Preview, Path: path}, FetchResultMedia{
+				ThumbnailLinks: thumbnails,
+				Title:          title,
+				Description:    description,
+			}); ok {
+				return media, fetchError
+			}
+			return nil, &FetchError{
+				NormalError: "",
+				BotError:    "This bot doesn’t support downloading from " + *root.Domain + "\nThe URL field in JSON is " + *root.URL,
+			}
 		case "gallery":
-			if gData, ok := root["gallery_data"]; ok {
-				if data, ok := root["media_metadata"]; ok {
-					return FetchResultAlbum{
-						Title:       title,
-						Description: description,
-						Album:       getGalleryData(data.(map[string]interface{}), gData.(map[string]interface{})["items"].([]interface{})),
-					}, nil
-				}
+			if root.GalleryData != nil && root.MediaMetadata != nil {
+				return getGalleryAlbum(root, path, title, description)
 			}
 			return nil, &FetchError{
 				NormalError: "",
@@ -413,49 +421,106 @@ func getPost(postUrl string, root map[string]interface{}) (fetchResult interface
 		default:
 			return nil, &FetchError{
 				NormalError: "",
-				BotError:    "This type of post is not supported: " + hint.(string),
+				BotError:    "This type of post is not supported: " + *root.PostHint,
 			}
 		}
-	} else {                                       // text or gallery
-		if gData, ok := root["gallery_data"]; ok { // gallery
-			if data, ok := root["media_metadata"]; ok {
-				return FetchResultAlbum{
-					Title:       title,
-					Description: description,
-					Album:       getGalleryData(data.(map[string]interface{}), gData.(map[string]interface{})["items"].([]interface{})),
-				}, nil
-			}
+	} else { // text or gallery
+		if root.GalleryData != nil && root.MediaMetadata != nil { // gallery
+			return getGalleryAlbum(root, path, title, description)
 		}
 		// Text
+		if root.Selftext == nil {
+			return nil, missingFieldError(path + "->selftext")
+		}
 		return FetchResultText{
 			Title: title,
-			Text:  strings.ReplaceAll(html.UnescapeString(root["selftext"].(string)), "&#x200B;", ""),
+			Text:  strings.ReplaceAll(html.UnescapeString(*root.Selftext), "&#x200B;", ""),
 		}, nil
 	}
 }
 
+// getPoll converts the poll_data of a post to FetchResultPoll. path is the path of poll_data in JSON.
+func getPoll(poll apiPollData, path, title, description string) (FetchResultPoll, *FetchError) {
+	result := FetchResultPoll{
+		Title:       title,
+		Description: description,
+		Options:     make([]FetchResultPollOption, 0, len(poll.Options)),
+		TotalVotes:  poll.TotalVoteCount,
+	}
+	if poll.VotingEndTimestamp != 0 {
+		result.EndTime = time.UnixMilli(poll.VotingEndTimestamp).UTC()
+	}
+	for i, option := range poll.Options {
+		if option.Text == nil {
+			return FetchResultPoll{}, missingFieldError(path + "->options[" + strconv.Itoa(i) + "]->text")
+		}
+		votes := int64(-1)
+		if option.VoteCount != nil {
+			votes = *option.VoteCount
+		}
+		result.Options = append(result.Options, FetchResultPollOption{
+			Text:  html.UnescapeString(*option.Text),
+			Votes: votes,
+		})
+	}
+	return result, nil
+}
+
+// firstPreviewImage gets the first image in the preview of a link. path is the path of the link in JSON.
+func firstPreviewImage(root *apiLink, path string) (apiPreviewImage, *FetchError) {
+	if root.Preview == nil {
+		return apiPreviewImage{}, missingFieldError(path + "->preview")
+	}
+	if len(root.Preview.Images) == 0 {
+		return apiPreviewImage{}, missingFieldError(path + "->preview->images[0]")
+	}
+	return root.Preview.Images[0], nil
+}
+
+// getGalleryAlbum creates the album of a gallery post. path is the path of the post in JSON.
+// The post must have gallery_data and media_metadata.
+func getGalleryAlbum(root *apiLink, path, title, description string) (FetchResultAlbum, *FetchError) {
+	if root.GalleryData.Items == nil {
+		return FetchResultAlbum{}, missingFieldError(path + "->gallery_data->items")
+	}
+	album, fetchError := getGalleryData(root.MediaMetadata, root.GalleryData.Items)
+	if fetchError != nil {
+		return FetchResultAlbum{}, fetchError
+	}
+	return FetchResultAlbum{
+		Title:       title,
+		Description: description,
+		Album:       album,
+	}, nil
+}
+
 // getGalleryData extracts the gallery data from gallery json
-func getGalleryData(files map[string]interface{}, galleryDataItems []interface{}) []FetchResultAlbumEntry {
+func getGalleryData(files map[string]apiMediaMetadata, galleryDataItems []apiGalleryItem) ([]FetchResultAlbumEntry, *FetchError) {
 	album := make([]FetchResultAlbumEntry, 0, len(galleryDataItems))
-	for _, data := range galleryDataItems {
-		galleryRoot := files[data.(map[string]interface{})["media_id"].(string)]
+	for i, data := range galleryDataItems {
+		if data.MediaID == "" {
+			return nil, missingFieldError("gallery_data->items[" + strconv.Itoa(i) + "]->media_id")
+		}
+		path := "media_metadata->" + data.MediaID
+		image, exists := files[data.MediaID]
+		if !exists {
+			return nil, missingFieldError(path)
+		}
 		// Extract the url
-		image := galleryRoot.(map[string]interface{})
-		if image["status"].(string) != "valid" { // I have not encountered anything else except valid so far
+		if image.Status != "valid" { // I have not encountered anything else except valid so far
 			continue
 		}
-		dataType := image["e"].(string)
 		// Check the type
-		switch dataType {
+		switch image.Type {
 		case "Image":
-			link := html.UnescapeString(image["s"].(map[string]interface{})["u"].(string))
-			// Get the caption
-			var caption string
-			if c, ok := data.(map[string]interface{})["caption"]; ok {
-				caption = c.(string)
+			if image.Source == nil || image.Source.URL == nil {
+				return nil, missingFieldError(path + "->s->u")
 			}
-			if c, ok := data.(map[string]interface{})["outbound_url"]; ok {
-				caption += "\n" + c.(string)
+			link := html.UnescapeString(*image.Source.URL)
+			// Get the caption
+			caption := data.Caption
+			if data.OutboundURL != "" {
+				caption += "\n" + data.OutboundURL
 			}
 			// Append to the album
 			album = append(album, FetchResultAlbumEntry{
@@ -464,14 +529,14 @@ func getGalleryData(files map[string]interface{}, galleryDataItems []interface{}
 				Type:    FetchResultMediaTypePhoto,
 			})
 		case "AnimatedImage":
-			link := html.UnescapeString(image["s"].(map[string]interface{})["mp4"].(string))
-			// Get the caption
-			var caption string
-			if c, ok := data.(map[string]interface{})["caption"]; ok {
-				caption = c.(string)
+			if image.Source == nil || image.Source.MP4 == nil {
+				return nil, missingFieldError(path + "->s->mp4")
 			}
-			if c, ok := data.(map[string]interface{})["outbound_url"]; ok {
-				caption += "\n" + c.(string)
+			link := html.UnescapeString(*image.Source.MP4)
+			// Get the caption
+			caption := data.Caption
+			if data.OutboundURL != "" {
+				caption += "\n" + data.OutboundURL
 			}
 			// Append to the album
 			album = append(album, FetchResultAlbumEntry{
@@ -480,50 +545,105 @@ func getGalleryData(files map[string]interface{}, galleryDataItems []interface{}
 				Type:    FetchResultMediaTypeGif,
 			})
 		case "RedditVideo":
-			id := image["id"].(string)
-			w := image["x"].(float64)
-			h := image["y"].(float64)
-			// Get the quality
-			res := "96"
-			if w >= 1920 && h >= 1080 { // is this the best way?
-				res = "1080"
-			} else if w >= 1280 && h >= 720 {
-				res = "720"
-			} else if w >= 854 && h >= 480 {
-				res = "480"
-			} else if w >= 640 && h >= 360 {
-				res = "360"
-			} else if w >= 426 && h >= 240 {
-				res = "240"
-			}
-			link := "https://v.redd.it/" + id + "/DASH_" + res + ".mp4"
-			// Get the caption
-			var caption string
-			if c, ok := data.(map[string]interface{})["caption"]; ok {
-				caption = c.(string)
+			if image.ID == nil {
+				return nil, missingFieldError(path + "->id")
 			}
-			// Append to the album
-			album = append(album, FetchResultAlbumEntry{
-				Link:    link,
-				Caption: caption,
+			if image.Width == nil || image.Height == nil {
+				return nil, missingFieldError(path + "->x")
+			}
+			entry := FetchResultAlbumEntry{
+				Link:    galleryVideoFallbackLink(*image.ID, *image.Width, *image.Height),
+				Caption: data.Caption,
 				Type:    FetchResultMediaTypeVideo,
-			})
+			}
+			// Get the real files and the audio from the playlist
+			if dashURL, ok := galleryVideoDashURL(image); ok {
+				if err := setGalleryVideoFiles(&entry, dashURL); err != nil {
+					log.Println("Unable to get the gallery video", dashURL, ":", err)
+				}
+			}
+			// Append to the album
+			album = append(album, entry)
 		default:
-			log.Println("Unknown type in send gallery:", dataType)
+			log.Println("Unknown type in send gallery:", image.Type)
 		}
 	}
-	return album
+	return album, nil
+}
+
+// galleryVideoFallbackLink guesses the link of a video of a gallery from its dimensions. It's used
+// when the playlist of the video cannot be read. The video will not have audio.
+func galleryVideoFallbackLink(id string, w, h int64) string {
+	// Get the quality
+	res := "96"
+	if w >= 1920 && h >= 1080 { // is this the best way?
+		res = "1080"
+	} else if w >= 1280 && h >= 720 {
+		res = "720"
+	} else if w >= 854 && h >= 480 {
+		res = "480"
+	} else if w >= 640 && h >= 360 {
+		res = "360"
+	} else if w >= 426 && h >= 240 {
+		res = "240"
+	}
+	return "https://v.redd.it/" + id + "/DASH_" + res + ".mp4"
+}
+
+// galleryVideoDashURL gets the DASH playlist of a video of a gallery. Some videos only have an
+// HLS playlist, but Reddit creates both playlists for each video; So the DASH playlist is taken from
+// the same directory. The HLS playlist itself is not used because its media are split in segments.
+func galleryVideoDashURL(video apiMediaMetadata) (string, bool) {
+	if video.DashURL != nil {
+		return *video.DashURL, true
+	}
+	if video.HlsURL == nil {
+		return "", false
+	}
+	u, err := url.Parse(html.UnescapeString(*video.HlsURL))
+	if err != nil || !strings.HasSuffix(u.Path, "/HLSPlaylist.m3u8") {
+		return "", false
+	}
+	u.Path = strings.TrimSuffix(u.Path, "HLSPlaylist.m3u8") + "DASHPlaylist.mpd"
+	return u.String(), true
+}
+
+// setGalleryVideoFiles sets the link, audio link and duration of a video of a gallery from its DASH
+// playlist. The best video and audio are used because the album does not let the user choose.
+func setGalleryVideoFiles(entry *FetchResultAlbumEntry, dashURL string) error {
+	dashURL = html.UnescapeString(dashURL)
+	playlist, err := ParseDashPlaylistFromID(dashURL)
+	if err != nil {
+		return err
+	}
+	if len(playlist.AvailableVideos) == 0 {
+		return errors.New("no video in the playlist")
+	}
+	SortVideoQualities(playlist.AvailableVideos)
+	base := getVideoVRedditBaseURL(dashURL)
+	entry.Link = base + playlist.AvailableVideos[0].BaseURL
+	if len(playlist.AvailableAudios) != 0 {
+		entry.AudioLink = base + string(playlist.AvailableAudios[len(playlist.AvailableAudios)-1])
+	}
+	entry.Duration = playlist.Duration
+	return nil
 }
 
 // extractPhotoGifQualities creates an array of FetchResultMediaEntry which are the qualities
-// of the photo or gif and their links
-func extractPhotoGifQualities(data map[string]interface{}) []FetchResultMediaEntry {
-	resolutions := data["resolutions"].([]interface{})
+// of the photo or gif and their links. path is the path of data in JSON.
+func extractPhotoGifQualities(data apiPreviewImage, path string) ([]FetchResultMediaEntry, *FetchError) {
+	if data.Source == nil {
+		return nil, missingFieldError(path + "->source")
+	}
+	resolutions := data.Resolutions
 	result := make([]FetchResultMediaEntry, 0, 1+len(resolutions))
 	// Include source image at last to keep the increasing quality
 	// Just a note for myself: This can be different from the one in resolutions
 	{
-		u, w, h := extractLinkAndRes(data["source"])
+		u, w, h, fetchError := extractLinkAndRes(*data.Source, path+"->source")
+		if fetchError != nil {
+			return nil, fetchError
+		}
 		result = append(result, FetchResultMediaEntry{
 			Link:    u,
 			Quality: strconv.FormatInt(w, 10) + "×" + strconv.FormatInt(h, 10),
@@ -535,7 +655,10 @@ func extractPhotoGifQualities(data map[string]interface{}) []FetchResultMediaEnt
 	}
 	// Now get all other thumbs
 	for i := len(resolutions) - 1; i >= 0; i-- {
-		u, w, h := extractLinkAndRes(resolutions[i])
+		u, w, h, fetchError := extractLinkAndRes(resolutions[i], path+"->resolutions["+strconv.Itoa(i)+"]")
+		if fetchError != nil {
+			return nil, fetchError
+		}
 		if i == len(resolutions)-1 { // In first case, the sizes can be same. Example: https://www.reddit.com/r/dankmemes/comments/vqphiy/more_than_bargain_for/
 			dim := Dimension{w, h}
 			if dim == result[0].Dim {
@@ -551,7 +674,7 @@ func extractPhotoGifQualities(data map[string]interface{}) []FetchResultMediaEnt
 			},
 		})
 	}
-	return result
+	return result, nil
 }
 
 // extractVideoQualities gets all possible qualities from DASHPlaylist URL
@@ -590,72 +713,101 @@ func getVideoVRedditBaseURL(vredditURL string) string {
 }
 
 // extractLinkAndRes extracts the data from "source":{ "url":"https://preview.redd.it/utx00pfe4cp41.jpg?auto=webp&amp;s=de4ff82478b12df6369b8d7eeca3894f094e87e1", "width":624, "height":960 } stuff
-// First return values are url, width, height
-func extractLinkAndRes(data interface{}) (u string, width int64, height int64) {
-	kv := data.(map[string]interface{})
-	return html.UnescapeString(kv["url"].(string)), int64(kv["width"].(float64)), int64(kv["height"].(float64))
+// First return values are url, width, height. path is the path of data in JSON.
+func extractLinkAndRes(data apiPreviewSource, path string) (u string, width int64, height int64, fetchError *FetchError) {
+	if data.URL == nil {
+		return "", 0, 0, missingFieldError(path + "->url")
+	}
+	return html.UnescapeString(*data.URL), data.Width, data.Height, nil
 }
 
 // Extract the thumbnails based on the root of the document.
 // Will return an empty string if the thumbnail could not be found.
-func extractThumbnails(root map[string]interface{}) FetchedThumbnails {
+func extractThumbnails(root *apiLink) FetchedThumbnails {
 	// At first check the thumbnail in the preview section.
-	if preview, ok := root["preview"].(map[string]interface{}); ok {
-		if images, ok := preview["images"].([]interface{}); ok && len(images) > 0 {
-			// I don't know when the len is more than 1. In albums this entry is non-existent
-			if index, ok := images[0].(map[string]interface{}); ok {
-				resolutions, _ := index["resolutions"].([]interface{})
-				result := make([]FetchedThumbnail, 0, len(resolutions)+1)
-				for _, resolution := range resolutions {
-					if thumb, ok := extractPreviewThumbnail(resolution); ok {
-						result = append(result, thumb)
-					}
-				}
-				if thumb, ok := extractPreviewThumbnail(index["source"]); ok {
-					result = append(result, thumb)
-				}
-				// At last, check if the result has at least one entry
-				if len(result) != 0 {
-					return result
-				}
-				// Fallback to the root thumbnail
+	// I don't know when the len of images is more than 1. In albums this entry is non-existent
+	if root.Preview != nil && len(root.Preview.Images) > 0 {
+		index := root.Preview.Images[0]
+		result := make([]FetchedThumbnail, 0, len(index.Resolutions)+1)
+		for _, resolution := range index.Resolutions {
+			if thumb, ok := extractPreviewThumbnail(resolution); ok {
+				result = append(result, thumb)
+			}
+		}
+		if index.Source != nil {
+			if thumb, ok := extractPreviewThumbnail(*index.Source); ok {
+				result = append(result, thumb)
 			}
 		}
+		// At last, check if the result has at least one entry
+		if len(result) != 0 {
+			return result
+		}
+		// Fallback to the root thumbnail
 	}
 	// As a fallback, just get the thumbnail in root which is always 140x140 and cropped
-	if t, ok := root["thumbnail"]; ok {
-		thumbnailUrl := html.UnescapeString(t.(string))
-		// Check the url; Sometimes, the value of this is default or NSFW
-		if util.IsUrl(thumbnailUrl) {
-			return FetchedThumbnails{FetchedThumbnail{
-				Link: thumbnailUrl,
-				Dim:  Dimension{}, // left empty...
-			}}
-		}
-
+	thumbnailUrl := html.UnescapeString(root.Thumbnail)
+	// Check the url; Sometimes, the value of this is default or NSFW
+	if util.IsUrl(thumbnailUrl) {
+		return FetchedThumbnails{FetchedThumbnail{
+			Link: thumbnailUrl,
+			Dim:  Dimension{}, // left empty...
+		}}
 	}
 	// Nothing found. Return empty string
 	return nil
 }
 
-func extractPreviewThumbnail(resolution interface{}) (FetchedThumbnail, bool) {
-	if r, ok := resolution.(map[string]interface{}); ok {
-		if u, ok := r["url"]; ok {
-			thumbnailUrl := html.UnescapeString(u.(string))
-			width, _ := r["width"].(float64)
-			height, _ := r["height"].(float64)
-			// Check the url; Sometimes, the value is not a URL and a generic string
-			if util.IsUrl(thumbnailUrl) {
-				return FetchedThumbnail{
-					Link: thumbnailUrl,
-					Dim: Dimension{
-						Width:  int64(width),
-						Height: int64(height),
-					},
-				}, true
-			}
+func extractPreviewThumbnail(resolution apiPreviewSource) (FetchedThumbnail, bool) {
+	if resolution.URL != nil {
+		thumbnailUrl := html.UnescapeString(*resolution.URL)
+		// Check the url; Sometimes, the value is not a URL and a generic string
+		if util.IsUrl(thumbnailUrl) {
+			return FetchedThumbnail{
+				Link: thumbnailUrl,
+				Dim: Dimension{
+					Width:  resolution.Width,
+					Height: resolution.Height,
+				},
+			}, true
 		}
 	}
 	// Failed
 	return FetchedThumbnail{}, false
 }
+
+// getPostMetadata gets the information about a post which can be shown in the captions
+func getPostMetadata(root *apiLink) PostMetadata {
+	return PostMetadata{
+		Subreddit: root.Subreddit,
+		Author:    root.Author,
+		Score:     root.Score,
+		Flair:     html.UnescapeString(strings.TrimSpace(root.LinkFlairText)),
+		Created:   int64(root.CreatedUTC),
+	}
+}
+
+// postFlags are the marks of a post which hide its content
+type postFlags struct {
+	NSFW    bool
+	Spoiler bool
+}
+
+// withPostInfo adds the metadata and the flags of a post to a result of getPost if it can have them
+func withPostInfo(result interface{}, metadata PostMetadata, flags postFlags) interface{} {
+	switch r := result.(type) {
+	case FetchResultMedia:
+		r.Metadata = metadata
+		// The media might be NSFW even if the post is not marked
+		r.NSFW, r.Spoiler = r.NSFW || flags.NSFW, flags.Spoiler
+		return r
+	case FetchResultAlbum:
+		r.Metadata = metadata
+		r.NSFW, r.Spoiler = flags.NSFW, flags.Spoiler
+		return r
+	case FetchResultText:
+		r.Spoiler = flags.Spoiler
+		return r
+	}
+	return result
+}
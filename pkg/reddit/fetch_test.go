@@ -9,17 +9,19 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestExtractLinkAndRes(t *testing.T) {
 	assertion := assert.New(t)
-	var parsedJson interface{}
+	var parsedJson apiPreviewSource
 	dataString := `{ "url":"https://preview.redd.it/utx00pfe4cp41.jpg?auto=webp&amp;s=de4ff82478b12df6369b8d7eeca3894f094e87e1", "width":624, "height":960 }`
 	err := json.NewDecoder(strings.NewReader(dataString)).Decode(&parsedJson)
 	assertion.NoError(err, "unexpected error when parsing test json")
-	url, width, height := extractLinkAndRes(parsedJson)
+	url, width, height, fetchError := extractLinkAndRes(parsedJson, "source")
+	assertion.Nil(fetchError, "unexpected fetch error")
 	assertion.Equal(int64(624), width, "unexpected width")
 	assertion.Equal(int64(960), height, "unexpected height")
 	assertion.Equal("https://preview.redd.it/utx00pfe4cp41.jpg?auto=webp&s=de4ff82478b12df6369b8d7eeca3894f094e87e1", url, "unexpected url")
@@ -155,10 +157,11 @@ func TestExtractPhotoGifQualities(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.TestName, func(t *testing.T) {
-			var parsedData map[string]interface{}
+			var parsedData apiPreviewImage
 			err := json.NewDecoder(strings.NewReader(test.RawData)).Decode(&parsedData)
 			assert.NoError(t, err, "sample data must be parsed without errors")
-			result := extractPhotoGifQualities(parsedData)
+			result, fetchError := extractPhotoGifQualities(parsedData, "preview->images[0]")
+			assert.Nil(t, fetchError, "not expecting fetch error")
 			assert.Equal(t, test.Expected, result)
 		})
 	}
@@ -234,22 +237,131 @@ func TestGetGalleryData(t *testing.T) {
 				},
 			},
 		},
-		// TODO: add a video test
 	}
 	for _, test := range tests {
 		t.Run(test.TestName, func(t *testing.T) {
-			var files map[string]interface{}
-			var galleryDataItems []interface{}
+			var files map[string]apiMediaMetadata
+			var galleryDataItems []apiGalleryItem
 			err := json.NewDecoder(strings.NewReader(test.Files)).Decode(&files)
 			assert.NoError(t, err, "not expecting error when decoding sample files")
 			err = json.NewDecoder(strings.NewReader(test.GalleryDataItems)).Decode(&galleryDataItems)
 			assert.NoError(t, err, "not expecting error when decoding sample gallery data items")
-			result := getGalleryData(files, galleryDataItems)
+			result, fetchError := getGalleryData(files, galleryDataItems)
+			assert.Nil(t, fetchError, "not expecting fetch error")
 			assert.Equal(t, test.ExpectedAlbum, result)
 		})
 	}
 }
 
+func TestGetGalleryDataVideo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/link/abc/asset/vid1/DASHPlaylist.mpd" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" mediaPresentationDuration="PT12.6S" type="static">
+  <Period duration="PT12.6S">
+    <AdaptationSet contentType="video">
+      <Representation height="480" id="1" mimeType="video/mp4" width="270"><BaseURL>DASH_480.mp4</BaseURL></Representation>
+      <Representation height="720" id="2" mimeType="video/mp4" width="406"><BaseURL>DASH_720.mp4</BaseURL></Representation>
+    </AdaptationSet>
+    <AdaptationSet contentType="audio">
+      <Representation id="3" mimeType="audio/mp4"><BaseURL>DASH_AUDIO_64.mp4</BaseURL></Representation>
+      <Representation id="4" mimeType="audio/mp4"><BaseURL>DASH_AUDIO_128.mp4</BaseURL></Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`))
+	}))
+	defer server.Close()
+	files := map[string]apiMediaMetadata{}
+	err := json.Unmarshal([]byte(`{
+		"vid1":{"status":"valid","e":"RedditVideo","id":"vid1","x":406,"y":720,"dashUrl":"`+server.URL+`/link/abc/asset/vid1/DASHPlaylist.mpd?a=1&amp;v=1"},
+		"vid2":{"status":"valid","e":"RedditVideo","id":"vid2","x":1280,"y":720,"dashUrl":"`+server.URL+`/link/abc/asset/vid2/DASHPlaylist.mpd?a=1"},
+		"vid3":{"status":"valid","e":"RedditVideo","id":"vid3","x":640,"y":360},
+		"vid4":{"status":"valid","e":"RedditVideo","id":"vid4","x":406,"y":720,"hlsUrl":"`+server.URL+`/link/abc/asset/vid1/HLSPlaylist.m3u8?a=1&amp;v=1"},
+		"vid5":{"status":"valid","e":"RedditVideo","id":"vid5","x":640,"y":360,"hlsUrl":"`+server.URL+`/link/abc/asset/vid5/playlist.m3u8"}
+	}`), &files)
+	assert.NoError(t, err)
+	album, fetchError := getGalleryData(files, []apiGalleryItem{
+		{MediaID: "vid1", Caption: "With audio"},
+		{MediaID: "vid2"},
+		{MediaID: "vid3"},
+		{MediaID: "vid4"},
+		{MediaID: "vid5"},
+	})
+	assert.Nil(t, fetchError)
+	assert.Equal(t, []FetchResultAlbumEntry{
+		{
+			Link:      server.URL + "/link/abc/asset/vid1/DASH_720.mp4",
+			Caption:   "With audio",
+			Type:      FetchResultMediaTypeVideo,
+			AudioLink: server.URL + "/link/abc/asset/vid1/DASH_AUDIO_128.mp4",
+			Duration:  13,
+		},
+		// The playlist cannot be read
+		{
+			Link: "https://v.redd.it/vid2/DASH_720.mp4",
+			Type: FetchResultMediaTypeVideo,
+		},
+		// No playlist
+		{
+			Link: "https://v.redd.it/vid3/DASH_360.mp4",
+			Type: FetchResultMediaTypeVideo,
+		},
+		// The DASH playlist is next to the HLS playlist
+		{
+			Link:      server.URL + "/link/abc/asset/vid1/DASH_720.mp4",
+			Type:      FetchResultMediaTypeVideo,
+			AudioLink: server.URL + "/link/abc/asset/vid1/DASH_AUDIO_128.mp4",
+			Duration:  13,
+		},
+		// Unknown HLS playlist
+		{
+			Link: "https://v.redd.it/vid5/DASH_360.mp4",
+			Type: FetchResultMediaTypeVideo,
+		},
+	}, album)
+}
+
+func TestGalleryVideoDashURL(t *testing.T) {
+	tests := []struct {
+		TestName   string
+		Video      string
+		ExpectedOk bool
+		Expected   string
+	}{
+		{
+			TestName:   "DASH",
+			Video:      `{"dashUrl":"https://v.redd.it/a/DASHPlaylist.mpd?a=1&amp;v=1","hlsUrl":"https://v.redd.it/a/HLSPlaylist.m3u8"}`,
+			ExpectedOk: true,
+			Expected:   "https://v.redd.it/a/DASHPlaylist.mpd?a=1&amp;v=1",
+		},
+		{
+			TestName:   "Only HLS",
+			Video:      `{"hlsUrl":"https://v.redd.it/a/HLSPlaylist.m3u8?a=1&amp;v=1&amp;f=sd"}`,
+			ExpectedOk: true,
+			Expected:   "https://v.redd.it/a/DASHPlaylist.mpd?a=1&v=1&f=sd",
+		},
+		{
+			TestName: "Unknown HLS Playlist",
+			Video:    `{"hlsUrl":"https://v.redd.it/a/playlist.m3u8"}`,
+		},
+		{
+			TestName: "No Playlist",
+			Video:    `{}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			var video apiMediaMetadata
+			assert.NoError(t, json.Unmarshal([]byte(test.Video), &video))
+			dashURL, ok := galleryVideoDashURL(video)
+			assert.Equal(t, test.ExpectedOk, ok)
+			assert.Equal(t, test.Expected, dashURL)
+		})
+	}
+}
+
 func TestGetPostId(t *testing.T) {
 	tests := []struct {
 		TestName          string
@@ -415,7 +527,13 @@ func TestGetCommentFromRoot(t *testing.T) {
 		{
 			TestName: "Text Comment",
 			Root:     `{"data":{"after":null,"before":null,"children":[{"data":{"all_awardings":[],"approved_at_utc":null,"approved_by":null,"archived":false,"associated_award":null,"author":"MD9564","author_flair_background_color":"#46d160","author_flair_css_class":"xbx","author_flair_richtext":[{"a":":XBX1:","e":"emoji","u":"https://emoji.redditmedia.com/wts1rb0yicq71_t5_2xrd1/XBX1"},{"a":":XBX2:","e":"emoji","u":"https://emoji.redditmedia.com/8wwoun2yicq71_t5_2xrd1/XBX2"}],"author_flair_template_id":"303594a4-2bbc-11eb-b5d1-0e259b87ccd1","author_flair_text":":XBX1::XBX2:","author_flair_text_color":"light","author_flair_type":"richtext","author_fullname":"t2_1eygor7t","author_is_blocked":false,"author_patreon_flair":false,"author_premium":false,"awarders":[],"banned_at_utc":null,"banned_by":null,"body":"The Plane Door is closed.","body_html":"\u0026lt;div class=\"md\"\u0026gt;\u0026lt;p\u0026gt;The Plane Door is closed.\u0026lt;/p\u0026gt;\n\u0026lt;/div\u0026gt;","can_gild":true,"can_mod_post":false,"collapsed":false,"collapsed_because_crowd_control":null,"collapsed_reason":null,"collapsed_reason_code":null,"comment_type":null,"controversiality":0,"created":1661315239,"created_utc":1661315239,"distinguished":null,"downs":0,"edited":false,"gilded":0,"gildings":{},"id":"iljyela","is_submitter":false,"likes":null,"link_id":"t3_ww9qw1","locked":false,"mod_note":null,"mod_reason_by":null,"mod_reason_title":null,"mod_reports":[],"name":"t1_iljyela","no_follow":false,"num_reports":null,"parent_id":"t3_ww9qw1","permalink":"/r/gtaonline/comments/ww9qw1/if_you_are_a_real_cayo_grinder_then_tell_me_whats/iljyela/","removal_reason":null,"replies":"","report_reasons":null,"saved":false,"score":31,"score_hidden":false,"send_replies":true,"stickied":false,"subreddit":"gtaonline","subreddit_id":"t5_2xrd1","subreddit_name_prefixed":"r/gtaonline","subreddit_type":"public","top_awarded_type":null,"total_awards_received":0,"treatment_tags":[],"unrepliable_reason":null,"ups":31,"user_reports":[]},"kind":"t1"}],"dist":1,"geo_filter":"","modhash":""},"kind":"Listing"}`,
-			Expected: FetchResultComment{"The Plane Door is closed."},
+			Expected: FetchResultComment{
+				Text:     "The Plane Door is closed.",
+				Author:   "MD9564",
+				Score:    31,
+				ParentID: "t3_ww9qw1",
+				LinkID:   "t3_ww9qw1",
+			},
 		},
 		// From https://www.reddit.com/r/whenthe/comments/wq2fpi/comment/ikkn4sr/?utm_source=share&utm_medium=web2x&context=3
 		{
@@ -430,13 +548,25 @@ func TestGetCommentFromRoot(t *testing.T) {
 				Title: "",
 			},
 		},
+		{
+			TestName: "Escaped Comment",
+			Root:     `{"data":{"children":[{"kind":"t1","data":{"author":"a","score":2,"body":"Tom \u0026amp; Jerry \u0026gt; Jerry","parent_id":"t3_p","link_id":"t3_p"}}]}}`,
+			Expected: FetchResultComment{
+				Text:     "Tom & Jerry > Jerry",
+				Author:   "a",
+				Score:    2,
+				ParentID: "t3_p",
+				LinkID:   "t3_p",
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.TestName, func(t *testing.T) {
-			var root map[string]interface{}
+			var root apiListing[apiComment]
 			err := json.NewDecoder(strings.NewReader(test.Root)).Decode(&root)
 			assert.NoError(t, err, "not expecting error when decoding sample root")
-			result := getCommentFromRoot(root)
+			result, fetchError := getCommentFromRoot(root)
+			assert.Nil(t, fetchError, "not expecting fetch error")
 			assert.Equal(t, test.Expected, result)
 		})
 	}
@@ -573,6 +703,12 @@ func TestGetPost(t *testing.T) {
 				},
 				Title: "The truth has been spoken",
 				Type:  FetchResultMediaTypePhoto,
+				Metadata: PostMetadata{
+					Subreddit: "dankmemes",
+					Author:    "mitus376",
+					Score:     4855,
+					Created:   1661276100,
+				},
 			},
 			ExpectedError: nil,
 		},
@@ -697,6 +833,13 @@ func TestGetPost(t *testing.T) {
 				},
 				Title: "you what now?",
 				Type:  FetchResultMediaTypePhoto,
+				Metadata: PostMetadata{
+					Subreddit: "dankmemes",
+					Author:    "HirbodBehnam",
+					Score:     29,
+					Flair:     "Halal Meme",
+					Created:   1599592252,
+				},
 			},
 		},
 		{
@@ -747,6 +890,12 @@ func TestGetPost(t *testing.T) {
 				},
 				Title: "You daughter of a bitch, I'm in.",
 				Type:  FetchResultMediaTypeGif,
+				Metadata: PostMetadata{
+					Subreddit: "dankmemes",
+					Author:    "mijuzz7",
+					Score:     11199,
+					Created:   1588189037,
+				},
 			},
 			ExpectedError: nil,
 		},
@@ -821,6 +970,12 @@ func TestGetPost(t *testing.T) {
 				},
 				Title: "I gotta do this more often",
 				Type:  FetchResultMediaTypeGif,
+				Metadata: PostMetadata{
+					Subreddit: "dankmemes",
+					Author:    "Alarmed-Ad-436",
+					Score:     11369,
+					Created:   1661286775,
+				},
 			},
 			ExpectedError: nil,
 		},
@@ -914,6 +1069,13 @@ func TestGetPost(t *testing.T) {
 				Title:    "When you’re showing a low level around",
 				Duration: 5,
 				Type:     FetchResultMediaTypeVideo,
+				Metadata: PostMetadata{
+					Subreddit: "gtaonline",
+					Author:    "GreuDeFumat",
+					Score:     405,
+					Flair:     ":VID1::VID2::VID3:",
+					Created:   1661322769,
+				},
 			},
 			ExpectedError: nil,
 		},
@@ -993,6 +1155,13 @@ func TestGetPost(t *testing.T) {
 				Title:    "When you’re showing a low level around",
 				Duration: 5,
 				Type:     FetchResultMediaTypeVideo,
+				Metadata: PostMetadata{
+					Subreddit: "gtaonline",
+					Author:    "GreuDeFumat",
+					Score:     405,
+					Flair:     ":VID1::VID2::VID3:",
+					Created:   1661322769,
+				},
 			},
 			ExpectedError: nil,
 		},
@@ -1083,6 +1252,12 @@ func TestGetPost(t *testing.T) {
 				Title:    "xQc moment",
 				Duration: 11,
 				Type:     FetchResultMediaTypeVideo,
+				Metadata: PostMetadata{
+					Subreddit: "whenthe",
+					Author:    "The-Great-Memelord",
+					Score:     15739,
+					Created:   1690682964,
+				},
 			},
 			ExpectedError: nil,
 		},
@@ -1103,7 +1278,16 @@ func TestGetPost(t *testing.T) {
 						Caption: "",
 						Type:    FetchResultMediaTypePhoto,
 					},
-				}},
+				},
+				Metadata: PostMetadata{
+					Subreddit: "gtaonline",
+					Author:    "AlphaMale3Percent",
+					Score:     2390,
+					Flair:     ":SNAP1::SNAP2::SNAP3::SNAP4::SNAP5:",
+					Created:   1661137957,
+				},
+				NSFW: true,
+			},
 			ExpectedError: nil,
 		},
 		{
@@ -1183,6 +1367,12 @@ func TestGetPost(t *testing.T) {
 				Description: "Female pheasant wasn't enchanted by his performance, but he hefuses to give up and keeps doing his mating dance .",
 				Duration:    23,
 				Type:        FetchResultMediaTypeVideo,
+				Metadata: PostMetadata{
+					Subreddit: "me_irl",
+					Author:    "CuteGrayRhino",
+					Score:     1003,
+					Created:   1723510398,
+				},
 			},
 			ExpectedError: nil,
 		},
@@ -1277,6 +1467,13 @@ func TestGetPost(t *testing.T) {
 				Title:    "Just had to do it myself ✨",
 				Duration: 67,
 				Type:     FetchResultMediaTypeVideo,
+				Metadata: PostMetadata{
+					Subreddit: "blender",
+					Author:    "7hamza1",
+					Score:     501,
+					Flair:     "I Made This",
+					Created:   1723830488,
+				},
 			},
 			ExpectedError: nil,
 		},
@@ -1300,7 +1497,14 @@ func TestGetPost(t *testing.T) {
 						Link: "https://preview.redd.it/cn6hkgnww9nd1.jpg?width=1645&format=pjpg&auto=webp&s=d02099affd634790931f2f5c275e1da42305c3d6",
 						Type: FetchResultMediaTypePhoto,
 					},
-				}},
+				},
+				Metadata: PostMetadata{
+					Subreddit: "Wellthatsucks",
+					Author:    "Heisenbergwayne",
+					Score:     31503,
+					Created:   1725665297,
+				},
+			},
 			ExpectedError: nil,
 		},
 		{
@@ -1323,7 +1527,15 @@ func TestGetPost(t *testing.T) {
 						Link: "https://preview.redd.it/d88d3dhpcb0f1.jpg?width=4000&format=pjpg&auto=webp&s=427679c21a9c5b3ac8f3f4aa72a6bb951d77a283",
 						Type: FetchResultMediaTypePhoto,
 					},
-				}},
+				},
+				Metadata: PostMetadata{
+					Subreddit: "pcmasterrace",
+					Author:    "BadAdvice8---------D",
+					Score:     568,
+					Flair:     "Discussion",
+					Created:   1747039273,
+				},
+			},
 			ExpectedError: nil,
 		},
 	}
@@ -1345,10 +1557,10 @@ func TestGetPost(t *testing.T) {
 				}
 			}
 			// Parse
-			var root map[string]interface{}
+			var root apiListing[apiLink]
 			err := json.Unmarshal(test.Root, &root)
 			assert.NoError(t, err, "not expecting error when decoding sample root")
-			result, fetchError := getPost(test.PostUrl, root)
+			result, fetchError := (&Oauth{}).getPost(test.PostUrl, root)
 			if fetchError != nil && test.ExpectedError != nil {
 				assert.Equal(t, *test.ExpectedError, *fetchError)
 			} else if fetchError != nil && test.ExpectedError == nil {
@@ -1361,3 +1573,140 @@ func TestGetPost(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPostMissingField(t *testing.T) {
+	tests := []struct {
+		TestName     string
+		Root         string
+		MissingField string
+	}{
+		{
+			TestName:     "No Data",
+			Root:         `{"kind": "Listing"}`,
+			MissingField: "data",
+		},
+		{
+			TestName:     "No Children",
+			Root:         `{"kind": "Listing", "data": {"children": []}}`,
+			MissingField: "data->children",
+		},
+		{
+			TestName:     "No Title",
+			Root:         `{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"selftext": ""}}]}}`,
+			MissingField: "data->children[0]->data->title",
+		},
+		{
+			TestName:     "Image Without Preview",
+			Root:         `{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "a", "post_hint": "image", "url": "https://i.redd.it/a.jpg"}}]}}`,
+			MissingField: "data->children[0]->data->preview",
+		},
+		{
+			TestName:     "Video Without Dash",
+			Root:         `{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "a", "post_hint": "hosted:video", "media": {"reddit_video": {"fallback_url": "https://v.redd.it/a/DASH_720.mp4"}}}}]}}`,
+			MissingField: "data->children[0]->data->media->reddit_video->dash_url",
+		},
+		{
+			TestName:     "Crosspost Without URL",
+			Root:         `{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "a", "crosspost_parent_list": [{"title": "b", "post_hint": "link"}]}}]}}`,
+			MissingField: "data->children[0]->data->crosspost_parent_list[0]->url",
+		},
+		{
+			TestName:     "Gallery Without Media",
+			Root:         `{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "a", "gallery_data": {"items": [{"media_id": "abc"}]}, "media_metadata": {}}}]}}`,
+			MissingField: "media_metadata->abc",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			var root apiListing[apiLink]
+			err := json.Unmarshal([]byte(test.Root), &root)
+			assert.NoError(t, err, "not expecting error when decoding sample root")
+			_, fetchError := (&Oauth{}).getPost("", root)
+			assert.Equal(t, missingFieldError(test.MissingField), fetchError)
+		})
+	}
+}
+
+func TestGetPostPoll(t *testing.T) {
+	root := `{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Which one?", "selftext": "Vote &amp; comment", "post_hint": "self", "poll_data": {"options": [{"id": "1", "text": "Cats", "vote_count": 30}, {"id": "2", "text": "Dogs &amp; birds", "vote_count": 10}], "total_vote_count": 40, "voting_end_timestamp": 1700000000000}}}]}}`
+	var listing apiListing[apiLink]
+	assert.NoError(t, json.Unmarshal([]byte(root), &listing))
+	result, fetchError := (&Oauth{}).getPost("", listing)
+	assert.Nil(t, fetchError)
+	assert.Equal(t, FetchResultPoll{
+		Title:       "Which one?",
+		Description: "Vote &amp; comment",
+		Options: []FetchResultPollOption{
+			{Text: "Cats", Votes: 30},
+			{Text: "Dogs & birds", Votes: 10},
+		},
+		TotalVotes: 40,
+		EndTime:    time.UnixMilli(1700000000000).UTC(),
+	}, result)
+	// Open polls might not have the vote counts
+	root = `{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Which one?", "selftext": "", "poll_data": {"options": [{"id": "1", "text": "Cats"}, {"id": "2", "text": "Dogs"}], "total_vote_count": 40}}}]}}`
+	listing = apiListing[apiLink]{}
+	assert.NoError(t, json.Unmarshal([]byte(root), &listing))
+	result, fetchError = (&Oauth{}).getPost("", listing)
+	assert.Nil(t, fetchError)
+	poll := result.(FetchResultPoll)
+	assert.False(t, poll.HasVotes())
+	assert.False(t, poll.Ended())
+}
+
+func TestFetchResultCacheTTL(t *testing.T) {
+	o := &Oauth{fetchCacheTTL: 5 * time.Minute}
+	tests := []struct {
+		TestName string
+		Result   interface{}
+		Expected time.Duration
+	}{
+		{"Text", FetchResultText{Title: "title"}, 5 * time.Minute},
+		{"Open Poll", FetchResultPoll{EndTime: time.Now().Add(time.Hour)}, pollFetchCacheTTL},
+		{"Poll Without End", FetchResultPoll{}, pollFetchCacheTTL},
+		{"Ended Poll", FetchResultPoll{EndTime: time.Now().Add(-time.Hour)}, 5 * time.Minute},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Equal(t, test.Expected, o.fetchResultCacheTTL(test.Result))
+		})
+	}
+	// A shorter TTL is not made longer
+	o.fetchCacheTTL = time.Second
+	assert.Equal(t, time.Second, o.fetchResultCacheTTL(FetchResultPoll{}))
+}
+
+func TestGetPostFlags(t *testing.T) {
+	oldDenyNsfw := denyNsfw
+	denyNsfw = false
+	defer func() { denyNsfw = oldDenyNsfw }()
+	getResult := func(root string) interface{} {
+		var listing apiListing[apiLink]
+		assert.NoError(t, json.Unmarshal([]byte(root), &listing))
+		result, fetchError := (&Oauth{}).getPost("", listing)
+		assert.Nil(t, fetchError)
+		return result
+	}
+	// Spoiler text posts
+	result := getResult(`{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Ending", "selftext": "He dies", "spoiler": true}}]}}`)
+	assert.Equal(t, FetchResultText{Title: "Ending", Text: "He dies", Spoiler: true}, result)
+	// Spoiler media
+	result = getResult(`{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Ending", "post_hint": "link", "url": "https://i.imgur.com/a.gifv", "spoiler": true}}]}}`)
+	media := result.(FetchResultMedia)
+	assert.True(t, media.Spoiler)
+	assert.False(t, media.NSFW)
+	// The crossposted post is NSFW
+	result = getResult(`{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Crosspost", "crosspost_parent_list": [{"title": "NSFW", "post_hint": "link", "url": "https://i.imgur.com/a.gifv", "over_18": true}]}}]}}`)
+	media = result.(FetchResultMedia)
+	assert.False(t, media.Spoiler)
+	assert.True(t, media.NSFW)
+}
+
+func TestGetPostLink(t *testing.T) {
+	var listing apiListing[apiLink]
+	assert.NoError(t, json.Unmarshal([]byte(`{"kind": "Listing", "data": {"children": [{"kind": "t3", "data": {"title": "Article &amp; more", "post_hint": "link", "url": "https://example.com/article?a=1&amp;b=2"}}]}}`), &listing))
+	result, fetchError := (&Oauth{}).getPost("", listing)
+	assert.Nil(t, fetchError)
+	// The title is not repeated in the text
+	assert.Equal(t, FetchResultText{Title: "Article & more", Text: "https://example.com/article?a=1&b=2"}, result)
+}
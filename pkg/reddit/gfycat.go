@@ -0,0 +1,53 @@
+package reddit
+
+import (
+	"context"
+	"net/url"
+)
+
+// gfycatExtractor gets the media of Gfycat. Gfycat does not exist anymore, so the media is taken
+// from the preview which Reddit has made of it.
+type gfycatExtractor struct{}
+
+func (gfycatExtractor) Match(link *url.URL) bool {
+	return hostIs(link, "gfycat.com")
+}
+
+func (gfycatExtractor) Extract(_ context.Context, request ExtractRequest) (FetchResultMedia, error) {
+	path := request.Path + "->preview"
+	if request.Preview == nil {
+		return FetchResultMedia{}, missingFieldError(path)
+	}
+	if len(request.Preview.Images) == 0 {
+		return FetchResultMedia{}, missingFieldError(path + "->images[0]")
+	}
+	// Just act like gif
+	if image := request.Preview.Images[0]; image.Variants.MP4 != nil {
+		qualities, fetchError := extractPhotoGifQualities(*image.Variants.MP4, path+"->images[0]->variants->mp4")
+		if fetchError != nil {
+			return FetchResultMedia{}, fetchError
+		}
+		return FetchResultMedia{
+			Medias: qualities,
+			Type:   FetchResultMediaTypeGif,
+		}, nil
+	}
+	// Check reddit_video_preview
+	if vid := request.Preview.RedditVideoPreview; vid != nil && vid.FallbackURL != nil && vid.DashURL != nil {
+		qualities, err := extractVideoQualities(*vid.DashURL)
+		if err != nil {
+			return FetchResultMedia{}, &FetchError{
+				NormalError: "Unable to get the qualities for Gfycat. The original link: " + request.PostURL + ". Error encountered: " + err.Error(),
+				BotError:    "Unable to get the video.\nHere is the link:" + *vid.FallbackURL,
+			}
+		}
+		return FetchResultMedia{
+			Medias: qualities,
+			Type:   FetchResultMediaTypeVideo,
+		}, nil
+	}
+	return FetchResultMedia{}, &FetchError{
+		NormalError: "Unable to get the media from Gfycat. The original link: " + request.PostURL,
+		BotError:    "Unable to get the video.\nHere is the link:" + request.URL,
+	}
+}
@@ -0,0 +1,39 @@
+package reddit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGfycatExtractor(t *testing.T) {
+	const preview = `{"images": [{"source": {"url": "https://external-preview.redd.it/a.gif", "width": 400, "height": 300}, "resolutions": [], "variants": {"mp4": {
+		"source": {"url": "https://external-preview.redd.it/a.gif?format=mp4&amp;s=1", "width": 400, "height": 300},
+		"resolutions": [{"url": "https://external-preview.redd.it/a.gif?width=108&amp;format=mp4&amp;s=2", "width": 108, "height": 81}]
+	}}}]}`
+	var request ExtractRequest
+	assert.NoError(t, json.Unmarshal([]byte(preview), &request.Preview))
+	request.URL = "https://gfycat.com/a"
+	result, err := gfycatExtractor{}.Extract(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, FetchResultMedia{
+		Medias: FetchResultMediaEntries{
+			{
+				Link:    "https://external-preview.redd.it/a.gif?format=mp4&s=1",
+				Quality: "400×300",
+				Dim:     Dimension{Width: 400, Height: 300},
+			},
+			{
+				Link:    "https://external-preview.redd.it/a.gif?width=108&format=mp4&s=2",
+				Quality: "108×81",
+				Dim:     Dimension{Width: 108, Height: 81},
+			},
+		},
+		Type: FetchResultMediaTypeGif,
+	}, result)
+	// The media must be in the preview
+	_, err = gfycatExtractor{}.Extract(context.Background(), ExtractRequest{URL: "https://gfycat.com/a", Path: "data"})
+	assert.Equal(t, missingFieldError("data->preview"), err)
+}
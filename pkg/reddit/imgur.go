@@ -0,0 +1,40 @@
+package reddit
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// imgurExtractor gets the GIFs of Imgur. The .gifv links are the pages of mp4 files and the .gif
+// files are downloaded from their download links.
+type imgurExtractor struct{}
+
+func (imgurExtractor) Match(link *url.URL) bool {
+	return strings.EqualFold(link.Hostname(), "i.imgur.com") &&
+		(strings.HasSuffix(link.Path, ".gifv") || strings.HasSuffix(link.Path, ".gif"))
+}
+
+func (imgurExtractor) Extract(_ context.Context, request ExtractRequest) (FetchResultMedia, error) {
+	link, err := url.Parse(request.URL)
+	if err != nil {
+		return FetchResultMedia{}, err
+	}
+	if strings.HasSuffix(link.Path, ".gifv") {
+		// Example: https://i.imgur.com/a.gifv
+		link.Path = strings.TrimSuffix(link.Path, "gifv") + "mp4"
+	} else {
+		// Example: https://www.reddit.com/r/dankmemes/comments/gag117/you_daughter_of_a_bitch_im_in/
+		dir, file := path.Split(link.Path)
+		link.Path = dir + "download/" + file
+	}
+	return FetchResultMedia{
+		Medias: []FetchResultMediaEntry{{
+			Link:    link.String(),
+			Quality: "Imgur",     // It doesn't matter
+			Dim:     Dimension{}, // We cannot get the dimension unless we download it
+		}},
+		Type: FetchResultMediaTypeGif,
+	}, nil
+}
@@ -0,0 +1,151 @@
+package reddit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/go-faster/errors"
+)
+
+// imgurApiPoint is the endpoint of the API of Imgur
+const imgurApiPoint = "https://api.imgur.com/3"
+
+// imgurAlbumPathRegex gets the kind and the ID of the albums from the links like imgur.com/a/<id>
+// and imgur.com/gallery/<title>-<id>
+var imgurAlbumPathRegex = regexp.MustCompile(`^/(a|gallery)/(?:[^/]*-)?([A-Za-z0-9]+)/?$`)
+
+// imgurAlbumExtractor gets the albums and galleries of Imgur. The API of Imgur needs a client ID
+// which is read from IMGUR_CLIENT_ID; Without it, the albums are sent as links.
+type imgurAlbumExtractor struct {
+	// The endpoint of the API. Changed in tests.
+	apiPoint string
+	clientID string
+}
+
+// imgurImage is an image or video in the API of Imgur
+type imgurImage struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// The mime type
+	Type     string `json:"type"`
+	Animated bool   `json:"animated"`
+	HasSound bool   `json:"has_sound"`
+	Link     string `json:"link"`
+	// The mp4 file of the animated images
+	MP4 string `json:"mp4"`
+}
+
+// imgurAlbumResponse is the response of the album/<id> and gallery/<id> endpoints. A gallery
+// might be a single image; In this case, the image is in imgurImage.
+type imgurAlbumResponse struct {
+	Data struct {
+		imgurImage
+		IsAlbum *bool        `json:"is_album"`
+		Images  []imgurImage `json:"images"`
+	} `json:"data"`
+}
+
+// newImgurAlbumExtractor creates the extractor of the Imgur albums with the client ID of the
+// environment. The albums are not matched if IMGUR_CLIENT_ID is not set.
+func newImgurAlbumExtractor() *imgurAlbumExtractor {
+	return &imgurAlbumExtractor{
+		apiPoint: imgurApiPoint,
+		clientID: os.Getenv("IMGUR_CLIENT_ID"),
+	}
+}
+
+func (e *imgurAlbumExtractor) Match(link *url.URL) bool {
+	host := strings.ToLower(link.Hostname())
+	return e.clientID != "" && (host == "imgur.com" || host == "www.imgur.com" || host == "m.imgur.com") &&
+		imgurAlbumPathRegex.MatchString(link.Path)
+}
+
+func (e *imgurAlbumExtractor) Extract(ctx context.Context, request ExtractRequest) (FetchResultMedia, error) {
+	album, err := e.ExtractAlbum(ctx, request)
+	if err != nil {
+		return FetchResultMedia{}, err
+	}
+	if len(album) == 0 {
+		return FetchResultMedia{}, errors.New("the album is empty")
+	}
+	return albumEntryMedia(album[0]), nil
+}
+
+func (e *imgurAlbumExtractor) ExtractAlbum(ctx context.Context, request ExtractRequest) ([]FetchResultAlbumEntry, error) {
+	link, err := url.Parse(request.URL)
+	if err != nil {
+		return nil, err
+	}
+	match := imgurAlbumPathRegex.FindStringSubmatch(link.Path)
+	if match == nil {
+		return nil, errors.New("no imgur album id in " + request.URL)
+	}
+	endpoint := "/album/"
+	if match[1] == "gallery" {
+		endpoint = "/gallery/"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.apiPoint+endpoint+match[2], nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create the request")
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Authorization", "Client-ID "+e.clientID)
+	resp, err := request.httpClient().Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get the album")
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, &FetchError{
+			NormalError: "",
+			BotError:    "This album is deleted from Imgur.",
+		}
+	default:
+		return nil, errors.New("unexpected status " + resp.Status + " from imgur")
+	}
+	var body imgurAlbumResponse
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, errors.Wrap(err, "cannot parse the album")
+	}
+	images := body.Data.Images
+	if body.Data.IsAlbum != nil && !*body.Data.IsAlbum {
+		// The gallery is a single image
+		images = []imgurImage{body.Data.imgurImage}
+	}
+	album := make([]FetchResultAlbumEntry, 0, len(images))
+	for _, image := range images {
+		if entry, ok := imgurAlbumEntry(image); ok {
+			album = append(album, entry)
+		}
+	}
+	if len(album) == 0 {
+		return nil, errors.New("imgur album " + match[2] + " does not have any media")
+	}
+	return album, nil
+}
+
+// imgurAlbumEntry converts an image of Imgur to a media of an album. The animated images and the
+// videos without sound are gifs. ok is false if the image does not have a link.
+func imgurAlbumEntry(image imgurImage) (entry FetchResultAlbumEntry, ok bool) {
+	entry.Caption = strings.TrimSpace(strings.TrimSpace(image.Title) + "\n" + strings.TrimSpace(image.Description))
+	entry.Link = image.Link
+	switch {
+	case strings.HasPrefix(image.Type, "video/") && image.HasSound:
+		entry.Type = FetchResultMediaTypeVideo
+	case strings.HasPrefix(image.Type, "video/") || image.Animated:
+		entry.Type = FetchResultMediaTypeGif
+		if image.MP4 != "" {
+			entry.Link = image.MP4
+		}
+	default:
+		entry.Type = FetchResultMediaTypePhoto
+	}
+	return entry, entry.Link != ""
+}
@@ -0,0 +1,89 @@
+package reddit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The responses of the API of Imgur, shortened
+const (
+	imgurAlbumJSON = `{"data":{"id":"abc","title":"My cats","description":null,"images_count":3,"is_album":true,"images":[` +
+		`{"id":"a","title":"Sleeping","description":"On the sofa","type":"image/jpeg","animated":false,"has_sound":false,"link":"https://i.imgur.com/a.jpeg"},` +
+		`{"id":"b","title":null,"description":null,"type":"image/gif","animated":true,"has_sound":false,"link":"https://i.imgur.com/b.gif","mp4":"https://i.imgur.com/b.mp4","gifv":"https://i.imgur.com/b.gifv"},` +
+		`{"id":"c","title":"Meowing","description":null,"type":"video/mp4","animated":true,"has_sound":true,"link":"https://i.imgur.com/c.mp4","mp4":"https://i.imgur.com/c.mp4"}` +
+		`]},"success":true,"status":200}`
+	imgurGalleryImageJSON = `{"data":{"id":"def","title":"A dog","description":null,"type":"image/png","animated":false,"has_sound":false,"link":"https://i.imgur.com/def.png","is_album":false},"success":true,"status":200}`
+)
+
+func TestImgurAlbumExtractorMatch(t *testing.T) {
+	tests := []struct {
+		Link  string
+		Match bool
+	}{
+		{"https://imgur.com/a/abc", true},
+		{"https://www.imgur.com/a/abc/", true},
+		{"https://m.imgur.com/gallery/abc", true},
+		{"https://imgur.com/gallery/my-cats-abc", true},
+		{"https://imgur.com/abc", false},
+		{"https://i.imgur.com/a/abc", false},
+		{"https://example.com/a/abc", false},
+	}
+	for _, test := range tests {
+		link, err := url.Parse(test.Link)
+		assert.NoError(t, err)
+		assert.Equal(t, test.Match, (&imgurAlbumExtractor{clientID: "id"}).Match(link), test.Link)
+		// Nothing is matched without a client ID
+		assert.False(t, (&imgurAlbumExtractor{}).Match(link), test.Link)
+	}
+}
+
+func TestImgurAlbumExtractor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Client-ID id" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/3/album/abc":
+			_, _ = w.Write([]byte(imgurAlbumJSON))
+		case "/3/gallery/def":
+			_, _ = w.Write([]byte(imgurGalleryImageJSON))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"data":{"error":"Unable to find an album with the id"},"success":false,"status":404}`))
+		}
+	}))
+	defer server.Close()
+	extractor := &imgurAlbumExtractor{apiPoint: server.URL + "/3", clientID: "id"}
+	extract := func(link string) ([]FetchResultAlbumEntry, error) {
+		return extractor.ExtractAlbum(context.Background(), ExtractRequest{URL: link, Client: server.Client()})
+	}
+	// Album
+	album, err := extract("https://imgur.com/a/abc")
+	assert.NoError(t, err)
+	assert.Equal(t, []FetchResultAlbumEntry{
+		{Link: "https://i.imgur.com/a.jpeg", Caption: "Sleeping\nOn the sofa", Type: FetchResultMediaTypePhoto},
+		{Link: "https://i.imgur.com/b.mp4", Type: FetchResultMediaTypeGif},
+		{Link: "https://i.imgur.com/c.mp4", Caption: "Meowing", Type: FetchResultMediaTypeVideo},
+	}, album)
+	// A gallery of a single image
+	album, err = extract("https://imgur.com/gallery/a-dog-def")
+	assert.NoError(t, err)
+	assert.Equal(t, []FetchResultAlbumEntry{
+		{Link: "https://i.imgur.com/def.png", Caption: "A dog", Type: FetchResultMediaTypePhoto},
+	}, album)
+	media, err := extractor.Extract(context.Background(), ExtractRequest{URL: "https://imgur.com/gallery/def"})
+	assert.NoError(t, err)
+	assert.Equal(t, FetchResultMedia{
+		Medias: FetchResultMediaEntries{{Link: "https://i.imgur.com/def.png", Quality: "Original"}},
+		Type:   FetchResultMediaTypePhoto,
+	}, media)
+	// Deleted
+	_, err = extract("https://imgur.com/a/deleted")
+	assert.Equal(t, &FetchError{BotError: "This album is deleted from Imgur."}, err)
+}
@@ -0,0 +1,55 @@
+package reddit
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImgurExtractor(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Link     string
+		Match    bool
+		Expected string
+	}{
+		{
+			TestName: "Gifv",
+			Link:     "https://i.imgur.com/a.gifv",
+			Match:    true,
+			Expected: "https://i.imgur.com/a.mp4",
+		},
+		{
+			TestName: "Gif",
+			Link:     "https://i.imgur.com/QdBe1Vw.gif",
+			Match:    true,
+			Expected: "https://i.imgur.com/download/QdBe1Vw.gif",
+		},
+		{
+			TestName: "Image",
+			Link:     "https://i.imgur.com/cP5n0Kz.jpg",
+		},
+		{
+			TestName: "Other host",
+			Link:     "https://example.com/a.gifv",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			link, err := url.Parse(test.Link)
+			assert.NoError(t, err)
+			assert.Equal(t, test.Match, imgurExtractor{}.Match(link))
+			if !test.Match {
+				return
+			}
+			result, err := imgurExtractor{}.Extract(context.Background(), ExtractRequest{URL: test.Link})
+			assert.NoError(t, err)
+			assert.Equal(t, FetchResultMedia{
+				Medias: FetchResultMediaEntries{{Link: test.Expected, Quality: "Imgur"}},
+				Type:   FetchResultMediaTypeGif,
+			}, result)
+		})
+	}
+}
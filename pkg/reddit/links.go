@@ -0,0 +1,44 @@
+package reddit
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// linkHosts are the hosts of the links which StartFetch supports
+var linkHosts = map[string]bool{
+	"www.reddit.com": true,
+	"reddit.com":     true,
+	"old.reddit.com": true,
+	"redd.it":        true,
+	"v.redd.it":      true,
+}
+
+// ExtractLinks finds the links to Reddit posts and comments in a text in their order. There can
+// be several links in a line and the links do not need a scheme. Duplicate links are removed.
+func ExtractLinks(text string) []string {
+	seen := make(map[string]bool)
+	var links []string
+	for _, field := range strings.FieldsFunc(text, isLinkSeparator) {
+		// Links at the end of sentences
+		link := strings.TrimRight(field, ".,;:!?")
+		if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+			link = "https://" + link
+		}
+		u, err := url.Parse(link)
+		if err != nil || !linkHosts[strings.ToLower(u.Host)] || strings.Trim(u.Path, "/") == "" {
+			continue
+		}
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// isLinkSeparator checks if a character cannot be in the links which ExtractLinks finds
+func isLinkSeparator(c rune) bool {
+	return unicode.IsSpace(c) || strings.ContainsRune(`()<>[]{}"'«»`, c)
+}
@@ -0,0 +1,50 @@
+package reddit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractLinks(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Text     string
+		Expected []string
+	}{
+		{
+			TestName: "Single link",
+			Text:     "https://www.reddit.com/r/pics/comments/a/title/",
+			Expected: []string{"https://www.reddit.com/r/pics/comments/a/title/"},
+		},
+		{
+			TestName: "Shared with title",
+			Text:     "Post title\nhttps://www.reddit.com/r/pics/comments/a/title/",
+			Expected: []string{"https://www.reddit.com/r/pics/comments/a/title/"},
+		},
+		{
+			TestName: "Several links in a line",
+			Text:     "https://redd.it/a, reddit.com/r/pics/comments/b/title and (https://old.reddit.com/r/pics/comments/c/title/).",
+			Expected: []string{
+				"https://redd.it/a",
+				"https://reddit.com/r/pics/comments/b/title",
+				"https://old.reddit.com/r/pics/comments/c/title/",
+			},
+		},
+		{
+			TestName: "Duplicates",
+			Text:     "https://redd.it/a\nhttps://v.redd.it/b https://redd.it/a",
+			Expected: []string{"https://redd.it/a", "https://v.redd.it/b"},
+		},
+		{
+			TestName: "Other links",
+			Text:     "https://example.com/r/pics https://www.reddit.com/ go.dev",
+			Expected: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			assert.Equal(t, test.Expected, ExtractLinks(test.Text))
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package reddit
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// listingApiPoint is the endpoint format which we should get the listings of subreddits from
+const listingApiPoint = "https://api.reddit.com/r/"
+
+// listingPageLimit is the maximum number of posts which Reddit returns in a page of a listing
+const listingPageLimit = 100
+
+// subredditRegex matches the valid names of subreddits
+var subredditRegex = regexp.MustCompile(`^[A-Za-z0-9_]{2,21}$`)
+
+// ListingSort is the sort of the posts of a subreddit listing
+type ListingSort string
+
+const (
+	ListingSortTop ListingSort = "top"
+	ListingSortHot ListingSort = "hot"
+	ListingSortNew ListingSort = "new"
+)
+
+// ListingPeriods are the periods which the top posts can be sorted in
+var ListingPeriods = []string{"hour", "day", "week", "month", "year", "all"}
+
+// GetListing gets a page of a listing of a subreddit. period is only used for the top posts and
+// after is the fullname of the last post of the previous page.
+func (o *Oauth) GetListing(subreddit string, sort ListingSort, period string, limit int, after string) (apiListing[apiLink], error) {
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if sort == ListingSortTop && period != "" {
+		query.Set("t", period)
+	}
+	if after != "" {
+		query.Set("after", after)
+	}
+	var listing apiListing[apiLink]
+	err := o.doGetJsonRequest(listingApiPoint+subreddit+"/"+string(sort)+"?"+query.Encode(), &listing)
+	return listing, err
+}
+
+// FetchListing gets the links of at most limit posts of a subreddit listing. The pages of the
+// listing are requested until there are enough posts or the listing ends. The pinned posts
+// are skipped and so are the NSFW posts if they are not allowed.
+func (o *Oauth) FetchListing(subreddit string, sort ListingSort, period string, limit int) ([]string, *FetchError) {
+	subreddit = strings.TrimPrefix(strings.TrimPrefix(subreddit, "/"), "r/")
+	if !subredditRegex.MatchString(subreddit) {
+		return nil, &FetchError{BotError: "Invalid subreddit name"}
+	}
+	var links []string
+	after := ""
+	for len(links) < limit {
+		listing, err := o.GetListing(subreddit, sort, period, min(limit-len(links), listingPageLimit), after)
+		if err != nil {
+			return nil, &FetchError{
+				NormalError: "Unable to get the listing: " + err.Error(),
+				BotError:    "Unable to get the posts of the subreddit",
+			}
+		}
+		if listing.Data == nil {
+			return nil, missingFieldError("data")
+		}
+		links = append(links, getListingLinks(listing, limit-len(links))...)
+		after = listing.Data.After
+		if after == "" || len(listing.Data.Children) == 0 {
+			break
+		}
+	}
+	return links, nil
+}
+
+// getListingLinks gets the links of at most limit posts of a page of a listing
+func getListingLinks(listing apiListing[apiLink], limit int) []string {
+	var links []string
+	for _, child := range listing.Data.Children {
+		if len(links) == limit {
+			break
+		}
+		post := child.Data
+		if child.Kind != "t3" || post == nil || post.Permalink == "" || post.Stickied || (post.Over18 && denyNsfw) {
+			continue
+		}
+		links = append(links, "https://www.reddit.com"+post.Permalink)
+	}
+	return links
+}
@@ -0,0 +1,34 @@
+package reddit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetListingLinks(t *testing.T) {
+	const page = `{"kind":"Listing","data":{"after":"t3_d","children":[
+		{"kind":"t3","data":{"permalink":"/r/pics/comments/a/pinned/","stickied":true}},
+		{"kind":"t3","data":{"permalink":"/r/pics/comments/b/first/"}},
+		{"kind":"t3","data":{"permalink":"/r/pics/comments/c/second/","over_18":true}},
+		{"kind":"t3","data":{"permalink":"/r/pics/comments/d/third/"}}
+	]}}`
+	var listing apiListing[apiLink]
+	assert.NoError(t, json.Unmarshal([]byte(page), &listing))
+	assert.Equal(t, "t3_d", listing.Data.After)
+	assert.Equal(t, []string{
+		"https://www.reddit.com/r/pics/comments/b/first/",
+		"https://www.reddit.com/r/pics/comments/c/second/",
+		"https://www.reddit.com/r/pics/comments/d/third/",
+	}, getListingLinks(listing, 10))
+	assert.Equal(t, []string{"https://www.reddit.com/r/pics/comments/b/first/"}, getListingLinks(listing, 1))
+}
+
+func TestFetchListingInvalidSubreddit(t *testing.T) {
+	var o Oauth
+	for _, subreddit := range []string{"", "a", "r/pics/../../api", "pics?limit=100"} {
+		_, fetchError := o.FetchListing(subreddit, ListingSortHot, "", 10)
+		assert.NotNil(t, fetchError, subreddit)
+	}
+}
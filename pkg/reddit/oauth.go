@@ -43,6 +43,9 @@ type Oauth struct {
 	rateLimitFreedom int64
 	// The HTTP client for Imgur downloads (might use proxy)
 	imgurHTTPClient *http.Client
+	// If not nil, the results of StartFetch are cached in it for fetchCacheTTL
+	fetchCache    FetchResultCache
+	fetchCacheTTL time.Duration
 }
 
 // tokenRequestResponse is the result of https://www.reddit.com/api/v1/access_token endpoint
@@ -77,6 +80,13 @@ func NewRedditOauth(clientId, clientSecret string) (*Oauth, error) {
 	return redditOauth, nil
 }
 
+// SetFetchResultCache makes StartFetch cache its results in cache for ttl.
+// This must be called before using the Oauth.
+func (o *Oauth) SetFetchResultCache(cache FetchResultCache, ttl time.Duration) {
+	o.fetchCache = cache
+	o.fetchCacheTTL = ttl
+}
+
 // tokenRefresh refreshes the
 func (o *Oauth) tokenRefresh(nextRefresh time.Duration) {
 	for {
@@ -129,13 +139,17 @@ func (o *Oauth) createToken() (time.Duration, error) {
 }
 
 // GetComment gets the info about a comment from reddit
-func (o *Oauth) GetComment(id string) (map[string]interface{}, error) {
-	return o.doGetJsonRequest(commentApiPoint + id)
+func (o *Oauth) GetComment(id string) (apiListing[apiComment], error) {
+	var listing apiListing[apiComment]
+	err := o.doGetJsonRequest(commentApiPoint+id, &listing)
+	return listing, err
 }
 
 // GetPost gets the info about a post from reddit
-func (o *Oauth) GetPost(id string) (map[string]interface{}, error) {
-	return o.doGetJsonRequest(postApiPoint + id)
+func (o *Oauth) GetPost(id string) (apiListing[apiLink], error) {
+	var listing apiListing[apiLink]
+	err := o.doGetJsonRequest(postApiPoint+id, &listing)
+	return listing, err
 }
 
 // FollowRedirect follows a page's redirect and returns the final URL
@@ -148,34 +162,37 @@ func (o *Oauth) FollowRedirect(u string) (string, error) {
 	return resp.Request.URL.String(), nil
 }
 
-func (o *Oauth) doGetJsonRequest(Url string) (map[string]interface{}, error) {
+// doGetJsonRequest sends a get request to Url and decodes the JSON response into result
+func (o *Oauth) doGetJsonRequest(Url string, result interface{}) error {
 	// Check rate limit
 	if time.Now().Unix() < atomic.LoadInt64(&o.rateLimitFreedom) {
-		return nil, RateLimitErr
+		return RateLimitErr
 	}
 	// Build the request
 	req, err := http.NewRequest("GET", Url, nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "cannot create request")
+		return errors.Wrap(err, "cannot create request")
 	}
 	req.Header.Set("User-Agent", userAgent)
 	req.Header.Set("Authorization", o.authorizationHeader)
 	// Do the request
 	resp, err := common.GlobalHttpClient.Do(req)
 	if err != nil {
-		return nil, errors.Wrap(err, "cannot do the request")
+		return errors.Wrap(err, "cannot do the request")
 	}
 	defer resp.Body.Close()
 	// Check the rate limit
 	if rateLimit, err := strconv.Atoi(resp.Header.Get("X-Ratelimit-Remaining")); err == nil && rateLimit == 0 {
 		freedom, _ := strconv.Atoi(resp.Header.Get("X-Ratelimit-Reset"))
 		atomic.StoreInt64(&o.rateLimitFreedom, time.Now().Unix()+int64(freedom))
-		return nil, RateLimitErr
+		return RateLimitErr
 	}
 	// Read the body
-	var responseBody map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&responseBody)
-	return responseBody, err
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return errors.Wrap(err, "cannot parse the response")
+	}
+	return nil
 }
 
 // head will do a head request. Useful to check redirects
@@ -208,14 +225,7 @@ func (o *Oauth) downloadToFile(link string, f *os.File) error {
 		return errors.Wrap(err, "cannot create request")
 	}
 	req.Header.Set("User-Agent", userAgent)
-	// Check Imgur and proxy
-	var client *http.Client
-	if o.imgurHTTPClient != nil && util.IsImgurLink(link) {
-		client = o.imgurHTTPClient
-	} else {
-		client = &common.GlobalHttpClient
-	}
-	resp, err := client.Do(req)
+	resp, err := o.downloadHttpClientFor(link).Do(req)
 	if err != nil {
 		return err
 	}
@@ -232,3 +242,12 @@ func (o *Oauth) downloadToFile(link string, f *os.File) error {
 	_, err = io.Copy(f, resp.Body)
 	return err
 }
+
+// httpClientFor gets the HTTP client which a link must be requested with.
+// The Imgur links might need to go through the proxy.
+func (o *Oauth) httpClientFor(link string) *http.Client {
+	if o != nil && o.imgurHTTPClient != nil && util.IsImgurLink(link) {
+		return o.imgurHTTPClient
+	}
+	return &common.GlobalHttpClient
+}
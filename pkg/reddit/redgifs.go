@@ -0,0 +1,188 @@
+package reddit
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-faster/errors"
+)
+
+// redgifsApiPoint is the endpoint of the API of Redgifs
+const redgifsApiPoint = "https://api.redgifs.com/v2"
+
+// redgifsTokenLifetime is how long a temporary token of Redgifs is used. The tokens are valid for
+// about a day, so they are renewed sooner than that.
+const redgifsTokenLifetime = 12 * time.Hour
+
+// redgifsIDRegex gets the ID of a gif from the links like redgifs.com/watch/<id>,
+// redgifs.com/ifr/<id> and i.redgifs.com/i/<id>.jpg
+var redgifsIDRegex = regexp.MustCompile(`^/(?:watch|ifr|i)/([A-Za-z]+)`)
+
+// redgifsUnauthorizedErr is returned when Redgifs does not accept the token
+var redgifsUnauthorizedErr = errors.New("redgifs token is not accepted")
+
+// redgifsExtractor gets the videos and gifs of Redgifs. Its API needs a temporary token which is
+// shared between the requests until it expires.
+type redgifsExtractor struct {
+	// The endpoint of the API. Changed in tests.
+	apiPoint string
+	// The token and when it expires
+	token          string
+	tokenExpiresAt time.Time
+	tokenMutex     sync.Mutex
+}
+
+// redgifsGifResponse is the response of the gifs/<id> endpoint
+type redgifsGifResponse struct {
+	Gif struct {
+		Duration float64 `json:"duration"`
+		Width    int64   `json:"width"`
+		Height   int64   `json:"height"`
+		HasAudio bool    `json:"hasAudio"`
+		URLs     struct {
+			HD string `json:"hd"`
+			SD string `json:"sd"`
+		} `json:"urls"`
+	} `json:"gif"`
+}
+
+func (*redgifsExtractor) Match(link *url.URL) bool {
+	return hostIs(link, "redgifs.com") && redgifsIDRegex.MatchString(link.Path)
+}
+
+func (e *redgifsExtractor) Extract(ctx context.Context, request ExtractRequest) (FetchResultMedia, error) {
+	// Everything in Redgifs is NSFW. Even if the post is not marked.
+	if denyNsfw {
+		return FetchResultMedia{}, nsfwNotAllowedErr
+	}
+	link, err := url.Parse(request.URL)
+	if err != nil {
+		return FetchResultMedia{}, err
+	}
+	match := redgifsIDRegex.FindStringSubmatch(link.Path)
+	if match == nil {
+		return FetchResultMedia{}, errors.New("no redgifs id in " + request.URL)
+	}
+	id := strings.ToLower(match[1])
+	gif, err := e.getGif(ctx, request.httpClient(), id)
+	if errors.Is(err, redgifsUnauthorizedErr) {
+		// The token might have been revoked
+		e.resetToken()
+		gif, err = e.getGif(ctx, request.httpClient(), id)
+	}
+	if err != nil {
+		return FetchResultMedia{}, err
+	}
+	result := FetchResultMedia{
+		Duration: int64(math.Round(gif.Gif.Duration)),
+		Type:     FetchResultMediaTypeVideo,
+		NSFW:     true,
+	}
+	// Gifs of Redgifs are videos without audio
+	if !gif.Gif.HasAudio {
+		result.Type = FetchResultMediaTypeGif
+	}
+	if gif.Gif.URLs.HD != "" {
+		result.Medias = append(result.Medias, FetchResultMediaEntry{
+			Link:    gif.Gif.URLs.HD,
+			Quality: "HD " + strconv.FormatInt(gif.Gif.Width, 10) + "×" + strconv.FormatInt(gif.Gif.Height, 10),
+			Dim:     Dimension{Width: gif.Gif.Width, Height: gif.Gif.Height},
+		})
+	}
+	if gif.Gif.URLs.SD != "" && gif.Gif.URLs.SD != gif.Gif.URLs.HD {
+		result.Medias = append(result.Medias, FetchResultMediaEntry{
+			Link:    gif.Gif.URLs.SD,
+			Quality: "SD",
+			Dim:     Dimension{}, // Redgifs does not give the dimension of SD files
+		})
+	}
+	if len(result.Medias) == 0 {
+		return FetchResultMedia{}, errors.New("redgifs gif " + id + " does not have any file")
+	}
+	return result, nil
+}
+
+// getGif gets the information of a gif with client
+func (e *redgifsExtractor) getGif(ctx context.Context, client *http.Client, id string) (redgifsGifResponse, error) {
+	var result redgifsGifResponse
+	token, err := e.getToken(ctx, client)
+	if err != nil {
+		return result, errors.Wrap(err, "cannot get redgifs token")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.apiPoint+"/gifs/"+url.PathEscape(id), nil)
+	if err != nil {
+		return result, errors.Wrap(err, "cannot create the request")
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return result, errors.Wrap(err, "cannot get the gif")
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return result, redgifsUnauthorizedErr
+	case http.StatusNotFound, http.StatusGone:
+		return result, &FetchError{
+			NormalError: "",
+			BotError:    "This media is deleted from Redgifs.",
+		}
+	default:
+		return result, errors.New("unexpected status " + resp.Status + " from redgifs")
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, errors.Wrap(err, "cannot parse the gif")
+	}
+	return result, nil
+}
+
+// getToken gets the current temporary token or a new one with client if it has expired
+func (e *redgifsExtractor) getToken(ctx context.Context, client *http.Client) (string, error) {
+	e.tokenMutex.Lock()
+	defer e.tokenMutex.Unlock()
+	if e.token != "" && time.Now().Before(e.tokenExpiresAt) {
+		return e.token, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.apiPoint+"/auth/temporary", nil)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot create the request")
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("unexpected status " + resp.Status)
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", errors.Wrap(err, "cannot parse the token")
+	}
+	if body.Token == "" {
+		return "", errors.New("empty token")
+	}
+	e.token = body.Token
+	e.tokenExpiresAt = time.Now().Add(redgifsTokenLifetime)
+	return e.token, nil
+}
+
+// resetToken forgets the current token, so a new one is received on the next request
+func (e *redgifsExtractor) resetToken() {
+	e.tokenMutex.Lock()
+	e.token = ""
+	e.tokenMutex.Unlock()
+}
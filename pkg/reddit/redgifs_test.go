@@ -0,0 +1,117 @@
+package reddit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The responses of the API of Redgifs, shortened
+const (
+	redgifsGifJSON   = `{"gif":{"id":"happygreencat","client_id":null,"createDate":1700000000,"hasAudio":false,"width":1080,"height":1920,"likes":120,"tags":["Cute"],"verified":false,"views":5000,"duration":9.24,"published":true,"type":1,"urls":{"silent":"https://media.redgifs.com/HappyGreenCat-silent.mp4","sd":"https://media.redgifs.com/HappyGreenCat-mobile.mp4","hd":"https://media.redgifs.com/HappyGreenCat.mp4","poster":"https://media.redgifs.com/HappyGreenCat-poster.jpg","thumbnail":"https://media.redgifs.com/HappyGreenCat-mobile.jpg"},"userName":"someone","avgColor":"#000000","gallery":null,"hideHome":false,"hideTrending":false,"sexuality":["straight"],"niches":[]},"user":null,"niches":[]}`
+	redgifsVideoJSON = `{"gif":{"id":"loudbluedog","hasAudio":true,"width":1280,"height":720,"duration":30.6,"type":1,"urls":{"sd":"https://media.redgifs.com/LoudBlueDog-mobile.mp4","hd":"https://media.redgifs.com/LoudBlueDog.mp4"}}}`
+)
+
+// newRedgifsServer creates a stand-in for the API of Redgifs. Each token is accepted only
+// acceptedTokens times. The number of the created tokens is written to tokens.
+func newRedgifsServer(acceptedTokens int, tokens *int) *httptest.Server {
+	uses := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/auth/temporary":
+			*tokens++
+			uses = 0
+			_, _ = w.Write([]byte(`{"token":"token` + strconv.Itoa(*tokens) + `"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token"+strconv.Itoa(*tokens) || uses >= acceptedTokens {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"code":"InvalidToken"}}`))
+			return
+		}
+		uses++
+		switch r.URL.Path {
+		case "/v2/gifs/happygreencat":
+			_, _ = w.Write([]byte(redgifsGifJSON))
+		case "/v2/gifs/loudbluedog":
+			_, _ = w.Write([]byte(redgifsVideoJSON))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"NotFound"}}`))
+		}
+	}))
+}
+
+func TestRedgifsExtractorMatch(t *testing.T) {
+	tests := []struct {
+		Link  string
+		Match bool
+	}{
+		{"https://www.redgifs.com/watch/happygreencat", true},
+		{"https://redgifs.com/ifr/happygreencat", true},
+		{"https://v3.redgifs.com/watch/happygreencat#rel=user", true},
+		{"https://i.redgifs.com/i/happygreencat.jpg", true},
+		{"https://www.redgifs.com/users/someone", false},
+		{"https://example.com/watch/happygreencat", false},
+	}
+	for _, test := range tests {
+		link, err := url.Parse(test.Link)
+		assert.NoError(t, err)
+		assert.Equal(t, test.Match, (&redgifsExtractor{}).Match(link), test.Link)
+	}
+}
+
+func TestRedgifsExtractor(t *testing.T) {
+	oldDenyNsfw := denyNsfw
+	denyNsfw = false
+	defer func() { denyNsfw = oldDenyNsfw }()
+	tokens := 0
+	server := newRedgifsServer(2, &tokens)
+	defer server.Close()
+	extractor := &redgifsExtractor{apiPoint: server.URL + "/v2"}
+	extract := func(link string) (FetchResultMedia, error) {
+		return extractor.Extract(context.Background(), ExtractRequest{URL: link, Client: server.Client()})
+	}
+	// Gif
+	result, err := extract("https://www.redgifs.com/watch/HappyGreenCat")
+	assert.NoError(t, err)
+	assert.Equal(t, FetchResultMedia{
+		Medias: FetchResultMediaEntries{
+			{
+				Link:    "https://media.redgifs.com/HappyGreenCat.mp4",
+				Quality: "HD 1080×1920",
+				Dim:     Dimension{Width: 1080, Height: 1920},
+			},
+			{
+				Link:    "https://media.redgifs.com/HappyGreenCat-mobile.mp4",
+				Quality: "SD",
+			},
+		},
+		Duration: 9,
+		Type:     FetchResultMediaTypeGif,
+		NSFW:     true,
+	}, result)
+	// Video with audio. The token is reused.
+	result, err = extract("https://i.redgifs.com/i/loudbluedog.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, FetchResultMediaTypeVideo, result.Type)
+	assert.Equal(t, int64(31), result.Duration)
+	assert.Len(t, result.Medias, 2)
+	assert.Equal(t, 1, tokens)
+	// The token is not accepted anymore, so a new one is received
+	_, err = extract("https://www.redgifs.com/watch/happygreencat")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, tokens)
+	// Deleted
+	_, err = extract("https://www.redgifs.com/watch/deleted")
+	assert.Equal(t, &FetchError{BotError: "This media is deleted from Redgifs."}, err)
+	// NSFW is not allowed
+	denyNsfw = true
+	_, err = extract("https://www.redgifs.com/watch/happygreencat")
+	assert.Equal(t, nsfwNotAllowedErr, err)
+}
@@ -0,0 +1,56 @@
+package reddit
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-faster/errors"
+)
+
+// streamableExtractor gets the videos of Streamable from the og:video tag of their pages.
+// Example: https://streamable.com/u2jzoo
+type streamableExtractor struct{}
+
+func (streamableExtractor) Match(link *url.URL) bool {
+	return hostIs(link, "streamable.com")
+}
+
+func (streamableExtractor) Extract(ctx context.Context, request ExtractRequest) (FetchResultMedia, error) {
+	// Download the source at first
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, request.URL, nil)
+	if err != nil {
+		return FetchResultMedia{}, errors.Wrap(err, "cannot create the request")
+	}
+	source, err := request.httpClient().Do(req)
+	if err != nil {
+		return FetchResultMedia{}, &FetchError{
+			NormalError: "Unable to get the source code of " + request.URL + ": " + err.Error(),
+			BotError:    "Unable to get the source code of " + request.URL,
+		}
+	}
+	defer source.Body.Close()
+	// Get the meta tag og:video
+	doc, err := goquery.NewDocumentFromReader(source.Body)
+	if err != nil {
+		return FetchResultMedia{}, &FetchError{
+			NormalError: "Unable to get the parse code of " + request.URL + ": " + err.Error(),
+			BotError:    "Unable to get the parse code of " + request.URL,
+		}
+	}
+	result := FetchResultMedia{
+		Medias: []FetchResultMediaEntry{{
+			Link:    "",
+			Quality: "streamable",
+			Dim:     Dimension{}, // Nope again. We have to download
+		}},
+		Type: FetchResultMediaTypeVideo,
+	}
+	doc.Find("meta").Each(func(i int, s *goquery.Selection) {
+		if name, _ := s.Attr("property"); name == "og:video" {
+			result.Medias[0].Link, _ = s.Attr("content")
+		}
+	})
+	return result, nil
+}
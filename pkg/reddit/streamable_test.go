@@ -0,0 +1,31 @@
+package reddit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamableExtractor(t *testing.T) {
+	link, _ := url.Parse("https://streamable.com/u2jzoo")
+	assert.True(t, streamableExtractor{}.Match(link))
+	link, _ = url.Parse("https://example.com/u2jzoo")
+	assert.False(t, streamableExtractor{}.Match(link))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html><head>
+<meta property="og:title" content="Video">
+<meta property="og:video" content="https://cdn-cf-east.streamable.com/video/mp4/u2jzoo.mp4">
+</head><body></body></html>`))
+	}))
+	defer server.Close()
+	result, err := streamableExtractor{}.Extract(context.Background(), ExtractRequest{URL: server.URL + "/u2jzoo", Client: server.Client()})
+	assert.NoError(t, err)
+	assert.Equal(t, FetchResultMedia{
+		Medias: FetchResultMediaEntries{{Link: "https://cdn-cf-east.streamable.com/video/mp4/u2jzoo.mp4", Quality: "streamable"}},
+		Type:   FetchResultMediaTypeVideo,
+	}, result)
+}
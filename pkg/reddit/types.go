@@ -1,5 +1,7 @@
 package reddit
 
+import "time"
+
 // DownloadAudioQuality is the string to send to user when they want to download audio of a video
 const DownloadAudioQuality = "Audio"
 
@@ -25,12 +27,31 @@ type FetchResultText struct {
 	Title string
 	// The text
 	Text string
+	// Spoiler says if the post is marked as a spoiler
+	Spoiler bool `json:",omitempty"`
 }
 
 // FetchResultComment is a result of StartFetch which represents a reddit comment text
 type FetchResultComment struct {
 	// The text of comment
 	Text string
+	// The username of the author without u/
+	Author string `json:",omitempty"`
+	Score  int64  `json:",omitempty"`
+	// The fullname of the parent of the comment. It's either a comment (t1_) or the post (t3_).
+	ParentID string `json:",omitempty"`
+	// The fullname of the post which the comment is on
+	LinkID string `json:",omitempty"`
+}
+
+// Comment is a comment of a post with its replies
+type Comment struct {
+	// The username of the author without u/
+	Author string
+	Score  int64
+	// The markdown of the comment
+	Text    string
+	Replies []Comment
 }
 
 // FetchResultMediaEntry contains the quality and the link to a media in reddit
@@ -82,6 +103,25 @@ const (
 	FetchResultMediaTypeVideo
 )
 
+// PostMetadata is the information about a post which can be shown in the captions
+type PostMetadata struct {
+	// The name of the subreddit without r/
+	Subreddit string `json:",omitempty"`
+	// The username of the author without u/
+	Author string `json:",omitempty"`
+	Score  int64  `json:",omitempty"`
+	// The text of the flair of the post
+	Flair string `json:",omitempty"`
+	// When the post was created in unix epoch
+	Created int64 `json:",omitempty"`
+}
+
+// Present checks if the metadata was fetched. The posts which are cached by older versions of the
+// bot do not have any metadata.
+func (m PostMetadata) Present() bool {
+	return m != PostMetadata{}
+}
+
 // FetchResultMedia is the result of the
 type FetchResultMedia struct {
 	// Medias is the list of all available media in different qualities
@@ -97,6 +137,12 @@ type FetchResultMedia struct {
 	Duration int64
 	// Types says what kind of media is this
 	Type FetchResultMediaType
+	// The information about the post
+	Metadata PostMetadata
+	// NSFW says if the post is marked as NSFW (over 18)
+	NSFW bool `json:",omitempty"`
+	// Spoiler says if the post is marked as a spoiler
+	Spoiler bool `json:",omitempty"`
 }
 
 // HasAudio checks if a video does have audio
@@ -127,6 +173,11 @@ type FetchResultAlbumEntry struct {
 	Caption string
 	// Types says what kind of media is this
 	Type FetchResultMediaType
+	// The link of the audio of a video which must be merged with it. Empty if the media
+	// does not have a separate audio.
+	AudioLink string `json:",omitempty"`
+	// Duration of the video. Zero if unknown or the media is not a video.
+	Duration int64 `json:",omitempty"`
 }
 
 // FetchResultAlbum is a result of reddit album
@@ -137,6 +188,111 @@ type FetchResultAlbum struct {
 	Title string
 	// Description is known as selftext in Reddit API
 	Description string
+	// The information about the post
+	Metadata PostMetadata
+	// NSFW says if the post is marked as NSFW (over 18)
+	NSFW bool `json:",omitempty"`
+	// Spoiler says if the post is marked as a spoiler
+	Spoiler bool `json:",omitempty"`
+}
+
+// FetchResultPollOption is an option of a reddit poll
+type FetchResultPollOption struct {
+	// The text of option
+	Text string
+	// Number of votes of this option. Reddit hides the votes of some polls until they end;
+	// In this case, this is -1.
+	Votes int64
+}
+
+// FetchResultPoll is a result of StartFetch which represents a reddit poll
+type FetchResultPoll struct {
+	// Title of the post which is the question of poll
+	Title string
+	// Description is known as selftext in Reddit API
+	Description string
+	// The options of poll in order
+	Options []FetchResultPollOption
+	// Total number of votes
+	TotalVotes int64
+	// When the voting ends or has ended. Zero if unknown.
+	EndTime time.Time
+}
+
+// Ended checks if the voting of poll has ended
+func (p FetchResultPoll) Ended() bool {
+	return !p.EndTime.IsZero() && time.Now().After(p.EndTime)
+}
+
+// HasVotes checks if the votes of all options are known
+func (p FetchResultPoll) HasVotes() bool {
+	for _, option := range p.Options {
+		if option.Votes < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// CachedFetchResult holds a result of StartFetch in a form which can be stored in a cache.
+// Exactly one of the fields is not nil.
+type CachedFetchResult struct {
+	Text    *FetchResultText    `json:",omitempty"`
+	Comment *FetchResultComment `json:",omitempty"`
+	Media   *FetchResultMedia   `json:",omitempty"`
+	Album   *FetchResultAlbum   `json:",omitempty"`
+	Poll    *FetchResultPoll    `json:",omitempty"`
+	// The post URL which StartFetch has returned
+	RealPostUrl string
+}
+
+// NewCachedFetchResult converts a result of StartFetch to CachedFetchResult.
+// Returns false if the type of result is unknown.
+func NewCachedFetchResult(result interface{}, realPostUrl string) (CachedFetchResult, bool) {
+	cached := CachedFetchResult{RealPostUrl: realPostUrl}
+	switch r := result.(type) {
+	case FetchResultText:
+		cached.Text = &r
+	case FetchResultComment:
+		cached.Comment = &r
+	case FetchResultMedia:
+		cached.Media = &r
+	case FetchResultAlbum:
+		cached.Album = &r
+	case FetchResultPoll:
+		cached.Poll = &r
+	default:
+		return CachedFetchResult{}, false
+	}
+	return cached, true
+}
+
+// Result converts the CachedFetchResult back to the result of StartFetch.
+// Returns nil if the cached value is empty.
+func (c CachedFetchResult) Result() interface{} {
+	switch {
+	case c.Text != nil:
+		return *c.Text
+	case c.Comment != nil:
+		return *c.Comment
+	case c.Media != nil:
+		return *c.Media
+	case c.Album != nil:
+		return *c.Album
+	case c.Poll != nil:
+		return *c.Poll
+	}
+	return nil
+}
+
+// FetchResultCache is a cache which the results of StartFetch can be stored in.
+// The keys are the fullnames of posts or comments like t3_abcd.
+type FetchResultCache interface {
+	// SetFetchResultCache sets a key and overwrite any old entries in cache. The entry
+	// must be deleted after ttl.
+	SetFetchResultCache(key string, value CachedFetchResult, ttl time.Duration) error
+	// GetFetchResultCache gets a result without deleting it from cache
+	GetFetchResultCache(key string) (CachedFetchResult, error)
 }
 
 // Dimension of a media
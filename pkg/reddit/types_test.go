@@ -1,8 +1,10 @@
 package reddit
 
 import (
+	"encoding/json"
 	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -355,3 +357,73 @@ func TestFetchedThumbnails_SelectThumbnail(t *testing.T) {
 		})
 	}
 }
+
+func TestCachedFetchResult(t *testing.T) {
+	tests := []struct {
+		Name   string
+		Result interface{}
+	}{
+		{
+			Name:   "Text",
+			Result: FetchResultText{Title: "title", Text: "text"},
+		},
+		{
+			Name:   "Comment",
+			Result: FetchResultComment{Text: "comment"},
+		},
+		{
+			Name: "Media",
+			Result: FetchResultMedia{
+				Medias: FetchResultMediaEntries{{
+					Link:    "https://v.redd.it/a/DASH_720.mp4",
+					Quality: "720p",
+					Dim:     Dimension{Width: 1280, Height: 720},
+				}},
+				ThumbnailLinks: FetchedThumbnails{{Link: "https://preview.redd.it/a.jpg"}},
+				Title:          "title",
+				Duration:       10,
+				Type:           FetchResultMediaTypeVideo,
+			},
+		},
+		{
+			Name: "Album",
+			Result: FetchResultAlbum{
+				Album: []FetchResultAlbumEntry{{
+					Link:    "https://i.redd.it/a.jpg",
+					Caption: "caption",
+					Type:    FetchResultMediaTypePhoto,
+				}},
+				Title: "title",
+			},
+		},
+		{
+			Name: "Poll",
+			Result: FetchResultPoll{
+				Title: "question",
+				Options: []FetchResultPollOption{
+					{Text: "yes", Votes: 10},
+					{Text: "no", Votes: 5},
+				},
+				TotalVotes: 15,
+				EndTime:    time.Unix(1700000000, 0).UTC(),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			cached, ok := NewCachedFetchResult(test.Result, "https://reddit.com/abcd")
+			assert.True(t, ok)
+			// It must survive being stored as json
+			data, err := json.Marshal(cached)
+			assert.NoError(t, err)
+			var decoded CachedFetchResult
+			assert.NoError(t, json.Unmarshal(data, &decoded))
+			assert.Equal(t, test.Result, decoded.Result())
+			assert.Equal(t, "https://reddit.com/abcd", decoded.RealPostUrl)
+		})
+	}
+	// Unknown types
+	_, ok := NewCachedFetchResult(nil, "")
+	assert.False(t, ok)
+	assert.Nil(t, CachedFetchResult{}.Result())
+}
@@ -0,0 +1,134 @@
+package reddit
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// userApiPoint is the endpoint format which we should get the posts of users from
+const userApiPoint = "https://api.reddit.com/user/"
+
+// maxSubmissionPages is the maximum number of pages of the submissions of a user which are
+// requested. Reddit does not return more than 1000 posts in a listing anyway.
+const maxSubmissionPages = 10
+
+// usernameRegex matches the valid usernames of Reddit
+var usernameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+
+// Submission is a media post of a user
+type Submission struct {
+	// The link to the post
+	Link string
+	// Either FetchResultMedia or FetchResultAlbum
+	Result interface{}
+}
+
+// ParseUserSubmittedURL gets the username from a link to the posts of a user like
+// https://www.reddit.com/user/spez/submitted. Returns false if the text is not such a link.
+func ParseUserSubmittedURL(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "http://") && !strings.HasPrefix(text, "https://") {
+		text = "https://" + text
+	}
+	u, err := url.Parse(text)
+	if err != nil || (u.Host != "www.reddit.com" && u.Host != "reddit.com" && u.Host != "old.reddit.com") {
+		return "", false
+	}
+	split := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(split) != 3 || (split[0] != "user" && split[0] != "u") || split[2] != "submitted" {
+		return "", false
+	}
+	return split[1], true
+}
+
+// GetUserSubmissions gets a page of the posts of a user. after is the fullname of the last post
+// of the previous page.
+func (o *Oauth) GetUserSubmissions(username string, limit int, after string) (apiListing[apiLink], error) {
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if after != "" {
+		query.Set("after", after)
+	}
+	var listing apiListing[apiLink]
+	err := o.doGetJsonRequest(userApiPoint+username+"/submitted?"+query.Encode(), &listing)
+	return listing, err
+}
+
+// FetchUserSubmissions gets at most limit media posts of a user from the newest one. The posts
+// which are not media (like texts and polls) and the NSFW posts if they are not allowed are skipped.
+func (o *Oauth) FetchUserSubmissions(username string, limit int) ([]Submission, *FetchError) {
+	username = strings.TrimPrefix(strings.TrimPrefix(username, "/"), "u/")
+	if !usernameRegex.MatchString(username) {
+		return nil, &FetchError{BotError: "Invalid username"}
+	}
+	var submissions []Submission
+	after := ""
+	for page := 0; page < maxSubmissionPages && len(submissions) < limit; page++ {
+		listing, err := o.GetUserSubmissions(username, listingPageLimit, after)
+		if err != nil {
+			return nil, &FetchError{
+				NormalError: "Unable to get the submissions: " + err.Error(),
+				BotError:    "Unable to get the posts of the user",
+			}
+		}
+		if listing.Data == nil {
+			return nil, missingFieldError("data")
+		}
+		submissions = append(submissions, o.getMediaSubmissions(listing, limit-len(submissions))...)
+		after = listing.Data.After
+		if after == "" || len(listing.Data.Children) == 0 {
+			break
+		}
+	}
+	return submissions, nil
+}
+
+// getMediaSubmissions converts the posts of a page of a listing and returns at most limit of the
+// ones which are media or albums
+func (o *Oauth) getMediaSubmissions(listing apiListing[apiLink], limit int) []Submission {
+	var submissions []Submission
+	for _, child := range listing.Data.Children {
+		if len(submissions) == limit {
+			break
+		}
+		if child.Kind != "t3" || child.Data == nil || child.Data.Permalink == "" || !mayBeMedia(child.Data) {
+			continue
+		}
+		link := "https://www.reddit.com" + child.Data.Permalink
+		// getPost skips the NSFW posts as well
+		result, fetchError := o.getPost(link, apiListing[apiLink]{Data: &apiListingData[apiLink]{
+			Children: []apiListingChild[apiLink]{child},
+		}})
+		if fetchError != nil {
+			continue
+		}
+		switch result.(type) {
+		case FetchResultMedia, FetchResultAlbum:
+			submissions = append(submissions, Submission{Link: link, Result: result})
+		}
+	}
+	return submissions
+}
+
+// mayBeMedia checks if a post might be a media or an album only by its type and link. It's used
+// to skip the posts which are surely not media without fetching anything for them.
+func mayBeMedia(post *apiLink) bool {
+	if len(post.CrosspostParentList) != 0 {
+		post = &post.CrosspostParentList[0]
+	}
+	if post.PollData != nil {
+		return false
+	}
+	if post.PostHint == nil {
+		return post.GalleryData != nil && post.MediaMetadata != nil
+	}
+	switch *post.PostHint {
+	case "image", "hosted:video", "gallery":
+		return true
+	case "link", "rich:video":
+		return post.URL != nil && (findExtractor(*post.URL) != nil || isDirectMediaLink(*post.URL))
+	default:
+		return false
+	}
+}
@@ -0,0 +1,152 @@
+package reddit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUserSubmittedURL(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Text     string
+		Username string
+		Ok       bool
+	}{
+		{
+			TestName: "User",
+			Text:     "https://www.reddit.com/user/spez/submitted/",
+			Username: "spez",
+			Ok:       true,
+		},
+		{
+			TestName: "Short",
+			Text:     "reddit.com/u/spez/submitted",
+			Username: "spez",
+			Ok:       true,
+		},
+		{
+			TestName: "Profile",
+			Text:     "https://www.reddit.com/user/spez/",
+		},
+		{
+			TestName: "Post",
+			Text:     "https://www.reddit.com/r/pics/comments/a/submitted/",
+		},
+		{
+			TestName: "Other host",
+			Text:     "https://example.com/user/spez/submitted",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			username, ok := ParseUserSubmittedURL(test.Text)
+			assert.Equal(t, test.Username, username)
+			assert.Equal(t, test.Ok, ok)
+		})
+	}
+}
+
+func TestGetMediaSubmissions(t *testing.T) {
+	const page = `{"kind":"Listing","data":{"after":null,"children":[
+		{"kind":"t3","data":{"title":"Text","selftext":"text","permalink":"/r/a/comments/1/text/"}},
+		{"kind":"t3","data":{"title":"First &amp;amp; foremost","post_hint":"link","url":"https://i.imgur.com/a.gifv","permalink":"/r/a/comments/2/first/"}},
+		{"kind":"t3","data":{"title":"NSFW","post_hint":"link","url":"https://i.imgur.com/b.gifv","over_18":true,"permalink":"/r/a/comments/3/nsfw/"}},
+		{"kind":"t3","data":{"title":"Second","post_hint":"link","url":"https://i.imgur.com/c.gifv","permalink":"/r/a/comments/4/second/"}}
+	]}}`
+	var listing apiListing[apiLink]
+	assert.NoError(t, json.Unmarshal([]byte(page), &listing))
+	oldDenyNsfw := denyNsfw
+	denyNsfw = false
+	defer func() { denyNsfw = oldDenyNsfw }()
+	links := func(submissions []Submission) []string {
+		var result []string
+		for _, submission := range submissions {
+			assert.IsType(t, FetchResultMedia{}, submission.Result)
+			result = append(result, submission.Link)
+		}
+		return result
+	}
+	assert.Equal(t, []string{
+		"https://www.reddit.com/r/a/comments/2/first/",
+		"https://www.reddit.com/r/a/comments/3/nsfw/",
+		"https://www.reddit.com/r/a/comments/4/second/",
+	}, links((&Oauth{}).getMediaSubmissions(listing, 10)))
+	first := (&Oauth{}).getMediaSubmissions(listing, 1)
+	assert.Equal(t, []string{"https://www.reddit.com/r/a/comments/2/first/"}, links(first))
+	// The listings are requested without raw_json, so the titles are escaped once
+	assert.Equal(t, "First &amp; foremost", first[0].Result.(FetchResultMedia).Title)
+	// NSFW posts are skipped if they are not allowed
+	denyNsfw = true
+	assert.Equal(t, []string{
+		"https://www.reddit.com/r/a/comments/2/first/",
+		"https://www.reddit.com/r/a/comments/4/second/",
+	}, links((&Oauth{}).getMediaSubmissions(listing, 10)))
+}
+
+func TestMayBeMedia(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Post     string
+		Expected bool
+	}{
+		{
+			TestName: "Text",
+			Post:     `{"selftext":"text"}`,
+		},
+		{
+			TestName: "Poll",
+			Post:     `{"selftext":"text","poll_data":{"options":[]}}`,
+		},
+		{
+			TestName: "Gallery Without Hint",
+			Post:     `{"gallery_data":{"items":[]},"media_metadata":{}}`,
+			Expected: true,
+		},
+		{
+			TestName: "Image",
+			Post:     `{"post_hint":"image","url":"https://i.redd.it/a.jpg"}`,
+			Expected: true,
+		},
+		{
+			TestName: "Reddit Video",
+			Post:     `{"post_hint":"hosted:video"}`,
+			Expected: true,
+		},
+		{
+			TestName: "Supported Link",
+			Post:     `{"post_hint":"link","url":"https://i.imgur.com/a.gifv"}`,
+			Expected: true,
+		},
+		{
+			TestName: "Direct Media Link",
+			Post:     `{"post_hint":"link","url":"https://example.com/a.mp4"}`,
+			Expected: true,
+		},
+		{
+			TestName: "Other Link",
+			Post:     `{"post_hint":"link","url":"https://example.com/article"}`,
+		},
+		{
+			TestName: "Unsupported Rich Video",
+			Post:     `{"post_hint":"rich:video","url":"https://example.com/watch"}`,
+		},
+		{
+			TestName: "Crossposted Image",
+			Post:     `{"selftext":"","crosspost_parent_list":[{"post_hint":"image","url":"https://i.redd.it/a.jpg"}]}`,
+			Expected: true,
+		},
+		{
+			TestName: "Crossposted Text",
+			Post:     `{"post_hint":"image","crosspost_parent_list":[{"selftext":"text"}]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			var post apiLink
+			assert.NoError(t, json.Unmarshal([]byte(test.Post), &post))
+			assert.Equal(t, test.Expected, mayBeMedia(&post))
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package reddit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+)
+
+// ytdlpBinary is the name or the path of the yt-dlp executable. Changed in tests.
+var ytdlpBinary = "yt-dlp"
+
+// ytdlpFormatFragment is the prefix of the fragment of the links which are downloaded with yt-dlp.
+// The rest of the fragment is the format which yt-dlp downloads.
+const ytdlpFormatFragment = "ytdlp="
+
+// ytdlpInfoTimeout is how long getting the formats of a link can take
+const ytdlpInfoTimeout = 30 * time.Second
+
+// ytdlpDownloadTimeout is how long downloading a link can take
+const ytdlpDownloadTimeout = 5 * time.Minute
+
+// ytdlpDefaultHosts are the hosts which yt-dlp is used for if YTDLP_HOSTS is not set
+var ytdlpDefaultHosts = []string{
+	"youtube.com", "youtu.be", "twitter.com", "x.com", "tiktok.com",
+	"vimeo.com", "twitch.tv", "dailymotion.com", "instagram.com",
+}
+
+// ytdlpExtractor gets the videos of the hosts which no other extractor supports with a locally
+// installed yt-dlp. It does not match anything if yt-dlp is not installed.
+type ytdlpExtractor struct {
+	// The hosts which this extractor matches (and their subdomains)
+	hosts []string
+}
+
+// ytdlpInfo is the output of yt-dlp --dump-single-json
+type ytdlpInfo struct {
+	Duration float64       `json:"duration"`
+	IsLive   bool          `json:"is_live"`
+	Formats  []ytdlpFormat `json:"formats"`
+}
+
+// ytdlpFormat is a format of a video in ytdlpInfo
+type ytdlpFormat struct {
+	FormatID string `json:"format_id"`
+	Ext      string `json:"ext"`
+	// The codecs are "none" if the format does not have video or audio
+	VCodec         string  `json:"vcodec"`
+	ACodec         string  `json:"acodec"`
+	Width          int64   `json:"width"`
+	Height         int64   `json:"height"`
+	FileSize       int64   `json:"filesize"`
+	FileSizeApprox int64   `json:"filesize_approx"`
+	TotalBitrate   float64 `json:"tbr"`
+}
+
+// newYtdlpExtractor creates the yt-dlp extractor with the hosts of YTDLP_HOSTS, or the default
+// hosts if it's not set
+func newYtdlpExtractor() ytdlpExtractor {
+	hosts := ytdlpDefaultHosts
+	if envHosts := os.Getenv("YTDLP_HOSTS"); envHosts != "" {
+		hosts = strings.Split(envHosts, ",")
+	}
+	return ytdlpExtractor{hosts: hosts}
+}
+
+// ytdlpExists returns true if yt-dlp is found
+func ytdlpExists() bool {
+	_, err := exec.LookPath(ytdlpBinary)
+	return err == nil
+}
+
+func (e ytdlpExtractor) Match(link *url.URL) bool {
+	for _, host := range e.hosts {
+		if hostIs(link, strings.TrimSpace(host)) {
+			return ytdlpExists()
+		}
+	}
+	return false
+}
+
+func (ytdlpExtractor) Extract(ctx context.Context, request ExtractRequest) (FetchResultMedia, error) {
+	ctx, cancel := context.WithTimeout(ctx, ytdlpInfoTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, ytdlpBinary,
+		"--dump-single-json",
+		"--no-playlist",
+		"--no-warnings",
+		request.URL)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return FetchResultMedia{}, errors.Wrap(errors.New(strings.TrimSpace(stderr.String())), "yt-dlp failed")
+	}
+	var info ytdlpInfo
+	if err = json.Unmarshal(output, &info); err != nil {
+		return FetchResultMedia{}, errors.Wrap(err, "cannot parse the output of yt-dlp")
+	}
+	if info.IsLive {
+		return FetchResultMedia{}, &FetchError{
+			NormalError: "",
+			BotError:    "Live streams cannot be downloaded.\nHere is the link: " + request.URL,
+		}
+	}
+	medias := ytdlpQualities(request.URL, info.Formats)
+	if len(medias) == 0 {
+		return FetchResultMedia{}, &FetchError{
+			NormalError: "",
+			BotError:    "This video is too large to upload on Telegram.\nHere is the link: " + request.URL,
+		}
+	}
+	return FetchResultMedia{
+		Medias:   medias,
+		Duration: int64(math.Round(info.Duration)),
+		Type:     FetchResultMediaTypeVideo,
+	}, nil
+}
+
+// ytdlpQualities converts the formats of a video to the qualities which are not larger than what
+// can be uploaded to Telegram. The formats which have video and audio are used as they are; The
+// H.264 videos without audio are paired with the best audio. For each height, the largest format
+// is used; The formats with unknown sizes are only used if no format of that height has a known
+// size. yt-dlp does not download them if they are too large. The result is sorted by quality;
+// Decreasing.
+func ytdlpQualities(link string, formats []ytdlpFormat) FetchResultMediaEntries {
+	// Find the best audio
+	var audio *ytdlpFormat
+	for i, format := range formats {
+		if format.VCodec == "none" && format.ACodec != "none" && format.Ext == "m4a" &&
+			(audio == nil || format.TotalBitrate > audio.TotalBitrate) {
+			audio = &formats[i]
+		}
+	}
+	type candidate struct {
+		format string
+		size   int64
+		dim    Dimension
+	}
+	best := make(map[int64]candidate)
+	for _, format := range formats {
+		if format.VCodec == "none" || format.Ext != "mp4" || format.Height == 0 {
+			continue
+		}
+		c := candidate{format: format.FormatID, size: format.size(), dim: Dimension{Width: format.Width, Height: format.Height}}
+		if format.ACodec == "none" {
+			// Telegram does not play the other codecs
+			if audio == nil || !(strings.HasPrefix(format.VCodec, "avc1") || strings.HasPrefix(format.VCodec, "h264")) {
+				continue
+			}
+			c.format += "+" + audio.FormatID
+			if audio.size() == 0 {
+				c.size = 0
+			} else if c.size != 0 {
+				c.size += audio.size()
+			}
+		}
+		if c.size > maxDownloadSize {
+			continue
+		}
+		if old, exists := best[format.Height]; exists && (c.size == 0 || c.size <= old.size) {
+			continue
+		}
+		best[format.Height] = c
+	}
+	heights := make([]int64, 0, len(best))
+	for height := range best {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] > heights[j] })
+	result := make(FetchResultMediaEntries, 0, len(heights))
+	for _, height := range heights {
+		c := best[height]
+		quality := strconv.FormatInt(height, 10) + "p"
+		if c.size != 0 {
+			quality += ", " + strconv.FormatFloat(float64(c.size)/1000/1000, 'f', 1, 64) + " MB"
+		}
+		result = append(result, FetchResultMediaEntry{
+			Link:    ytdlpLink(link, c.format),
+			Quality: quality,
+			Dim:     c.dim,
+		})
+	}
+	return result
+}
+
+// size gets the size of a format in bytes. Zero if unknown.
+func (f ytdlpFormat) size() int64 {
+	if f.FileSize != 0 {
+		return f.FileSize
+	}
+	return f.FileSizeApprox
+}
+
+// ytdlpLink creates the link of a format of a video. The format is put in the fragment, so
+// the link can still be opened by the users.
+func ytdlpLink(link, format string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	u.Fragment = ytdlpFormatFragment + format
+	return u.String()
+}
+
+// parseYtdlpLink gets the link of the video and the format from a link of ytdlpLink.
+// ok is false if the link is not a yt-dlp link.
+func parseYtdlpLink(link string) (videoLink, format string, ok bool) {
+	u, err := url.Parse(link)
+	if err != nil || !strings.HasPrefix(u.Fragment, ytdlpFormatFragment) {
+		return "", "", false
+	}
+	format = strings.TrimPrefix(u.Fragment, ytdlpFormatFragment)
+	u.Fragment = ""
+	return u.String(), format, true
+}
+
+// downloadWithYtdlp downloads a format of a video with yt-dlp in a temp file
+func downloadWithYtdlp(link, format string) (*os.File, error) {
+	tmpFile, err := os.CreateTemp("", "*.mp4")
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create a temporary file for the video")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ytdlpDownloadTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, ytdlpBinary,
+		"--format", format,
+		"--no-playlist",
+		"--no-warnings",
+		"--no-part",
+		"--force-overwrites",
+		"--max-filesize", strconv.Itoa(maxDownloadSize),
+		"--merge-output-format", "mp4",
+		"--output", tmpFile.Name(),
+		link)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	// yt-dlp replaces the file, so it must be opened again
+	_ = tmpFile.Close()
+	if err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return nil, errors.Wrap(errors.New(strings.TrimSpace(stderr.String())), "yt-dlp failed")
+	}
+	stat, err := os.Stat(tmpFile.Name())
+	if err != nil || stat.Size() == 0 {
+		// yt-dlp skips the files which are larger than --max-filesize
+		_ = os.Remove(tmpFile.Name())
+		return nil, FileTooBigError
+	}
+	videoFile, err := os.Open(tmpFile.Name())
+	if err != nil {
+		_ = os.Remove(tmpFile.Name())
+		return nil, err
+	}
+	return videoFile, nil
+}
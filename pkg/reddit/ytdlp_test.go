@@ -0,0 +1,128 @@
+package reddit
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeYtdlp is a stand-in for yt-dlp. It prints the info of a video and writes the format which
+// is downloaded in the output file.
+const fakeYtdlp = `#!/bin/sh
+if [ "$1" = "--dump-single-json" ]; then
+	case "$4" in
+	*live*) echo '{"is_live":true,"formats":[]}' ;;
+	*broken*) echo 'ERROR: Unsupported URL' >&2; exit 1 ;;
+	*) cat <<'JSON'
+{"id":"a","title":"A video","duration":212.6,"is_live":false,"formats":[
+{"format_id":"139","ext":"m4a","vcodec":"none","acodec":"mp4a.40.5","filesize":1000000,"tbr":48.8},
+{"format_id":"140","ext":"m4a","vcodec":"none","acodec":"mp4a.40.2","filesize":3000000,"tbr":129.5},
+{"format_id":"18","ext":"mp4","vcodec":"avc1.42001E","acodec":"mp4a.40.2","width":640,"height":360,"filesize":null,"filesize_approx":5000000},
+{"format_id":"http-480","ext":"mp4","vcodec":"avc1.4d401e","acodec":"mp4a.40.2","width":854,"height":480},
+{"format_id":"22","ext":"mp4","vcodec":"avc1.64001F","acodec":"mp4a.40.2","width":1280,"height":720,"filesize":null},
+{"format_id":"136","ext":"mp4","vcodec":"avc1.4d401f","acodec":"none","width":1280,"height":720,"filesize":20000000},
+{"format_id":"247","ext":"webm","vcodec":"vp9","acodec":"none","width":1280,"height":720,"filesize":30000000},
+{"format_id":"398","ext":"mp4","vcodec":"av01.0.05M.08","acodec":"none","width":1280,"height":720,"filesize":25000000},
+{"format_id":"137","ext":"mp4","vcodec":"avc1.640028","acodec":"none","width":1920,"height":1080,"filesize":60000000}
+]}
+JSON
+	;;
+	esac
+	exit 0
+fi
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--format) format="$2"; shift ;;
+	--output) output="$2"; shift ;;
+	esac
+	shift
+done
+printf '%s' "$format" > "$output"
+`
+
+// useFakeYtdlp makes the extractor use fakeYtdlp until the test ends
+func useFakeYtdlp(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake yt-dlp is a shell script")
+	}
+	binary := filepath.Join(t.TempDir(), "yt-dlp")
+	assert.NoError(t, os.WriteFile(binary, []byte(fakeYtdlp), 0755))
+	oldBinary := ytdlpBinary
+	ytdlpBinary = binary
+	t.Cleanup(func() { ytdlpBinary = oldBinary })
+}
+
+func TestYtdlpExtractorMatch(t *testing.T) {
+	extractor := ytdlpExtractor{hosts: ytdlpDefaultHosts}
+	youtube, _ := url.Parse("https://www.youtube.com/watch?v=a")
+	other, _ := url.Parse("https://example.com/watch?v=a")
+	// Disabled without yt-dlp
+	oldBinary := ytdlpBinary
+	ytdlpBinary = filepath.Join(t.TempDir(), "missing-yt-dlp")
+	assert.False(t, extractor.Match(youtube))
+	ytdlpBinary = oldBinary
+	useFakeYtdlp(t)
+	assert.True(t, extractor.Match(youtube))
+	assert.False(t, extractor.Match(other))
+}
+
+func TestYtdlpExtractor(t *testing.T) {
+	useFakeYtdlp(t)
+	result, err := ytdlpExtractor{}.Extract(context.Background(), ExtractRequest{URL: "https://www.youtube.com/watch?v=a"})
+	assert.NoError(t, err)
+	assert.Equal(t, FetchResultMedia{
+		Medias: FetchResultMediaEntries{
+			{
+				Link:    "https://www.youtube.com/watch?v=a#ytdlp=136+140",
+				Quality: "720p, 23.0 MB",
+				Dim:     Dimension{Width: 1280, Height: 720},
+			},
+			{
+				// The size is unknown
+				Link:    "https://www.youtube.com/watch?v=a#ytdlp=http-480",
+				Quality: "480p",
+				Dim:     Dimension{Width: 854, Height: 480},
+			},
+			{
+				Link:    "https://www.youtube.com/watch?v=a#ytdlp=18",
+				Quality: "360p, 5.0 MB",
+				Dim:     Dimension{Width: 640, Height: 360},
+			},
+		},
+		Duration: 213,
+		Type:     FetchResultMediaTypeVideo,
+	}, result)
+	// Live streams
+	_, err = ytdlpExtractor{}.Extract(context.Background(), ExtractRequest{URL: "https://www.youtube.com/live"})
+	assert.Equal(t, &FetchError{BotError: "Live streams cannot be downloaded.\nHere is the link: https://www.youtube.com/live"}, err)
+	// Errors of yt-dlp
+	_, err = ytdlpExtractor{}.Extract(context.Background(), ExtractRequest{URL: "https://www.youtube.com/broken"})
+	assert.ErrorContains(t, err, "Unsupported URL")
+}
+
+func TestYtdlpDownload(t *testing.T) {
+	useFakeYtdlp(t)
+	videoFile, err := (&Oauth{}).DownloadVideo("https://www.youtube.com/watch?v=a#ytdlp=136+140", "")
+	assert.NoError(t, err)
+	defer func() {
+		_ = videoFile.Close()
+		_ = os.Remove(videoFile.Name())
+	}()
+	content, err := os.ReadFile(videoFile.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "136+140", string(content))
+}
+
+func TestParseYtdlpLink(t *testing.T) {
+	link, format, ok := parseYtdlpLink(ytdlpLink("https://x.com/a/status/1", "http-2176"))
+	assert.True(t, ok)
+	assert.Equal(t, "https://x.com/a/status/1", link)
+	assert.Equal(t, "http-2176", format)
+	_, _, ok = parseYtdlpLink("https://v.redd.it/a/DASH_720.mp4")
+	assert.False(t, ok)
+}
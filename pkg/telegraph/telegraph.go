@@ -0,0 +1,211 @@
+// Package telegraph publishes pages on Telegraph (https://telegra.ph) or any server which has the
+// same API. Telegram shows these pages with Instant View.
+package telegraph
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/go-faster/errors"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DefaultAPIURL is the API of telegra.ph
+const DefaultAPIURL = "https://api.telegra.ph"
+
+// The short name of the account which is created when no access token is given
+const accountShortName = "RedditDownloaderBot"
+
+// maxTitleLength is the maximum length of the title of a page
+const maxTitleLength = 256
+
+// defaultTitle is the title of the pages which have no title
+const defaultTitle = "Reddit post"
+
+// The tags which Telegraph supports and are created from the HTML of Telegram
+var allowedTags = map[string]bool{
+	"a": true, "b": true, "blockquote": true, "br": true, "code": true, "i": true, "p": true, "pre": true, "s": true, "u": true,
+}
+
+// Node is an element of the content of a page. It's either a string or a NodeElement.
+type Node any
+
+// NodeElement is an HTML element in the content of a page
+type NodeElement struct {
+	Tag      string            `json:"tag"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Children []Node            `json:"children,omitempty"`
+}
+
+// apiResponse is the response of all endpoints of the API
+type apiResponse struct {
+	Ok     bool            `json:"ok"`
+	Error  string          `json:"error"`
+	Result json.RawMessage `json:"result"`
+}
+
+// Client publishes pages with the Telegraph API
+type Client struct {
+	apiURL     string
+	authorName string
+	httpClient *http.Client
+	// The access token of the account. If it's empty, an account is created on the first publish.
+	accessToken string
+	tokenLock   sync.Mutex
+}
+
+// NewClient creates a Client which uses the API at apiURL. If accessToken is empty, a new account
+// is created when the first page is published. authorName is shown on the pages.
+func NewClient(apiURL, accessToken, authorName string, httpClient *http.Client) *Client {
+	return &Client{
+		apiURL:      strings.TrimSuffix(apiURL, "/"),
+		authorName:  authorName,
+		httpClient:  httpClient,
+		accessToken: accessToken,
+	}
+}
+
+// Publish creates a page with the title and the Telegram HTML blocks (like the ones markdown.Blocks
+// creates). Returns the URL of the page.
+func (c *Client) Publish(title string, blocks []string) (string, error) {
+	token, err := c.token()
+	if err != nil {
+		return "", err
+	}
+	content, err := json.Marshal(BlocksToNodes(blocks))
+	if err != nil {
+		return "", errors.Wrap(err, "cannot encode the content")
+	}
+	title = strings.TrimSpace(title)
+	if title == "" {
+		title = defaultTitle
+	}
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = string(runes[:maxTitleLength-1]) + "…"
+	}
+	var page struct {
+		URL string `json:"url"`
+	}
+	err = c.call("createPage", url.Values{
+		"access_token": {token},
+		"title":        {title},
+		"author_name":  {c.authorName},
+		"content":      {string(content)},
+	}, &page)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot create the page")
+	}
+	return page.URL, nil
+}
+
+// token gets the access token and creates an account if there is none
+func (c *Client) token() (string, error) {
+	c.tokenLock.Lock()
+	defer c.tokenLock.Unlock()
+	if c.accessToken != "" {
+		return c.accessToken, nil
+	}
+	var account struct {
+		AccessToken string `json:"access_token"`
+	}
+	err := c.call("createAccount", url.Values{
+		"short_name":  {accountShortName},
+		"author_name": {c.authorName},
+	}, &account)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot create an account")
+	}
+	c.accessToken = account.AccessToken
+	return c.accessToken, nil
+}
+
+// call calls a method of the API and decodes its result in result
+func (c *Client) call(method string, params url.Values, result any) error {
+	resp, err := c.httpClient.PostForm(c.apiURL+"/"+method, params)
+	if err != nil {
+		return errors.Wrap(err, "cannot send the request")
+	}
+	defer resp.Body.Close()
+	var response apiResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return errors.Wrap(err, "cannot decode the response of status "+resp.Status)
+	}
+	if !response.Ok {
+		return errors.New("API error: " + response.Error)
+	}
+	if err = json.Unmarshal(response.Result, result); err != nil {
+		return errors.Wrap(err, "cannot decode the result")
+	}
+	return nil
+}
+
+// BlocksToNodes converts the Telegram HTML blocks to the content of a page. Blocks which are not a
+// quote or a code block are paragraphs. Tags which Telegraph does not support (like spoilers) are
+// removed, but their content is kept.
+func BlocksToNodes(blocks []string) []Node {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	var result []Node
+	for _, block := range blocks {
+		nodes, err := html.ParseFragment(strings.NewReader(block), body)
+		if err != nil { // never happens with a strings.Reader
+			continue
+		}
+		var children []Node
+		for _, node := range nodes {
+			children = append(children, convertNode(node, false)...)
+		}
+		// Quotes and code blocks are blocks themselves
+		if len(children) == 1 {
+			if element, ok := children[0].(NodeElement); ok && (element.Tag == "blockquote" || element.Tag == "pre") {
+				result = append(result, element)
+				continue
+			}
+		}
+		result = append(result, NodeElement{Tag: "p", Children: children})
+	}
+	return result
+}
+
+// convertNode converts an HTML node to the nodes of a page. Line breaks are converted to br
+// unless inPre is true.
+func convertNode(node *html.Node, inPre bool) []Node {
+	switch node.Type {
+	case html.TextNode:
+		if inPre {
+			return []Node{node.Data}
+		}
+		var result []Node
+		for i, line := range strings.Split(node.Data, "\n") {
+			if i > 0 {
+				result = append(result, NodeElement{Tag: "br"})
+			}
+			if line != "" {
+				result = append(result, line)
+			}
+		}
+		return result
+	case html.ElementNode:
+		inPre = inPre || node.Data == "pre"
+		var children []Node
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			children = append(children, convertNode(child, inPre)...)
+		}
+		if !allowedTags[node.Data] {
+			return children
+		}
+		element := NodeElement{Tag: node.Data, Children: children}
+		if node.Data == "a" {
+			for _, attr := range node.Attr {
+				if attr.Key == "href" {
+					element.Attrs = map[string]string{"href": attr.Val}
+				}
+			}
+		}
+		return []Node{element}
+	}
+	return nil
+}
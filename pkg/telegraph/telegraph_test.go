@@ -0,0 +1,139 @@
+package telegraph
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newStandInServer creates a server which acts like the Telegraph API. It creates an account with
+// the token "token" and the pages are saved in pages.
+func newStandInServer(t *testing.T, pages *[]map[string]string, accounts *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NoError(t, r.ParseForm())
+		var response apiResponse
+		switch r.URL.Path {
+		case "/createAccount":
+			accounts.Add(1)
+			assert.Equal(t, accountShortName, r.Form.Get("short_name"))
+			response = apiResponse{Ok: true, Result: json.RawMessage(`{"access_token":"token"}`)}
+		case "/createPage":
+			if r.Form.Get("access_token") != "token" {
+				response = apiResponse{Ok: false, Error: "ACCESS_TOKEN_INVALID"}
+				break
+			}
+			*pages = append(*pages, map[string]string{
+				"title":       r.Form.Get("title"),
+				"author_name": r.Form.Get("author_name"),
+				"content":     r.Form.Get("content"),
+			})
+			response = apiResponse{Ok: true, Result: json.RawMessage(`{"url":"https://telegra.ph/Page-01-01"}`)}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			response = apiResponse{Ok: false, Error: "METHOD_NOT_FOUND"}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestPublish(t *testing.T) {
+	var pages []map[string]string
+	var accounts atomic.Int32
+	server := newStandInServer(t, &pages, &accounts)
+	defer server.Close()
+	client := NewClient(server.URL+"/", "", "Bot", server.Client())
+	// The account is created once
+	for i := 0; i < 2; i++ {
+		pageURL, err := client.Publish("Title", []string{"<b>Hello</b>"})
+		assert.NoError(t, err)
+		assert.Equal(t, "https://telegra.ph/Page-01-01", pageURL)
+	}
+	assert.Equal(t, int32(1), accounts.Load())
+	assert.Len(t, pages, 2)
+	assert.Equal(t, "Title", pages[0]["title"])
+	assert.Equal(t, "Bot", pages[0]["author_name"])
+	assert.JSONEq(t, `[{"tag":"p","children":[{"tag":"b","children":["Hello"]}]}]`, pages[0]["content"])
+}
+
+func TestPublishTitle(t *testing.T) {
+	var pages []map[string]string
+	var accounts atomic.Int32
+	server := newStandInServer(t, &pages, &accounts)
+	defer server.Close()
+	client := NewClient(server.URL, "token", "", server.Client())
+	_, err := client.Publish(" ", []string{"a"})
+	assert.NoError(t, err)
+	long := make([]rune, 300)
+	for i := range long {
+		long[i] = 'ب'
+	}
+	_, err = client.Publish(string(long), []string{"a"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), accounts.Load())
+	assert.Equal(t, defaultTitle, pages[0]["title"])
+	assert.Equal(t, string(long[:maxTitleLength-1])+"…", pages[1]["title"])
+}
+
+func TestPublishError(t *testing.T) {
+	var pages []map[string]string
+	var accounts atomic.Int32
+	server := newStandInServer(t, &pages, &accounts)
+	defer server.Close()
+	client := NewClient(server.URL, "wrong", "", server.Client())
+	_, err := client.Publish("Title", []string{"a"})
+	assert.ErrorContains(t, err, "ACCESS_TOKEN_INVALID")
+	client = NewClient(server.URL+"/missing", "token", "", server.Client())
+	_, err = client.Publish("Title", []string{"a"})
+	assert.ErrorContains(t, err, "METHOD_NOT_FOUND")
+}
+
+func TestBlocksToNodes(t *testing.T) {
+	tests := []struct {
+		TestName string
+		Blocks   []string
+		Expected string
+	}{
+		{
+			TestName: "Paragraphs",
+			Blocks:   []string{"one", "two &amp; three"},
+			Expected: `[{"tag":"p","children":["one"]},{"tag":"p","children":["two & three"]}]`,
+		},
+		{
+			TestName: "Line breaks",
+			Blocks:   []string{"<i>a\nb</i>"},
+			Expected: `[{"tag":"p","children":[{"tag":"i","children":["a",{"tag":"br"},"b"]}]}]`,
+		},
+		{
+			TestName: "Link",
+			Blocks:   []string{`<a href="https://go.dev/?a=1&amp;b=2">Go</a>`},
+			Expected: `[{"tag":"p","children":[{"tag":"a","attrs":{"href":"https://go.dev/?a=1&b=2"},"children":["Go"]}]}]`,
+		},
+		{
+			TestName: "Quote",
+			Blocks:   []string{"<blockquote>a\n\nb</blockquote>"},
+			Expected: `[{"tag":"blockquote","children":["a",{"tag":"br"},{"tag":"br"},"b"]}]`,
+		},
+		{
+			TestName: "Code",
+			Blocks:   []string{`<pre><code class="language-go">a &lt; b` + "\n" + `c</code></pre>`},
+			Expected: `[{"tag":"pre","children":[{"tag":"code","children":["a < b\nc"]}]}]`,
+		},
+		{
+			TestName: "Spoiler",
+			Blocks:   []string{"<tg-spoiler><s>hidden</s></tg-spoiler>"},
+			Expected: `[{"tag":"p","children":[{"tag":"s","children":["hidden"]}]}]`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.TestName, func(t *testing.T) {
+			content, err := json.Marshal(BlocksToNodes(test.Blocks))
+			assert.NoError(t, err)
+			assert.JSONEq(t, test.Expected, string(content))
+		})
+	}
+}